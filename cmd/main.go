@@ -1,23 +1,43 @@
 package main
 
 import (
-	_ "apisecurityplatform/docs"
+	"apisecurityplatform/docs"
+	"apisecurityplatform/pkg/audit"
+	"apisecurityplatform/pkg/auth"
+	"apisecurityplatform/pkg/cache"
+	"apisecurityplatform/pkg/certs"
+	"apisecurityplatform/pkg/config"
 	"apisecurityplatform/pkg/database"
+	"apisecurityplatform/pkg/gateway"
 	"apisecurityplatform/pkg/handlers"
+	"apisecurityplatform/pkg/health"
+	"apisecurityplatform/pkg/logging"
 	"apisecurityplatform/pkg/middleware"
 	"apisecurityplatform/pkg/observability"
+	"apisecurityplatform/pkg/openapi"
+	"apisecurityplatform/pkg/repository"
+	"apisecurityplatform/pkg/secrets"
+	"apisecurityplatform/pkg/server"
+	"apisecurityplatform/pkg/slo"
+	"apisecurityplatform/pkg/usage"
+	"context"
+	"errors"
 	"fmt"
-	"log"
-	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
-	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/time/rate"
+	"gorm.io/gorm"
 )
 
+var logger = logging.For("main")
+
 // @title           Secure API Management Platform
 // @version         1.0
 // @description     A secure API management platform with authentication and API key management.
@@ -41,94 +61,486 @@ import (
 // @tag.name users
 // @tag.description User operations
 
+// @tag.name health
+// @tag.description Liveness and readiness probes
+
+// @tag.name admin
+// @tag.description Gateway administration, served on the admin listener
+
 func main() {
+	// Load and validate configuration before touching any dependency
+	configManager, err := config.NewManager(os.Args[1:])
+	if err != nil {
+		fatal("Failed to load configuration", err)
+	}
+	cfg := configManager.Current()
+
+	if err := logging.Setup(cfg.Log.Level, cfg.Log.Packages); err != nil {
+		fatal("Failed to initialize logging", err)
+	}
+	logger.Info("Loaded configuration", "config", cfg.Redacted())
+
+	// Cancelled on SIGINT or SIGTERM to start a graceful shutdown
+	signalCtx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	// Background goroutines run until the listeners have drained
+	ctx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	// Cleanup steps run in the order added, once the listeners have drained
+	var shutdown server.Shutdown
+
 	// Initialize tracer
-	cleanup, err := observability.InitTracer()
+	shutdownTelemetry, err := observability.InitTracer(cfg.Tracing, cfg.Environment)
 	if err != nil {
-		log.Fatalf("Failed to initialize tracer: %v", err)
+		fatal("Failed to initialize tracer", err)
 	}
-	defer cleanup()
+
+	// Resolve secrets, which may live in files, the environment or Vault
+	runtimeSecrets, err := loadSecrets(ctx, cfg)
+	if err != nil {
+		fatal("Failed to load secrets", err)
+	}
+	signingKeys := auth.NewSigningKeys(runtimeSecrets.jwtSecret.Get())
+	runtimeSecrets.jwtSecret.OnChange(func(secret string) {
+		if err := cfg.ValidateSecrets(secret, ""); err != nil {
+			logger.Warn("Ignoring rotated JWT secret", "error", err)
+			return
+		}
+		signingKeys.Rotate(secret)
+		logger.Info("JWT signing key rotated")
+	})
+	runtimeSecrets.refresher.Start(ctx)
 
 	// Initialize database connection first
-	database.ConnectDatabase()
+	db, err := database.Open(cfg.Database, runtimeSecrets.dbPassword.Get)
+	if err != nil {
+		fatal("Failed to initialize database", err)
+	}
 
-	router := gin.Default()
+	// Wire repositories into the handlers
+	users := repository.NewGormUserRepository(db)
+	apiKeys := repository.NewGormAPIKeyRepository(db)
+	routes := repository.NewGormRouteRepository(db)
+	usageRecords := repository.NewGormUsageRepository(db)
 
-	// Swagger documentation
-	router.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	authHandler := handlers.NewAuthHandler(users, signingKeys, cfg.JWT)
+	userHandler := handlers.NewUserHandler(users)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeys)
+	usageHandler := handlers.NewUsageHandler(usageRecords)
+
+	// Per-user and per-key usage is metered into the database rather than
+	// labelled onto metrics, where it would grow without bound
+	usageMeter := usage.NewMeter(usageRecords)
+	usageMeter.Watch(ctx, cfg.Usage.FlushInterval, cfg.Usage.Retention)
+
+	// Objectives are measured from the HTTP server metrics
+	sloTracker, err := slo.NewTracker(cfg.SLO)
+	if err != nil {
+		fatal("Invalid SLO configuration", err)
+	}
+
+	// Runtime-reloadable middleware
+	rateLimiter := middleware.NewRateLimiter(rate.Limit(cfg.RateLimit.RequestsPerSecond), cfg.RateLimit.Burst)
+	cors := middleware.NewCORS(cfg.CORS.AllowedOrigins)
+
+	auditLog := audit.NewLogger(os.Stdout)
+
+	// TLS termination, with certificates reloaded when the files change
+	publicServer := server.New("public", cfg.Server.Address(), nil, cfg.Server)
+	apiKeyAuth := middleware.APIKeyAuth(apiKeys)
+	if cfg.Server.TLS.Enabled {
+		certReloader, err := certs.NewReloaderFromConfig(cfg.Server.TLS)
+		if err != nil {
+			fatal("Failed to load TLS certificate", err)
+		}
+		certReloader.Watch(ctx, cfg.Server.TLS.ReloadInterval)
+		publicServer.HTTP.TLSConfig = certReloader.TLSConfig()
 
-	// Enable debug mode
+		// Verified client certificates act as machine credentials next to API keys
+		if cfg.Server.TLS.ClientAuth != "none" {
+			identities := certs.NewIdentityMapperFromConfig(cfg.Server.TLS)
+			apiKeyAuth = middleware.ClientCertAuth(identities, apiKeyAuth)
+		}
+	}
+
+	// Enable debug mode, logged through the gin logger at debug level
 	gin.SetMode(gin.DebugMode)
+	ginLogger := logging.For("gin")
+	gin.DebugPrintFunc = func(format string, values ...any) {
+		ginLogger.Debug(strings.TrimSpace(fmt.Sprintf(format, values...)))
+	}
+	gin.DebugPrintRouteFunc = func(method, path, handler string, handlers int) {
+		ginLogger.Debug("Route registered", "method", method, "path", path, "handler", handler, "handlers", handlers)
+	}
 
-	// Health check endpoint with tracing
-	router.GET("/health", func(c *gin.Context) {
-		ctx := c.Request.Context()
-		tracer := observability.GetTracer()
-
-		// Create a child span for simulated DB check
-		_, dbSpan := tracer.Start(ctx, "health.check.database")
-		// Simulate DB check
-		time.Sleep(10 * time.Millisecond)
-		dbSpan.SetAttributes(attribute.Bool("database.healthy", true))
-		dbSpan.End()
-
-		c.JSON(http.StatusOK, gin.H{
-			"status":    "healthy",
-			"timestamp": time.Now().Format(time.RFC3339),
-		})
-	})
+	// gRPC calls to gateway routes get their errors as gRPC statuses, so that
+	// translation wraps everything else, the access log included
+	router := gin.New()
+	router.Use(middleware.Recovery(), gateway.GRPCErrors(), middleware.AccessLog())
+
+	// Probes are served ahead of the global middleware, so they are neither
+	// rate limited nor traced. Liveness has no dependency checks: an outage
+	// of the database takes instances out of rotation instead of restarting them.
+	liveness := health.NewRegistry("liveness")
+	readiness := health.NewRegistry("readiness")
+	publicHealth := handlers.NewHealthHandler(liveness, readiness, false)
+	router.GET("/livez", publicHealth.Livez)
+	router.GET("/readyz", publicHealth.Readyz)
+	// Kept for load balancers configured before /readyz existed
+	router.GET("/health", publicHealth.Readyz)
 
 	// Add global middleware
 	router.Use(middleware.MetricsMiddleware())
+	router.Use(usageMeter.Middleware())
 	router.Use(middleware.TracingMiddleware())
+	router.Use(cors.Middleware())
+	router.Use(rateLimiter.Middleware())
 
-	// Metrics endpoint
-	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	// Requests to documented endpoints are validated against the API
+	// documentation before they reach handlers
+	var apiValidator *openapi.Validator
+	if cfg.OpenAPI.Enabled {
+		if apiValidator, err = platformValidator(cfg.OpenAPI); err != nil {
+			fatal("Failed to load OpenAPI document", err)
+		}
+		router.Use(apiValidator.Middleware())
+	}
 
 	// Auth routes
 	auth := router.Group("/auth")
 	{
-		auth.POST("/register", handlers.Register)
-		auth.POST("/login", handlers.Login)
-		auth.POST("/logout", middleware.AuthMiddleware(), handlers.Logout)
+		auth.POST("/register", authHandler.Register)
+		auth.POST("/login", authHandler.Login)
+		auth.POST("/logout", middleware.AuthMiddleware(signingKeys), authHandler.Logout)
 	}
 
 	// Protected routes
 	api := router.Group("/users")
-	api.Use(middleware.AuthMiddleware())
+	api.Use(middleware.AuthMiddleware(signingKeys))
 	{
-		api.GET("/me", handlers.GetUserProfile)
-		api.DELETE("/:id", handlers.DeleteUser)
+		api.GET("/me", userHandler.GetUserProfile)
+		api.GET("/usage", usageHandler.GetMyUsage)
+		api.DELETE("/:id", userHandler.DeleteUser)
 
 		// API Key routes
-		api.POST("/api-keys", handlers.CreateAPIKey)
-		api.GET("/api-keys", handlers.ListAPIKeys)
-		api.DELETE("/api-keys/:id", handlers.DeleteAPIKey)
+		api.POST("/api-keys", apiKeyHandler.CreateAPIKey)
+		api.GET("/api-keys", apiKeyHandler.ListAPIKeys)
+		api.DELETE("/api-keys/:id", apiKeyHandler.DeleteAPIKey)
 	}
 
 	// Protected route with API key authentication
 	apiKeyProtected := router.Group("/api")
-	apiKeyProtected.Use(middleware.APIKeyAuth())
+	apiKeyProtected.Use(apiKeyAuth)
 	{
 		apiKeyProtected.GET("/test", func(c *gin.Context) {
 			c.JSON(200, gin.H{
-				"message": "API key is valid",
-				"key_id":  c.GetUint("api_key_id"),
+				"message":     "API key is valid",
+				"key_id":      c.GetUint("api_key_id"),
+				"auth_method": c.GetString("auth_method"),
+				"identity":    c.GetString("client_identity"),
 			})
 		})
 	}
 
-	// Print out all registered routes for debugging
-	fmt.Println("\nRegistered Routes:")
-	for _, route := range router.Routes() {
-		fmt.Printf("Method: %s, Path: %s, Handler: %v\n",
-			route.Method,
-			route.Path,
-			route.Handler)
+	// Responses of routes with caching enabled are kept in memory, or in the
+	// database when instances should share them
+	var cacheBackend cache.Backend
+	switch cfg.Gateway.Cache.Backend {
+	case config.CacheBackendDatabase:
+		dbBackend := cache.NewGormBackend(db)
+		dbBackend.Watch(ctx, cfg.Gateway.Cache.CleanupInterval)
+		cacheBackend = dbBackend
+	default:
+		cacheBackend = cache.NewMemoryBackend(cfg.Gateway.Cache.MaxBytes)
+	}
+	responseCache := cache.New(cacheBackend, cfg.Gateway.Cache.MaxEntryBytes)
+
+	// Requests no platform route matched are proxied to the gateway routes, from
+	// the config file and the route registry, which can change them at runtime
+	apiGateway, err := gateway.New(nil, gateway.Authenticators{
+		JWT:     middleware.AuthMiddleware(signingKeys),
+		APIKey:  apiKeyAuth,
+		Revoked: credentialRevoked(signingKeys, apiKeys),
+	}, responseCache)
+	if err != nil {
+		fatal("Failed to initialize gateway", err)
+	}
+	routeRegistry := gateway.NewRegistry(routes, apiGateway, cfg.Gateway.Routes)
+	if err := routeRegistry.Load(ctx); err != nil {
+		fatal("Failed to load gateway routes", err)
+	}
+	routeRegistry.Watch(ctx, cfg.Gateway.RegistryRefreshInterval)
+	apiGateway.Start(ctx)
+	router.NoRoute(apiGateway.Handlers()...)
+
+	if err := registerReadinessChecks(readiness, db, apiGateway, responseCache); err != nil {
+		fatal("Failed to register health checks", err)
+	}
+
+	// Reloaded gateway routes must also fit with the stored ones
+	configManager.Check(func(cfg *config.Config) error {
+		return routeRegistry.CheckStatic(ctx, cfg.Gateway.Routes)
+	})
+	configManager.OnReload(func(event config.ReloadEvent) {
+		handleConfigReload(event, rateLimiter, cors, routeRegistry, auditLog)
+	})
+	if err := configManager.Watch(ctx); err != nil {
+		fatal("Failed to watch configuration", err)
 	}
 
-	// Start the server on port 8080
-	if err := router.Run(":8080"); err != nil {
-		panic(err)
+	// Internal admin router, served on a separate port that is not exposed publicly
+	adminRouter := gin.New()
+	adminRouter.Use(middleware.Recovery(), middleware.AccessLog())
+	if apiValidator != nil {
+		adminRouter.Use(apiValidator.Middleware())
 	}
+
+	// Metrics endpoint
+	adminRouter.GET("/metrics", gin.WrapH(observability.MetricsHandler()))
+
+	// Probes with the per-check breakdown, for the kubelet and operators
+	adminHealth := handlers.NewHealthHandler(liveness, readiness, true)
+	adminRouter.GET("/livez", adminHealth.Livez)
+	adminRouter.GET("/readyz", adminHealth.Readyz)
+
+	// Swagger documentation
+	adminRouter.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+
+	// Gateway route administration, restricted to admins
+	routeHandler := handlers.NewRouteHandler(routeRegistry, auditLog)
+	cacheHandler := handlers.NewCacheHandler(responseCache, auditLog)
+	sloHandler := handlers.NewSLOHandler(sloTracker)
+	admin := adminRouter.Group("/admin")
+	admin.Use(middleware.AuthMiddleware(signingKeys), middleware.RequireRole("admin"))
+	{
+		admin.GET("/routes", routeHandler.ListRoutes)
+		admin.POST("/routes", routeHandler.CreateRoute)
+		admin.GET("/routes/:name", routeHandler.GetRoute)
+		admin.PUT("/routes/:name", routeHandler.UpdateRoute)
+		admin.DELETE("/routes/:name", routeHandler.DeleteRoute)
+		admin.GET("/routes/:name/versions", routeHandler.ListRouteVersions)
+		admin.POST("/routes/:name/rollback", routeHandler.RollbackRoute)
+		admin.POST("/cache/purge", cacheHandler.PurgeCache)
+		admin.GET("/usage", usageHandler.GetUsage)
+		admin.GET("/slo", sloHandler.GetStatus)
+		admin.GET("/slo/rules", sloHandler.GetRules)
+		admin.GET("/slo/dashboard", sloHandler.GetDashboard)
+	}
+
+	// Runtime diagnostics, restricted to admins
+	profiler, err := observability.NewProfiler(ctx, cfg.Profiling)
+	if err != nil {
+		fatal("Failed to set up profiling", err)
+	}
+	debugHandler := handlers.NewDebugHandler(configManager, profiler, auditLog)
+	debug := adminRouter.Group("/debug")
+	debug.Use(middleware.AuthMiddleware(signingKeys), middleware.RequireRole("admin"))
+	{
+		debug.GET("/pprof/*name", debugHandler.Pprof)
+		debug.POST("/pprof/*name", debugHandler.Pprof)
+		debug.GET("/goroutines", debugHandler.Goroutines)
+		debug.GET("/config", debugHandler.GetConfig)
+		debug.GET("/log-level", debugHandler.GetLogLevel)
+		debug.PUT("/log-level", debugHandler.SetLogLevel)
+		debug.POST("/profiles", debugHandler.CaptureProfile)
+		debug.GET("/profiles", debugHandler.ListProfiles)
+		debug.GET("/profiles/:id", debugHandler.DownloadProfile)
+	}
+
+	shutdown.Add("background tasks", func(context.Context) error {
+		stopBackground()
+		return nil
+	})
+	// Requests have drained, so this flush writes the last of the usage
+	shutdown.Add("usage meter", usageMeter.Flush)
+	shutdown.Add("database pool", func(context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.Close()
+	})
+	shutdown.Add("telemetry", shutdownTelemetry)
+
+	// Serve until SIGINT or SIGTERM, then drain in-flight requests
+	// Without TLS, gRPC clients reach the gateway over cleartext HTTP/2
+	router.UseH2C = !cfg.Server.TLS.Enabled
+	publicServer.HTTP.Handler = router.Handler()
+	runErr := server.Run(signalCtx, cfg.Server.ShutdownTimeout,
+		publicServer,
+		server.NewAdmin(cfg.Admin.Address(), adminRouter, cfg.Server),
+	)
+	if err := shutdown.Run(cfg.Server.ShutdownTimeout); err != nil {
+		logger.Error("Shutdown completed with errors", "error", err)
+	}
+	if runErr != nil {
+		fatal("Server error", runErr)
+	}
+	logger.Info("Shutdown complete")
+}
+
+// platformValidator validates the platform's own endpoints against the
+// documentation generated into docs/docs.go, unless another document is configured
+func platformValidator(cfg config.OpenAPIConfig) (*openapi.Validator, error) {
+	data := []byte(docs.SwaggerInfo.ReadDoc())
+	if cfg.Spec != "" {
+		var err error
+		if data, err = os.ReadFile(cfg.Spec); err != nil {
+			return nil, err
+		}
+	}
+	doc, err := openapi.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return openapi.New("platform", doc, "", cfg.ValidateResponses, cfg.MaxBodyBytes)
+}
+
+type runtimeSecrets struct {
+	jwtSecret  *secrets.Value
+	dbPassword *secrets.Value
+	refresher  *secrets.Refresher
+}
+
+// fatal logs err and exits
+func fatal(msg string, err error) {
+	logger.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// registerReadinessChecks adds the dependency checks behind /readyz. Only the
+// database fails readiness; the other dependencies degrade the instance but
+// every instance shares them, so taking it out of rotation would not help.
+func registerReadinessChecks(readiness *health.Registry, db *gorm.DB, apiGateway *gateway.Gateway, responseCache *cache.Cache) error {
+	return errors.Join(
+		readiness.Register(health.Checker{
+			Name:     "database",
+			Check:    func(ctx context.Context) error { return database.Ping(ctx, db) },
+			Critical: true,
+		}),
+		readiness.Register(health.Checker{
+			Name:     "database_schema",
+			Check:    func(ctx context.Context) error { return database.CheckSchema(ctx, db) },
+			CacheFor: time.Minute,
+			Critical: true,
+		}),
+		// The checks below read in-memory state, so they run on every probe
+		readiness.Register(health.Checker{
+			Name:     "tracing_exporter",
+			Check:    observability.CheckExporter,
+			CacheFor: -1,
+		}),
+		readiness.Register(health.Checker{
+			Name:     "gateway_upstreams",
+			Check:    apiGateway.CheckUpstreams,
+			CacheFor: -1,
+		}),
+		readiness.Register(health.Checker{
+			Name:  "response_cache",
+			Check: responseCache.Ping,
+		}),
+	)
+}
+
+// credentialRevoked reports whether the credential of an open gateway stream
+// is no longer accepted: a JWT that was logged out, has expired or was
+// signed with a retired key, or an API key that was deleted. Lookup errors
+// keep the stream open.
+func credentialRevoked(signingKeys *auth.SigningKeys, apiKeys repository.APIKeyRepository) gateway.RevocationCheck {
+	return func(ctx context.Context, credential gateway.Credential) bool {
+		switch credential.Method {
+		case "jwt":
+			_, err := signingKeys.Authenticate(credential.Token)
+			return err != nil
+		case "api_key":
+			_, err := apiKeys.FindByID(ctx, credential.APIKeyID)
+			return errors.Is(err, repository.ErrNotFound)
+		}
+		return false
+	}
+}
+
+// loadSecrets resolves the configured secret references and prepares their
+// periodic refresh, so the JWT key and DB password can rotate without a restart
+func loadSecrets(ctx context.Context, cfg *config.Config) (*runtimeSecrets, error) {
+	resolver := secrets.NewResolver()
+	var refreshed []*secrets.Value
+
+	if cfg.Secrets.Vault.Address != "" {
+		vaultToken, err := resolver.Value(ctx, cfg.Secrets.Vault.Token)
+		if err != nil {
+			return nil, err
+		}
+		resolver.Register("vault", secrets.NewVaultProvider(
+			cfg.Secrets.Vault.Address, cfg.Secrets.Vault.Mount, vaultToken.Get))
+		refreshed = append(refreshed, vaultToken)
+	}
+
+	jwtSecret, err := resolver.Value(ctx, cfg.JWT.Secret)
+	if err != nil {
+		return nil, err
+	}
+	dbPassword, err := resolver.Value(ctx, cfg.Database.Password)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.ValidateSecrets(jwtSecret.Get(), dbPassword.Get()); err != nil {
+		return nil, err
+	}
+
+	refreshed = append(refreshed, jwtSecret, dbPassword)
+	return &runtimeSecrets{
+		jwtSecret:  jwtSecret,
+		dbPassword: dbPassword,
+		refresher:  secrets.NewRefresher(cfg.Secrets.RefreshInterval, refreshed...),
+	}, nil
+}
+
+// handleConfigReload applies the reloadable settings to the running
+// middleware and reports every reload attempt as a metric and audit event
+func handleConfigReload(event config.ReloadEvent, rateLimiter *middleware.RateLimiter, cors *middleware.CORS,
+	routeRegistry *gateway.Registry, auditLog *audit.Logger) {
+	details := map[string]any{"trigger": event.Trigger}
+	outcome := audit.OutcomeSuccess
+
+	// The routes were checked with the rest of the config, but the stored
+	// routes may have changed since; nothing is applied if they no longer fit
+	err := event.Err
+	if err == nil {
+		if err = routeRegistry.SetStatic(context.Background(), event.Current.Gateway.Routes); err != nil {
+			err = fmt.Errorf("gateway routes: %w", err)
+		}
+	}
+
+	if err == nil {
+		cfg := event.Current
+		rateLimiter.Update(rate.Limit(cfg.RateLimit.RequestsPerSecond), cfg.RateLimit.Burst)
+		cors.Update(cfg.CORS.AllowedOrigins)
+		observability.SetSampleRatio(cfg.Tracing.SampleRatio)
+		// The level was validated with the rest of the config
+		logging.SetLevels(cfg.Log.Level, cfg.Log.Packages)
+
+		observability.ConfigLastReloadSuccess.SetToCurrentTime()
+		if len(event.RestartRequired) > 0 {
+			details["restart_required"] = event.RestartRequired
+			logger.Warn("Configuration reloaded; some changes take effect after a restart",
+				"trigger", event.Trigger, "restart_required", event.RestartRequired)
+		} else {
+			logger.Info("Configuration reloaded", "trigger", event.Trigger)
+		}
+	} else {
+		outcome = audit.OutcomeFailure
+		details["error"] = err.Error()
+		logger.Error("Configuration reload rejected, keeping previous configuration", "trigger", event.Trigger, "error", err)
+	}
+
+	observability.ConfigReloads.WithLabelValues(event.Trigger, outcome).Inc()
+	auditLog.Record(audit.Event{
+		Action:  "config.reload",
+		Actor:   "system",
+		Outcome: outcome,
+		Details: details,
+	})
 }
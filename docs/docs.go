@@ -22,9 +22,14 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/auth/login": {
+        "/admin/cache/purge": {
             "post": {
-                "description": "Authenticate user and return JWT token",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Remove the cached responses of a gateway route, or those carrying a tag. Exactly one of route and tag must be set. Served on the admin listener.",
                 "consumes": [
                     "application/json"
                 ],
@@ -32,79 +37,92 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Login user",
+                "summary": "Purge cached responses",
                 "parameters": [
                     {
-                        "description": "Login credentials",
-                        "name": "credentials",
+                        "description": "Route or tag to purge",
+                        "name": "purge",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/pkg_handlers.LoginInput"
+                            "$ref": "#/definitions/pkg_handlers.PurgeCacheInput"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Login successful with token",
+                        "description": "Number of entries purged",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": true
+                            "additionalProperties": {
+                                "type": "integer"
+                            }
                         }
                     },
-                    "401": {
-                        "description": "Invalid credentials",
+                    "400": {
+                        "description": "Invalid input",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": true
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
             }
         },
-        "/auth/logout": {
-            "post": {
+        "/admin/routes": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Invalidate the current JWT token",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "List every route in effect, from the config file and the registry. Served on the admin listener.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Logout user",
+                "summary": "List gateway routes",
                 "responses": {
                     "200": {
-                        "description": "Logout successful",
+                        "description": "Routes",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "403": {
+                        "description": "Access denied",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": true
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
-            }
-        },
-        "/auth/register": {
+            },
             "post": {
-                "description": "Register a new user with username, email, and password",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Store a new route and put it into effect. The body uses the keys of a gateway route in the config file. Served on the admin listener.",
                 "consumes": [
                     "application/json"
                 ],
@@ -112,80 +130,97 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Register a new user",
+                "summary": "Create gateway route",
                 "parameters": [
                     {
-                        "description": "User registration details",
-                        "name": "user",
+                        "description": "Route definition",
+                        "name": "route",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/pkg_handlers.RegisterInput"
+                            "$ref": "#/definitions/apisecurityplatform_pkg_config.RouteConfig"
                         }
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "User registered successfully",
+                        "description": "Route created",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/apisecurityplatform_pkg_gateway.RouteEntry"
                         }
                     },
                     "400": {
-                        "description": "Invalid input",
+                        "description": "Invalid route",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": true
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Route already exists",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
             }
         },
-        "/users/api-keys": {
+        "/admin/routes/{name}": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get all API keys for the authenticated user",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Get a route in effect by name. Served on the admin listener.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "api-keys"
+                    "admin"
+                ],
+                "summary": "Get gateway route",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Route name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "List API keys",
                 "responses": {
                     "200": {
-                        "description": "List of API keys",
+                        "description": "Route",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/apisecurityplatform_pkg_gateway.RouteEntry"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "404": {
+                        "description": "Route not found",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": true
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
             },
-            "post": {
+            "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Generate a new API key for the authenticated user",
+                "description": "Replace the definition of a stored route, recording a new version. Served on the admin listener.",
                 "consumes": [
                     "application/json"
                 ],
@@ -193,105 +228,107 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "api-keys"
+                    "admin"
                 ],
-                "summary": "Create API key",
+                "summary": "Update gateway route",
                 "parameters": [
                     {
-                        "description": "API key details",
-                        "name": "key",
+                        "type": "string",
+                        "description": "Route name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Route definition",
+                        "name": "route",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/pkg_handlers.CreateAPIKeyInput"
+                            "$ref": "#/definitions/apisecurityplatform_pkg_config.RouteConfig"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "API key created successfully",
+                    "200": {
+                        "description": "Route updated",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/apisecurityplatform_pkg_gateway.RouteEntry"
                         }
                     },
                     "400": {
-                        "description": "Invalid input",
+                        "description": "Invalid route",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": true
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "404": {
+                        "description": "Route not found",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": true
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
-            }
-        },
-        "/users/api-keys/{id}": {
+            },
             "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Delete a specific API key",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Remove a stored route. Its history is kept for rollback. Served on the admin listener.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "api-keys"
+                    "admin"
                 ],
-                "summary": "Delete API key",
+                "summary": "Delete gateway route",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "API key ID",
-                        "name": "id",
+                        "description": "Route name",
+                        "name": "name",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "API key deleted successfully",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": true
-                        }
-                    },
-                    "401": {
-                        "description": "Unauthorized",
+                        "description": "Route deleted",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": true
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "404": {
-                        "description": "API key not found",
+                        "description": "Route not found",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": true
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
             }
         },
-        "/users/me": {
-            "get": {
+        "/admin/routes/{name}/rollback": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get the profile of the authenticated user",
+                "description": "Restore the definition a route had at an earlier version, recorded as a new version. Served on the admin listener.",
                 "consumes": [
                     "application/json"
                 ],
@@ -299,75 +336,123 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "admin"
+                ],
+                "summary": "Roll back gateway route",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Route name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Version to restore",
+                        "name": "rollback",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/pkg_handlers.RollbackRouteInput"
+                        }
+                    }
                 ],
-                "summary": "Get user profile",
                 "responses": {
                     "200": {
-                        "description": "User profile",
+                        "description": "Route rolled back",
+                        "schema": {
+                            "$ref": "#/definitions/apisecurityplatform_pkg_gateway.RouteEntry"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": true
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "404": {
+                        "description": "Version not found",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": true
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
             }
         },
-        "/users/{id}": {
-            "delete": {
+        "/admin/routes/{name}/versions": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Delete a user (admin only)",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "List every recorded version of a stored route. Served on the admin listener.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "admin"
                 ],
-                "summary": "Delete user",
+                "summary": "List gateway route versions",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "User ID",
-                        "name": "id",
+                        "type": "string",
+                        "description": "Route name",
+                        "name": "name",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "User deleted successfully",
+                        "description": "Versions",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
                         }
                     },
-                    "403": {
-                        "description": "Access denied",
+                    "404": {
+                        "description": "Route not found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
                                 "type": "string"
                             }
                         }
+                    }
+                }
+            }
+        },
+        "/admin/slo": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Error budget consumption of each configured SLO, computed from the http_requests_total and http_server_duration_seconds series of this instance since it started. Served on the admin listener.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get SLO status",
+                "responses": {
+                    "200": {
+                        "description": "Error budgets",
+                        "schema": {
+                            "$ref": "#/definitions/apisecurityplatform_pkg_slo.Report"
+                        }
                     },
-                    "404": {
-                        "description": "User not found",
+                    "403": {
+                        "description": "Access denied",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -377,20 +462,1575 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "pkg_handlers.CreateAPIKeyInput": {
-            "type": "object",
-            "required": [
-                "name"
-            ],
-            "properties": {
-                "description": {
+        },
+        "/admin/slo/dashboard": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Grafana dashboard charting each SLO's error budget, burn rate and SLIs from the series recorded by the generated rules. Served on the admin listener.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get SLO dashboard",
+                "responses": {
+                    "200": {
+                        "description": "Grafana dashboard",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/slo/rules": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Prometheus rules file recording each SLO's error ratios and alerting on multi-window error budget burn. Served on the admin listener.",
+                "produces": [
+                    "application/yaml"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get SLO alerting rules",
+                "responses": {
+                    "200": {
+                        "description": "Prometheus rules file",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/usage": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Requests, errors and bytes metered per user, API key and route, in hourly or daily periods. Usage is written in batches, so the latest requests may take up to the flush interval to appear. Served on the admin listener.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get usage",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Start of the period, RFC 3339 (default 24 hours before to)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End of the period, RFC 3339 (default now)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "hour or day (default hour)",
+                        "name": "granularity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only usage of this user",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only usage made with this API key",
+                        "name": "api_key_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only usage of this route",
+                        "name": "route",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Usage",
+                        "schema": {
+                            "$ref": "#/definitions/pkg_handlers.UsageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/login": {
+            "post": {
+                "description": "Authenticate user and return JWT token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Login user",
+                "parameters": [
+                    {
+                        "description": "Login credentials",
+                        "name": "credentials",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/pkg_handlers.LoginInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Login successful with token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid credentials",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/logout": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Invalidate the current JWT token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Logout user",
+                "responses": {
+                    "200": {
+                        "description": "Logout successful",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/register": {
+            "post": {
+                "description": "Register a new user with username, email, and password",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Register a new user",
+                "parameters": [
+                    {
+                        "description": "User registration details",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/pkg_handlers.RegisterInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "User registered successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/debug/config": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "The configuration in effect, including reloaded changes, with secrets and the header and query values set by gateway routes redacted. Durations are in nanoseconds. Served on the admin listener.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Dump configuration",
+                "responses": {
+                    "200": {
+                        "description": "Configuration",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/debug/goroutines": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Stack traces of every goroutine, in the format of an unrecovered panic. Served on the admin listener.",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Dump goroutines",
+                "responses": {
+                    "200": {
+                        "description": "Goroutine stacks",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/debug/log-level": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "The default log level and the levels of single packages. Served on the admin listener.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get log levels",
+                "responses": {
+                    "200": {
+                        "description": "Log levels",
+                        "schema": {
+                            "$ref": "#/definitions/pkg_handlers.LogLevelResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Change the default log level, and the package levels when given, until the next restart or configuration reload. Served on the admin listener.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Set log levels",
+                "parameters": [
+                    {
+                        "description": "Log levels",
+                        "name": "levels",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/pkg_handlers.LogLevelInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Log levels",
+                        "schema": {
+                            "$ref": "#/definitions/pkg_handlers.LogLevelResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid level",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/debug/profiles": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Profiles captured on this instance, newest first. Served on the admin listener.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List profiles",
+                "responses": {
+                    "200": {
+                        "description": "Profiles",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/apisecurityplatform_pkg_observability.Profile"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Capture a CPU or heap profile of this instance for download. A heap profile is complete on return; a CPU profile samples for the given duration in the background. Only the latest profiles are kept. Served on the admin listener.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Capture a profile",
+                "parameters": [
+                    {
+                        "description": "Profile kind (cpu or heap) and duration",
+                        "name": "profile",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/pkg_handlers.CaptureProfileInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Profile started",
+                        "schema": {
+                            "$ref": "#/definitions/apisecurityplatform_pkg_observability.Profile"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "A CPU profile is already running",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/debug/profiles/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Download a completed profile in the gzipped protobuf format read by go tool pprof. Served on the admin listener.",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Download a profile",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Profile ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Profile",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Profile not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Profile is not completed",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/livez": {
+            "get": {
+                "description": "Reports whether the process is running and able to serve requests. It does not check dependencies, so a database outage does not get instances restarted. With ?verbose=true on the admin listener, the result of each check is included.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Liveness probe",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Include the result of each check (admin listener only)",
+                        "name": "verbose",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Live",
+                        "schema": {
+                            "$ref": "#/definitions/apisecurityplatform_pkg_health.Report"
+                        }
+                    },
+                    "503": {
+                        "description": "Not live",
+                        "schema": {
+                            "$ref": "#/definitions/apisecurityplatform_pkg_health.Report"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Reports whether the instance can take traffic: the database answers and its schema matches this release. The tracing exporter, gateway upstreams and response cache are reported as degraded without failing the probe. Results are cached for a few seconds. With ?verbose=true on the admin listener, the result of each check is included.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Readiness probe",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Include the result of each check (admin listener only)",
+                        "name": "verbose",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Ready",
+                        "schema": {
+                            "$ref": "#/definitions/apisecurityplatform_pkg_health.Report"
+                        }
+                    },
+                    "503": {
+                        "description": "Not ready",
+                        "schema": {
+                            "$ref": "#/definitions/apisecurityplatform_pkg_health.Report"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/api-keys": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get all API keys for the authenticated user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api-keys"
+                ],
+                "summary": "List API keys",
+                "responses": {
+                    "200": {
+                        "description": "List of API keys",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Generate a new API key for the authenticated user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api-keys"
+                ],
+                "summary": "Create API key",
+                "parameters": [
+                    {
+                        "description": "API key details",
+                        "name": "key",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/pkg_handlers.CreateAPIKeyInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "API key created successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/users/api-keys/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete a specific API key",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api-keys"
+                ],
+                "summary": "Delete API key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "API key deleted successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "API key not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the profile of the authenticated user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get user profile",
+                "responses": {
+                    "200": {
+                        "description": "User profile",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/users/usage": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Requests, errors and bytes metered for the authenticated user, per route and API key, in hourly or daily periods. Usage is written in batches, so the latest requests may take up to the flush interval to appear.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get own usage",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Start of the period, RFC 3339 (default 24 hours before to)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End of the period, RFC 3339 (default now)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "hour or day (default hour)",
+                        "name": "granularity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only usage made with this API key",
+                        "name": "api_key_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only usage of this route",
+                        "name": "route",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Usage",
+                        "schema": {
+                            "$ref": "#/definitions/pkg_handlers.UsageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete a user (admin only)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Delete user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User deleted successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "apisecurityplatform_pkg_config.CircuitBreakerConfig": {
+            "type": "object",
+            "properties": {
+                "failure_ratio": {
+                    "type": "number"
+                },
+                "min_requests": {
+                    "type": "integer"
+                },
+                "open_timeout": {
+                    "description": "OpenTimeout is how long the breaker stays open before letting a probe through",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/time.Duration"
+                        }
+                    ]
+                },
+                "window": {
+                    "$ref": "#/definitions/time.Duration"
+                }
+            }
+        },
+        "apisecurityplatform_pkg_config.HeaderTransformConfig": {
+            "type": "object",
+            "properties": {
+                "remove": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "set": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apisecurityplatform_pkg_config.NameValueConfig"
+                    }
+                }
+            }
+        },
+        "apisecurityplatform_pkg_config.HealthCheckConfig": {
+            "type": "object",
+            "properties": {
+                "healthy_threshold": {
+                    "type": "integer"
+                },
+                "interval": {
+                    "$ref": "#/definitions/time.Duration"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "timeout": {
+                    "$ref": "#/definitions/time.Duration"
+                },
+                "unhealthy_threshold": {
+                    "type": "integer"
+                }
+            }
+        },
+        "apisecurityplatform_pkg_config.MaskRuleConfig": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "description": "Action is \"remove\", the default, or \"redact\", which keeps the field\nwith a placeholder value",
+                    "type": "string"
+                },
+                "field": {
+                    "type": "string"
+                },
+                "unless_scope": {
+                    "description": "UnlessScope exempts callers whose API key has this scope",
+                    "type": "string"
+                }
+            }
+        },
+        "apisecurityplatform_pkg_config.NameValueConfig": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "value": {
+                    "type": "string"
+                }
+            }
+        },
+        "apisecurityplatform_pkg_config.OpenAPIConfig": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "max_body_bytes": {
+                    "description": "MaxBodyBytes is the largest request body accepted for validation",
+                    "type": "integer"
+                },
+                "spec": {
+                    "description": "Spec is the path of an OpenAPI 3 or Swagger 2 document, in JSON or\nYAML. The platform's own endpoints default to the generated docs.",
+                    "type": "string"
+                },
+                "validate_responses": {
+                    "description": "ValidateResponses logs responses that drift from the document. It\nbuffers response bodies, so it is meant for staging.",
+                    "type": "boolean"
+                }
+            }
+        },
+        "apisecurityplatform_pkg_config.OutlierDetectionConfig": {
+            "type": "object",
+            "properties": {
+                "base_ejection_time": {
+                    "$ref": "#/definitions/time.Duration"
+                },
+                "consecutive_failures": {
+                    "type": "integer"
+                },
+                "max_ejection_percent": {
+                    "description": "MaxEjectionPercent caps the share of a route's upstreams ejected at once",
+                    "type": "integer"
+                }
+            }
+        },
+        "apisecurityplatform_pkg_config.PathRewriteConfig": {
+            "type": "object",
+            "properties": {
+                "pattern": {
+                    "type": "string"
+                },
+                "replacement": {
+                    "type": "string"
+                }
+            }
+        },
+        "apisecurityplatform_pkg_config.QueryRenameConfig": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "apisecurityplatform_pkg_config.QueryTransformConfig": {
+            "type": "object",
+            "properties": {
+                "remove": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "rename": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apisecurityplatform_pkg_config.QueryRenameConfig"
+                    }
+                },
+                "set": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apisecurityplatform_pkg_config.NameValueConfig"
+                    }
+                }
+            }
+        },
+        "apisecurityplatform_pkg_config.RequestTransformConfig": {
+            "type": "object",
+            "properties": {
+                "headers": {
+                    "$ref": "#/definitions/apisecurityplatform_pkg_config.HeaderTransformConfig"
+                },
+                "path": {
+                    "$ref": "#/definitions/apisecurityplatform_pkg_config.PathRewriteConfig"
+                },
+                "query": {
+                    "$ref": "#/definitions/apisecurityplatform_pkg_config.QueryTransformConfig"
+                }
+            }
+        },
+        "apisecurityplatform_pkg_config.ResponseTransformConfig": {
+            "type": "object",
+            "properties": {
+                "headers": {
+                    "$ref": "#/definitions/apisecurityplatform_pkg_config.HeaderTransformConfig"
+                },
+                "mask": {
+                    "description": "Mask removes or redacts fields of JSON bodies",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apisecurityplatform_pkg_config.MaskRuleConfig"
+                    }
+                },
+                "max_body_bytes": {
+                    "description": "MaxBodyBytes is the largest body that is masked; larger responses\nare refused rather than passed through unmasked",
+                    "type": "integer"
+                }
+            }
+        },
+        "apisecurityplatform_pkg_config.RetryConfig": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "description": "Attempts is the total number of tries, so 1 disables retries",
+                    "type": "integer"
+                },
+                "budget_ratio": {
+                    "description": "BudgetRatio caps retries as a share of the route's requests",
+                    "type": "number"
+                },
+                "min_retries_per_second": {
+                    "description": "MinRetriesPerSecond is allowed regardless of the ratio, for low-traffic routes",
+                    "type": "integer"
+                }
+            }
+        },
+        "apisecurityplatform_pkg_config.RouteCacheConfig": {
+            "type": "object",
+            "properties": {
+                "default_ttl": {
+                    "description": "DefaultTTL applies to responses without explicit freshness; 0 leaves them uncached",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/time.Duration"
+                        }
+                    ]
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "key_by": {
+                    "description": "KeyBy scopes entries to the caller: \"user\" and/or \"api_key\"",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "max_ttl": {
+                    "description": "MaxTTL caps the freshness of any response; 0 means no cap",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/time.Duration"
+                        }
+                    ]
+                },
+                "tags": {
+                    "description": "Tags are attached to every entry of the route, for purging",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "apisecurityplatform_pkg_config.RouteConfig": {
+            "type": "object",
+            "properties": {
+                "auth": {
+                    "description": "Auth is the credential required: \"jwt\", \"api_key\", \"any\" or \"none\"",
+                    "type": "string"
+                },
+                "cache": {
+                    "$ref": "#/definitions/apisecurityplatform_pkg_config.RouteCacheConfig"
+                },
+                "circuit_breaker": {
+                    "$ref": "#/definitions/apisecurityplatform_pkg_config.CircuitBreakerConfig"
+                },
+                "health_check": {
+                    "$ref": "#/definitions/apisecurityplatform_pkg_config.HealthCheckConfig"
+                },
+                "load_balancing": {
+                    "description": "LoadBalancing is \"round_robin\", \"least_connections\" or \"weighted\"",
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "openapi": {
+                    "description": "OpenAPI validates requests before they are transformed. With\nStripPrefix, paths in the document are relative to PathPrefix.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/apisecurityplatform_pkg_config.OpenAPIConfig"
+                        }
+                    ]
+                },
+                "outlier_detection": {
+                    "$ref": "#/definitions/apisecurityplatform_pkg_config.OutlierDetectionConfig"
+                },
+                "path_prefix": {
+                    "type": "string"
+                },
+                "protocol": {
+                    "description": "Protocol is \"http\" or \"grpc\". gRPC routes reach http upstreams over\ncleartext HTTP/2 (h2c).",
+                    "type": "string"
+                },
+                "retry": {
+                    "$ref": "#/definitions/apisecurityplatform_pkg_config.RetryConfig"
+                },
+                "streaming": {
+                    "$ref": "#/definitions/apisecurityplatform_pkg_config.StreamingConfig"
+                },
+                "strip_prefix": {
+                    "description": "StripPrefix removes PathPrefix before forwarding",
+                    "type": "boolean"
+                },
+                "timeout": {
+                    "description": "Timeout bounds each attempt against an upstream, retries included",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/time.Duration"
+                        }
+                    ]
+                },
+                "transform": {
+                    "$ref": "#/definitions/apisecurityplatform_pkg_config.TransformConfig"
+                },
+                "upstreams": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apisecurityplatform_pkg_config.UpstreamConfig"
+                    }
+                }
+            }
+        },
+        "apisecurityplatform_pkg_config.StreamingConfig": {
+            "type": "object",
+            "properties": {
+                "max_message_bytes": {
+                    "description": "MaxMessageBytes caps the WebSocket messages a client sends; a larger\nmessage closes the connection",
+                    "type": "integer"
+                },
+                "message_burst": {
+                    "type": "integer"
+                },
+                "messages_per_second": {
+                    "description": "MessagesPerSecond throttles the WebSocket messages a client sends on\neach connection; 0 disables the limit",
+                    "type": "number"
+                },
+                "revocation_check_interval": {
+                    "description": "RevocationCheckInterval is how often the credential of an open stream\nis checked, so the stream can be closed once it is revoked",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/time.Duration"
+                        }
+                    ]
+                }
+            }
+        },
+        "apisecurityplatform_pkg_config.TransformConfig": {
+            "type": "object",
+            "properties": {
+                "request": {
+                    "$ref": "#/definitions/apisecurityplatform_pkg_config.RequestTransformConfig"
+                },
+                "response": {
+                    "$ref": "#/definitions/apisecurityplatform_pkg_config.ResponseTransformConfig"
+                }
+            }
+        },
+        "apisecurityplatform_pkg_config.UpstreamConfig": {
+            "type": "object",
+            "properties": {
+                "url": {
+                    "type": "string"
+                },
+                "weight": {
+                    "description": "Weight is only used by the weighted strategy",
+                    "type": "integer"
+                }
+            }
+        },
+        "apisecurityplatform_pkg_gateway.RouteEntry": {
+            "type": "object",
+            "properties": {
+                "definition": {
+                    "$ref": "#/definitions/apisecurityplatform_pkg_config.RouteConfig"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "source": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "version": {
+                    "description": "Version and UpdatedAt are only set for registry routes",
+                    "type": "integer"
+                }
+            }
+        },
+        "apisecurityplatform_pkg_health.Report": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apisecurityplatform_pkg_health.Result"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "apisecurityplatform_pkg_health.Result": {
+            "type": "object",
+            "properties": {
+                "cached": {
+                    "description": "Cached is set when the result was reused from an earlier run",
+                    "type": "boolean"
+                },
+                "checked_at": {
+                    "type": "string"
+                },
+                "critical": {
+                    "type": "boolean"
+                },
+                "duration_ms": {
+                    "type": "number"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "apisecurityplatform_pkg_observability.Profile": {
+            "type": "object",
+            "properties": {
+                "duration": {
+                    "description": "Duration is how long a CPU profile samples for",
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "apisecurityplatform_pkg_slo.ObjectiveReport": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "route": {
+                    "type": "string"
+                },
+                "slis": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apisecurityplatform_pkg_slo.SLIReport"
+                    }
+                }
+            }
+        },
+        "apisecurityplatform_pkg_slo.Report": {
+            "type": "object",
+            "properties": {
+                "objectives": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apisecurityplatform_pkg_slo.ObjectiveReport"
+                    }
+                },
+                "since": {
+                    "description": "Since is when the instance started counting",
+                    "type": "string"
+                }
+            }
+        },
+        "apisecurityplatform_pkg_slo.SLIReport": {
+            "type": "object",
+            "properties": {
+                "bad": {
+                    "type": "number"
+                },
+                "budget_consumed": {
+                    "description": "BudgetConsumed is the fraction of the error budget spent, above 1\nonce the objective is missed",
+                    "type": "number"
+                },
+                "budget_remaining": {
+                    "type": "number"
+                },
+                "error_ratio": {
+                    "description": "ErrorRatio is the fraction of requests that failed",
+                    "type": "number"
+                },
+                "objective": {
+                    "description": "Objective is the fraction of requests that must pass",
+                    "type": "number"
+                },
+                "requests": {
+                    "type": "number"
+                },
+                "sli": {
+                    "type": "string"
+                },
+                "threshold": {
+                    "description": "Threshold is the latency objective, for the latency SLI",
+                    "type": "string"
+                }
+            }
+        },
+        "pkg_handlers.CaptureProfileInput": {
+            "type": "object",
+            "required": [
+                "kind"
+            ],
+            "properties": {
+                "duration": {
+                    "description": "Duration of a CPU profile, 30s by default",
+                    "type": "string",
+                    "example": "30s"
+                },
+                "kind": {
+                    "type": "string",
+                    "example": "cpu"
+                }
+            }
+        },
+        "pkg_handlers.CreateAPIKeyInput": {
+            "type": "object",
+            "required": [
+                "name",
+                "scopes"
+            ],
+            "properties": {
+                "description": {
                     "type": "string"
                 },
                 "name": {
                     "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "pkg_handlers.LogLevelInput": {
+            "type": "object",
+            "required": [
+                "level"
+            ],
+            "properties": {
+                "level": {
+                    "type": "string",
+                    "example": "debug"
+                },
+                "packages": {
+                    "description": "Packages replaces the package levels when set",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "pkg_handlers.LogLevelResponse": {
+            "type": "object",
+            "properties": {
+                "level": {
+                    "type": "string",
+                    "example": "info"
+                },
+                "packages": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
                 }
             }
         },
@@ -409,6 +2049,17 @@ const docTemplate = `{
                 }
             }
         },
+        "pkg_handlers.PurgeCacheInput": {
+            "type": "object",
+            "properties": {
+                "route": {
+                    "type": "string"
+                },
+                "tag": {
+                    "type": "string"
+                }
+            }
+        },
         "pkg_handlers.RegisterInput": {
             "type": "object",
             "required": [
@@ -428,6 +2079,160 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "pkg_handlers.RollbackRouteInput": {
+            "type": "object",
+            "required": [
+                "version"
+            ],
+            "properties": {
+                "version": {
+                    "type": "integer",
+                    "minimum": 1
+                }
+            }
+        },
+        "pkg_handlers.UsageEntry": {
+            "type": "object",
+            "properties": {
+                "api_key_id": {
+                    "type": "integer"
+                },
+                "bytes_in": {
+                    "type": "integer"
+                },
+                "bytes_out": {
+                    "type": "integer"
+                },
+                "client_errors": {
+                    "type": "integer"
+                },
+                "period": {
+                    "type": "string"
+                },
+                "requests": {
+                    "type": "integer"
+                },
+                "route": {
+                    "type": "string"
+                },
+                "server_errors": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "pkg_handlers.UsageResponse": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string"
+                },
+                "granularity": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "totals": {
+                    "$ref": "#/definitions/pkg_handlers.UsageTotals"
+                },
+                "usage": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/pkg_handlers.UsageEntry"
+                    }
+                }
+            }
+        },
+        "pkg_handlers.UsageTotals": {
+            "type": "object",
+            "properties": {
+                "bytes_in": {
+                    "type": "integer"
+                },
+                "bytes_out": {
+                    "type": "integer"
+                },
+                "client_errors": {
+                    "type": "integer"
+                },
+                "requests": {
+                    "type": "integer"
+                },
+                "server_errors": {
+                    "type": "integer"
+                }
+            }
+        },
+        "time.Duration": {
+            "type": "integer",
+            "enum": [
+                -9223372036854775808,
+                9223372036854775807,
+                1,
+                1000,
+                1000000,
+                1000000000,
+                60000000000,
+                3600000000000,
+                -9223372036854775808,
+                9223372036854775807,
+                1,
+                1000,
+                1000000,
+                1000000000,
+                60000000000,
+                3600000000000,
+                -9223372036854775808,
+                9223372036854775807,
+                1,
+                1000,
+                1000000,
+                1000000000,
+                60000000000,
+                3600000000000,
+                1,
+                1000,
+                1000000,
+                1000000000,
+                60000000000,
+                3600000000000
+            ],
+            "x-enum-varnames": [
+                "minDuration",
+                "maxDuration",
+                "Nanosecond",
+                "Microsecond",
+                "Millisecond",
+                "Second",
+                "Minute",
+                "Hour",
+                "minDuration",
+                "maxDuration",
+                "Nanosecond",
+                "Microsecond",
+                "Millisecond",
+                "Second",
+                "Minute",
+                "Hour",
+                "minDuration",
+                "maxDuration",
+                "Nanosecond",
+                "Microsecond",
+                "Millisecond",
+                "Second",
+                "Minute",
+                "Hour",
+                "Nanosecond",
+                "Microsecond",
+                "Millisecond",
+                "Second",
+                "Minute",
+                "Hour"
+            ]
         }
     },
     "securityDefinitions": {
@@ -445,6 +2250,14 @@ const docTemplate = `{
         {
             "description": "User operations",
             "name": "users"
+        },
+        {
+            "description": "Liveness and readiness probes",
+            "name": "health"
+        },
+        {
+            "description": "Gateway administration, served on the admin listener",
+            "name": "admin"
         }
     ]
 }`
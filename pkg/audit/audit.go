@@ -0,0 +1,48 @@
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Outcomes recorded on audit events
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Event is a single security relevant action recorded in the audit trail
+type Event struct {
+	Time    time.Time      `json:"time"`
+	Action  string         `json:"action"`
+	Actor   string         `json:"actor"`
+	Outcome string         `json:"outcome"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// Logger writes audit events as JSON lines
+type Logger struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewLogger creates a Logger writing to w
+func NewLogger(w io.Writer) *Logger {
+	return &Logger{enc: json.NewEncoder(w)}
+}
+
+// Record writes the event, stamping the current time if none is set
+func (l *Logger) Record(event Event) error {
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.enc.Encode(struct {
+		Audit bool `json:"audit"`
+		Event
+	}{true, event})
+}
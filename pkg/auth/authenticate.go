@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"apisecurityplatform/pkg/models"
+	"apisecurityplatform/pkg/observability"
+	"apisecurityplatform/pkg/repository"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Credential errors. Their messages are safe to return to the caller.
+var (
+	ErrMissingToken       = errors.New("Authorization header is required")
+	ErrInvalidTokenFormat = errors.New("Invalid authorization header format")
+	ErrTokenInvalidated   = errors.New("Token has been invalidated")
+	ErrInvalidToken       = errors.New("Invalid token")
+	ErrInvalidClaims      = errors.New("Invalid token claims")
+	ErrMissingAPIKey      = errors.New("API key is required")
+	ErrInvalidAPIKey      = errors.New("Invalid API key")
+)
+
+// Claims is the identity carried by a platform JWT
+type Claims struct {
+	UserID uint
+	Email  string
+	Role   string
+}
+
+// BearerToken extracts the token from an Authorization header value
+func BearerToken(header string) (string, error) {
+	if header == "" {
+		return "", ErrMissingToken
+	}
+	parts := strings.Split(header, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return "", ErrInvalidTokenFormat
+	}
+	return parts[1], nil
+}
+
+// Authenticate verifies a JWT that has not been logged out and returns its claims
+func (k *SigningKeys) Authenticate(tokenString string) (*Claims, error) {
+	if GetBlacklist().IsBlacklisted(tokenString) {
+		return nil, ErrTokenInvalidated
+	}
+	token, err := k.ParseToken(tokenString)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalidClaims
+	}
+	userID, okID := claims["user_id"].(float64)
+	email, okEmail := claims["email"].(string)
+	role, okRole := claims["role"].(string)
+	if !okID || !okEmail || !okRole {
+		return nil, ErrInvalidClaims
+	}
+	return &Claims{UserID: uint(userID), Email: email, Role: role}, nil
+}
+
+// AuthenticateAPIKey finds the stored key matching key and records its use.
+// Errors other than ErrInvalidAPIKey mean the key could not be checked.
+func AuthenticateAPIKey(ctx context.Context, keys repository.APIKeyRepository, key string) (*models.APIKey, error) {
+	if key == "" {
+		return nil, ErrMissingAPIKey
+	}
+
+	// Keys are stored as bcrypt hashes, so each one has to be compared
+	apiKeys, err := keys.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list API keys: %w", err)
+	}
+	var storedKey *models.APIKey
+	for i := range apiKeys {
+		if err := bcrypt.CompareHashAndPassword([]byte(apiKeys[i].Key), []byte(key)); err == nil {
+			storedKey = &apiKeys[i]
+			break
+		}
+	}
+	if storedKey == nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now().Unix()
+	storedKey.LastUsedAt = &now
+	keys.UpdateLastUsed(ctx, storedKey.ID, now)
+	return storedKey, nil
+}
+
+// Authentication methods, as recorded by RecordAttempt
+const (
+	MethodPassword   = "password"
+	MethodJWT        = "jwt"
+	MethodAPIKey     = "api_key"
+	MethodClientCert = "client_cert"
+)
+
+// Outcomes of an authentication attempt
+const (
+	OutcomeSuccess = "success"
+	OutcomeMissing = "missing"
+	OutcomeInvalid = "invalid"
+	OutcomeRevoked = "revoked"
+	OutcomeError   = "error"
+)
+
+// Outcome classifies the error returned by an authentication step
+func Outcome(err error) string {
+	switch {
+	case err == nil:
+		return OutcomeSuccess
+	case errors.Is(err, ErrMissingToken), errors.Is(err, ErrMissingAPIKey):
+		return OutcomeMissing
+	case errors.Is(err, ErrTokenInvalidated):
+		return OutcomeRevoked
+	case errors.Is(err, ErrInvalidTokenFormat), errors.Is(err, ErrInvalidToken),
+		errors.Is(err, ErrInvalidClaims), errors.Is(err, ErrInvalidAPIKey):
+		return OutcomeInvalid
+	default:
+		return OutcomeError
+	}
+}
+
+// RecordAttempt counts an authentication attempt by method and the outcome of
+// err, and adds it as an event to the span of ctx
+func RecordAttempt(ctx context.Context, method string, err error) {
+	outcome := Outcome(err)
+	observability.AuthAttempts.WithLabelValues(method, outcome).Inc()
+	trace.SpanFromContext(ctx).AddEvent("auth.authenticate", trace.WithAttributes(
+		attribute.String("auth.method", method),
+		attribute.String("auth.outcome", outcome),
+	))
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SigningKeys holds the current JWT signing key and the one it replaced, so
+// tokens issued before a rotation stay valid until they expire
+type SigningKeys struct {
+	mu       sync.RWMutex
+	current  []byte
+	previous []byte
+}
+
+// NewSigningKeys creates a key set with a single active secret
+func NewSigningKeys(secret string) *SigningKeys {
+	return &SigningKeys{current: []byte(secret)}
+}
+
+// Rotate makes secret the signing key and keeps the old key for verification
+func (k *SigningKeys) Rotate(secret string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if string(k.current) == secret {
+		return
+	}
+	k.previous = k.current
+	k.current = []byte(secret)
+}
+
+// Current returns the key new tokens are signed with
+func (k *SigningKeys) Current() []byte {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.current
+}
+
+// ParseToken validates an HMAC signed token against the current key, then the previous one
+func (k *SigningKeys) ParseToken(tokenString string) (*jwt.Token, error) {
+	k.mu.RLock()
+	keys := [][]byte{k.current}
+	if k.previous != nil {
+		keys = append(keys, k.previous)
+	}
+	k.mu.RUnlock()
+
+	var err error
+	for _, key := range keys {
+		var token *jwt.Token
+		token, err = jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+			return key, nil
+		})
+		// Only a bad signature is worth retrying with the older key
+		if err == nil || !errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+			return token, err
+		}
+	}
+	return nil, err
+}
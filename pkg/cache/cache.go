@@ -0,0 +1,390 @@
+package cache
+
+import (
+	"apisecurityplatform/pkg/logging"
+	"apisecurityplatform/pkg/observability"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var logger = logging.For("cache")
+
+// Entry is a stored response. Entries returned by a Backend are shared and
+// must not be modified.
+type Entry struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+	Route  string      `json:"route"`
+	// URI is the request target the entry answers, for invalidation
+	URI  string   `json:"uri"`
+	Tags []string `json:"tags"`
+	// StoredAt is when the response was received, for the Age header
+	StoredAt time.Time `json:"stored_at"`
+	// FreshUntil is when the entry must be revalidated before it is served
+	FreshUntil time.Time `json:"fresh_until"`
+	// ExpiresAt is when the backend may drop the entry
+	ExpiresAt time.Time `json:"expires_at"`
+	// Vary is set on the index entry of a response that varies on these
+	// request headers; the response itself is stored under a variant key
+	Vary []string `json:"vary,omitempty"`
+}
+
+// Backend stores cache entries. The in-memory backend serves a single
+// instance; a shared backend lets instances reuse each other's responses.
+type Backend interface {
+	// Get returns the entry for key, or nil if there is none or it expired
+	Get(ctx context.Context, key string) (*Entry, error)
+	Set(ctx context.Context, key string, entry *Entry) error
+	// PurgeRoute and PurgeTag remove entries and report how many were removed
+	PurgeRoute(ctx context.Context, route string) (int, error)
+	PurgeTag(ctx context.Context, tag string) (int, error)
+	// PurgeURI removes the entries of a route for a request target, for
+	// every caller and variant
+	PurgeURI(ctx context.Context, route, uri string) (int, error)
+}
+
+// Key scopes for Policy.KeyBy
+const (
+	KeyByUser   = "user"
+	KeyByAPIKey = "api_key"
+)
+
+// Results recorded on the gateway_cache_requests_total metric and the X-Cache header
+const (
+	ResultHit         = "hit"
+	ResultMiss        = "miss"
+	ResultRevalidated = "revalidated"
+	ResultBypass      = "bypass"
+)
+
+// Policy is the caching behaviour of one gateway route
+type Policy struct {
+	Route string
+	// DefaultTTL applies to responses without explicit freshness; zero means
+	// such responses are not stored
+	DefaultTTL time.Duration
+	// MaxTTL caps the freshness of any response
+	MaxTTL time.Duration
+	// KeyBy scopes entries to the caller, by user and/or API key
+	KeyBy []string
+	// Tags are attached to every entry of the route, for purging
+	Tags []string
+	// Authenticated marks routes that require credentials. Unless KeyBy
+	// scopes the entries, their responses are only shared when the upstream
+	// marks them public.
+	Authenticated bool
+}
+
+// revalidateWindow is how long a stale entry with a validator is kept, so
+// it can be refreshed with a conditional request instead of a full one
+const revalidateWindow = 10 * time.Minute
+
+// Cache is an HTTP cache in front of the gateway's upstreams
+type Cache struct {
+	backend       Backend
+	maxEntryBytes int64
+}
+
+// New creates a cache storing responses of up to maxEntryBytes in backend
+func New(backend Backend, maxEntryBytes int64) *Cache {
+	return &Cache{backend: backend, maxEntryBytes: maxEntryBytes}
+}
+
+// Ping checks that the backend answers a lookup
+func (c *Cache) Ping(ctx context.Context) error {
+	_, err := c.backend.Get(ctx, "health-check")
+	return err
+}
+
+// Purge removes the entries of a route, or carrying a tag
+func (c *Cache) Purge(ctx context.Context, route, tag string) (int, error) {
+	var purged int
+	var err error
+	by := "route"
+	if route != "" {
+		purged, err = c.backend.PurgeRoute(ctx, route)
+	} else {
+		by = "tag"
+		purged, err = c.backend.PurgeTag(ctx, tag)
+	}
+	if err == nil {
+		observability.GatewayCachePurgedEntries.WithLabelValues(by).Add(float64(purged))
+	}
+	return purged, err
+}
+
+// Handler serves GET requests from the cache where possible and stores
+// cacheable responses from next. Successful unsafe requests invalidate the
+// entries of the resources they changed.
+func (c *Cache) Handler(policy Policy, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.serve(policy, next, w, r)
+	})
+}
+
+func (c *Cache) serve(policy Policy, next http.Handler, w http.ResponseWriter, r *http.Request) {
+	reqCC := parseCacheControl(r.Header)
+	scope, scoped := scopeOf(policy, r.Context())
+	// Upgrades and event streams are long-lived and never cacheable
+	stream := r.Header.Get("Upgrade") != "" || strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+	if unsafeMethod(r.Method) {
+		observability.GatewayCacheRequests.WithLabelValues(policy.Route, ResultBypass).Inc()
+		w.Header().Set("X-Cache", "BYPASS")
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+		c.invalidate(policy, r, sw.status, sw.Header())
+		return
+	}
+	if r.Method != http.MethodGet || reqCC.has("no-store") || !scoped || stream {
+		observability.GatewayCacheRequests.WithLabelValues(policy.Route, ResultBypass).Inc()
+		w.Header().Set("X-Cache", "BYPASS")
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	ctx := r.Context()
+	base := hashKey(policy.Route, scope, r.URL.RequestURI())
+	entry, key := c.lookup(ctx, base, r)
+
+	now := time.Now()
+	if entry != nil && now.Before(entry.FreshUntil) && !reqCC.has("no-cache") {
+		observability.GatewayCacheRequests.WithLabelValues(policy.Route, ResultHit).Inc()
+		serveEntry(w, r, entry, "HIT", now)
+		return
+	}
+
+	// A stale entry with a validator is refreshed with a conditional request,
+	// unless the client made its own
+	out := r
+	revalidating := false
+	if entry != nil && r.Header.Get("If-None-Match") == "" && r.Header.Get("If-Modified-Since") == "" {
+		etag, lastModified := entry.Header.Get("ETag"), entry.Header.Get("Last-Modified")
+		if etag != "" || lastModified != "" {
+			out = r.Clone(ctx)
+			if etag != "" {
+				out.Header.Set("If-None-Match", etag)
+			}
+			if lastModified != "" {
+				out.Header.Set("If-Modified-Since", lastModified)
+			}
+			revalidating = true
+		}
+	}
+
+	rec := newRecorder(w, c.maxEntryBytes, revalidating)
+	next.ServeHTTP(rec, out)
+	received := time.Now()
+
+	if rec.notModified {
+		refreshed := c.refresh(ctx, policy, entry, rec.header, key, received)
+		observability.GatewayCacheRequests.WithLabelValues(policy.Route, ResultRevalidated).Inc()
+		serveEntry(w, r, refreshed, "REVALIDATED", received)
+		return
+	}
+
+	observability.GatewayCacheRequests.WithLabelValues(policy.Route, ResultMiss).Inc()
+	if rec.overflow {
+		return
+	}
+	lifetime, ok := freshness(rec.status, rec.header, policy, received)
+	if !ok {
+		return
+	}
+	c.store(ctx, policy, base, r, &Entry{
+		Status:     rec.status,
+		Header:     storedHeader(rec.header),
+		Body:       rec.body.Bytes(),
+		Route:      policy.Route,
+		URI:        r.URL.RequestURI(),
+		Tags:       entryTags(policy, rec.header),
+		StoredAt:   received,
+		FreshUntil: received.Add(lifetime),
+	})
+}
+
+// invalidate removes the entries of the request target and of the
+// resources named by Location and Content-Location after a successful
+// unsafe request, as RFC 9111 section 4.4 requires. Other origins' URIs are
+// not invalidated.
+func (c *Cache) invalidate(policy Policy, r *http.Request, status int, header http.Header) {
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if status >= http.StatusBadRequest {
+		return
+	}
+	uris := []string{r.URL.RequestURI()}
+	for _, name := range []string{"Location", "Content-Location"} {
+		value := header.Get(name)
+		if value == "" {
+			continue
+		}
+		target, err := r.URL.Parse(value)
+		if err != nil || (target.Host != "" && !strings.EqualFold(target.Host, r.Host)) {
+			continue
+		}
+		if uri := target.RequestURI(); !slices.Contains(uris, uri) {
+			uris = append(uris, uri)
+		}
+	}
+
+	// The client may go away once it has the response
+	ctx := context.WithoutCancel(r.Context())
+	for _, uri := range uris {
+		purged, err := c.backend.PurgeURI(ctx, policy.Route, uri)
+		if err != nil {
+			logger.ErrorContext(ctx, "Cache invalidation failed", "error", err)
+			continue
+		}
+		observability.GatewayCachePurgedEntries.WithLabelValues("invalidation").Add(float64(purged))
+	}
+}
+
+// unsafeMethod reports whether a request may change the target resource
+func unsafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return false
+	}
+	return true
+}
+
+// lookup finds the entry for r, following the index entry of a response
+// that varies on request headers. It returns the entry and its key.
+func (c *Cache) lookup(ctx context.Context, base string, r *http.Request) (*Entry, string) {
+	entry, err := c.backend.Get(ctx, base)
+	if err != nil {
+		logger.ErrorContext(ctx, "Cache lookup failed", "error", err)
+		return nil, ""
+	}
+	if entry == nil || len(entry.Vary) == 0 {
+		return entry, base
+	}
+
+	key := variantKey(base, entry.Vary, r.Header)
+	if entry, err = c.backend.Get(ctx, key); err != nil {
+		logger.ErrorContext(ctx, "Cache lookup failed", "error", err)
+		return nil, ""
+	}
+	return entry, key
+}
+
+func (c *Cache) store(ctx context.Context, policy Policy, base string, r *http.Request, entry *Entry) {
+	entry.ExpiresAt = expiresAt(entry)
+
+	key := base
+	if vary := varyHeaders(entry.Header); len(vary) > 0 {
+		key = variantKey(base, vary, r.Header)
+		index := &Entry{Route: entry.Route, URI: entry.URI, Tags: entry.Tags, Vary: vary, ExpiresAt: entry.ExpiresAt}
+		if err := c.backend.Set(ctx, base, index); err != nil {
+			logger.ErrorContext(ctx, "Cache store failed", "error", err)
+			return
+		}
+	}
+	if err := c.backend.Set(ctx, key, entry); err != nil {
+		logger.ErrorContext(ctx, "Cache store failed", "error", err)
+	}
+}
+
+// refresh applies the headers of a 304 response to a stored entry, as
+// RFC 9111 section 4.3.4 requires, and stores the result
+func (c *Cache) refresh(ctx context.Context, policy Policy, entry *Entry, header http.Header, key string, now time.Time) *Entry {
+	refreshed := *entry
+	refreshed.Header = entry.Header.Clone()
+	for name, values := range storedHeader(header) {
+		if name != "Content-Length" {
+			refreshed.Header[name] = values
+		}
+	}
+	refreshed.StoredAt = now
+	refreshed.FreshUntil = now
+	if lifetime, ok := freshness(refreshed.Status, refreshed.Header, policy, now); ok {
+		refreshed.FreshUntil = now.Add(lifetime)
+	}
+	refreshed.ExpiresAt = expiresAt(&refreshed)
+
+	if err := c.backend.Set(ctx, key, &refreshed); err != nil {
+		logger.ErrorContext(ctx, "Cache store failed", "error", err)
+	}
+	return &refreshed
+}
+
+// serveEntry writes a stored response, or a 304 if the client's validator matches
+func serveEntry(w http.ResponseWriter, r *http.Request, entry *Entry, result string, now time.Time) {
+	header := w.Header()
+	for name, values := range entry.Header {
+		header[name] = values
+	}
+	header.Set("Age", strconv.Itoa(int(now.Sub(entry.StoredAt).Seconds())))
+	header.Set("X-Cache", result)
+
+	if etagMatches(r.Header.Get("If-None-Match"), entry.Header.Get("ETag")) {
+		header.Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.WriteHeader(entry.Status)
+	w.Write(entry.Body)
+}
+
+func expiresAt(entry *Entry) time.Time {
+	if entry.Header.Get("ETag") != "" || entry.Header.Get("Last-Modified") != "" {
+		return entry.FreshUntil.Add(revalidateWindow)
+	}
+	return entry.FreshUntil
+}
+
+// storedHeader drops the headers that describe a single transfer rather than the resource
+func storedHeader(header http.Header) http.Header {
+	stored := header.Clone()
+	for _, name := range []string{"Age", "X-Cache", "Set-Cookie", "Connection", "Keep-Alive", "Transfer-Encoding"} {
+		stored.Del(name)
+	}
+	return stored
+}
+
+func varyHeaders(header http.Header) []string {
+	var names []string
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if name = http.CanonicalHeaderKey(strings.TrimSpace(name)); name != "" && !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// entryTags combines the route's tags with those the upstream sent in Cache-Tag
+func entryTags(policy Policy, header http.Header) []string {
+	tags := slices.Clone(policy.Tags)
+	for _, value := range header.Values("Cache-Tag") {
+		for _, tag := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+func variantKey(base string, vary []string, header http.Header) string {
+	parts := []string{base}
+	for _, name := range vary {
+		parts = append(parts, name+"="+strings.Join(header.Values(name), ","))
+	}
+	return hashKey(parts...)
+}
+
+func hashKey(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
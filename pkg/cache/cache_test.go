@@ -0,0 +1,293 @@
+package cache
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// origin is an upstream answering with a fixed response and counting the
+// requests it receives
+type origin struct {
+	calls   int
+	status  int
+	header  http.Header
+	body    string
+	lastReq *http.Request
+}
+
+func (o *origin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	o.calls++
+	o.lastReq = r
+	for name, values := range o.header {
+		w.Header()[name] = values
+	}
+	status := o.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if etag := o.header.Get("ETag"); etag != "" && r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.WriteHeader(status)
+	w.Write([]byte(o.body))
+}
+
+func newOrigin(header ...string) *origin {
+	o := &origin{header: make(http.Header), body: "hello"}
+	for i := 0; i+1 < len(header); i += 2 {
+		o.header.Add(header[i], header[i+1])
+	}
+	return o
+}
+
+func newTestCache() *Cache {
+	return New(NewMemoryBackend(1<<20), 1<<20)
+}
+
+// do sends a request through h and returns the X-Cache result and the response
+func do(h http.Handler, method, target string, header ...string) (string, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(method, target, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Header().Get("X-Cache"), rec
+}
+
+func TestFreshness(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		header []string
+		status int
+		stored bool
+	}{
+		{"max-age", Policy{}, []string{"Cache-Control", "max-age=60"}, 0, true},
+		{"s-maxage", Policy{}, []string{"Cache-Control", "s-maxage=60"}, 0, true},
+		{"expires", Policy{}, []string{"Expires", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)}, 0, true},
+		{"default ttl", Policy{DefaultTTL: time.Minute}, nil, 0, true},
+		{"no freshness", Policy{}, nil, 0, false},
+		{"no-store", Policy{DefaultTTL: time.Minute}, []string{"Cache-Control", "no-store"}, 0, false},
+		{"private", Policy{}, []string{"Cache-Control", "private, max-age=60"}, 0, false},
+		{"private scoped", Policy{KeyBy: []string{KeyByUser}}, []string{"Cache-Control", "private, max-age=60"}, 0, true},
+		{"set-cookie", Policy{}, []string{"Cache-Control", "max-age=60", "Set-Cookie", "a=b"}, 0, false},
+		{"vary star", Policy{}, []string{"Cache-Control", "max-age=60", "Vary", "*"}, 0, false},
+		{"uncacheable status", Policy{}, []string{"Cache-Control", "max-age=60"}, http.StatusInternalServerError, false},
+		{"authenticated", Policy{Authenticated: true}, []string{"Cache-Control", "max-age=60"}, 0, false},
+		{"authenticated public", Policy{Authenticated: true}, []string{"Cache-Control", "public, max-age=60"}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newOrigin(tt.header...)
+			o.status = tt.status
+			tt.policy.Route = "test"
+			h := newTestCache().Handler(tt.policy, o)
+
+			ctx := WithIdentity(context.Background(), Identity{UserID: "1"})
+			for i := 0; i < 2; i++ {
+				req := httptest.NewRequest(http.MethodGet, "/items", nil).WithContext(ctx)
+				h.ServeHTTP(httptest.NewRecorder(), req)
+			}
+			if stored := o.calls == 1; stored != tt.stored {
+				t.Errorf("origin called %d times, stored = %v, want %v", o.calls, stored, tt.stored)
+			}
+		})
+	}
+}
+
+func TestFreshnessMaxTTL(t *testing.T) {
+	now := time.Now()
+	header := http.Header{"Cache-Control": {"max-age=3600"}}
+	lifetime, ok := freshness(http.StatusOK, header, Policy{MaxTTL: time.Minute}, now)
+	if !ok || lifetime != time.Minute {
+		t.Errorf("freshness = %s, %v; want max_ttl of 1m0s", lifetime, ok)
+	}
+}
+
+func TestServesFreshEntry(t *testing.T) {
+	o := newOrigin("Cache-Control", "max-age=60")
+	h := newTestCache().Handler(Policy{Route: "test"}, o)
+
+	if result, _ := do(h, http.MethodGet, "/items"); result != "MISS" {
+		t.Fatalf("first request: X-Cache = %s, want MISS", result)
+	}
+	result, rec := do(h, http.MethodGet, "/items")
+	if result != "HIT" || rec.Body.String() != "hello" || rec.Header().Get("Age") == "" {
+		t.Errorf("second request: X-Cache = %s, body %q, Age %q; want a HIT", result, rec.Body, rec.Header().Get("Age"))
+	}
+	if result, _ := do(h, http.MethodGet, "/items", "Cache-Control", "no-cache"); result == "HIT" {
+		t.Error("request with no-cache served from the cache")
+	}
+	if result, _ := do(h, http.MethodGet, "/items?page=2"); result != "MISS" {
+		t.Errorf("other query: X-Cache = %s, want MISS", result)
+	}
+}
+
+func TestVary(t *testing.T) {
+	o := newOrigin("Cache-Control", "max-age=60", "Vary", "Accept-Language")
+	h := newTestCache().Handler(Policy{Route: "test"}, o)
+
+	steps := []struct {
+		language string
+		want     string
+	}{
+		{"en", "MISS"},
+		{"de", "MISS"},
+		{"en", "HIT"},
+		{"de", "HIT"},
+		{"", "MISS"},
+	}
+	for i, step := range steps {
+		if result, _ := do(h, http.MethodGet, "/items", "Accept-Language", step.language); result != step.want {
+			t.Errorf("request %d in %q: X-Cache = %s, want %s", i+1, step.language, result, step.want)
+		}
+	}
+}
+
+func TestETagRevalidation(t *testing.T) {
+	o := newOrigin("Cache-Control", "no-cache", "ETag", `"v1"`)
+	h := newTestCache().Handler(Policy{Route: "test"}, o)
+
+	do(h, http.MethodGet, "/items")
+	result, rec := do(h, http.MethodGet, "/items")
+	if result != "REVALIDATED" || rec.Code != http.StatusOK || rec.Body.String() != "hello" {
+		t.Fatalf("stale request: X-Cache = %s, status %d, body %q; want the revalidated entry", result, rec.Code, rec.Body)
+	}
+	if got := o.lastReq.Header.Get("If-None-Match"); got != `"v1"` {
+		t.Errorf("upstream If-None-Match = %q, want the stored ETag", got)
+	}
+
+	// A changed resource replaces the entry
+	o.header.Set("ETag", `"v2"`)
+	o.body = "changed"
+	if _, rec := do(h, http.MethodGet, "/items"); rec.Code != http.StatusOK || rec.Body.String() != "changed" {
+		t.Errorf("after a change: status %d, body %q; want the new response", rec.Code, rec.Body)
+	}
+}
+
+func TestClientValidator(t *testing.T) {
+	o := newOrigin("Cache-Control", "max-age=60", "ETag", `"v1"`)
+	h := newTestCache().Handler(Policy{Route: "test"}, o)
+	do(h, http.MethodGet, "/items")
+
+	tests := []struct {
+		ifNoneMatch string
+		want        int
+	}{
+		{`"v1"`, http.StatusNotModified},
+		{`W/"v1"`, http.StatusNotModified},
+		{`"v0", "v1"`, http.StatusNotModified},
+		{"*", http.StatusNotModified},
+		{`"v2"`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		result, rec := do(h, http.MethodGet, "/items", "If-None-Match", tt.ifNoneMatch)
+		if result != "HIT" || rec.Code != tt.want {
+			t.Errorf("If-None-Match %s: X-Cache %s, status %d; want a HIT with %d", tt.ifNoneMatch, result, rec.Code, tt.want)
+		}
+		if tt.want == http.StatusNotModified && rec.Body.Len() != 0 {
+			t.Errorf("If-None-Match %s: 304 with a body", tt.ifNoneMatch)
+		}
+	}
+	if o.calls != 1 {
+		t.Errorf("origin called %d times, want 1", o.calls)
+	}
+}
+
+func TestUnsafeRequestsInvalidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		status      int
+		header      []string
+		invalidated []string
+	}{
+		{"post", http.MethodPost, "/items", http.StatusCreated, nil, []string{"/items"}},
+		{"put", http.MethodPut, "/items/1", http.StatusOK, nil, []string{"/items/1"}},
+		{"patch", http.MethodPatch, "/items/1", http.StatusNoContent, nil, []string{"/items/1"}},
+		{"delete", http.MethodDelete, "/items/1?force=1", http.StatusOK, nil, nil},
+		{"location", http.MethodPost, "/items", http.StatusCreated, []string{"Location", "/items/1"}, []string{"/items", "/items/1"}},
+		{"content-location", http.MethodPost, "/search", http.StatusOK, []string{"Content-Location", "items/1"}, []string{"/search", "/items/1"}},
+		{"same origin", http.MethodPost, "/search", http.StatusOK, []string{"Location", "http://example.com/items/1"}, []string{"/search", "/items/1"}},
+		{"other origin", http.MethodPost, "/search", http.StatusOK, []string{"Location", "http://other.example/items/1"}, []string{"/search"}},
+		{"error", http.MethodPut, "/items/1", http.StatusConflict, []string{"Location", "/items"}, nil},
+		{"safe", http.MethodHead, "/items", http.StatusOK, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCache()
+			o := newOrigin("Cache-Control", "max-age=60")
+			h := c.Handler(Policy{Route: "test", KeyBy: []string{KeyByUser}}, o)
+			targets := []string{"/items", "/items/1", "/items/2", "/search"}
+			get := func(user, target string) string {
+				ctx := WithIdentity(context.Background(), Identity{UserID: user})
+				rec := httptest.NewRecorder()
+				h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil).WithContext(ctx))
+				return rec.Header().Get("X-Cache")
+			}
+			for _, target := range targets {
+				get("1", target)
+				get("2", target)
+			}
+
+			write := newOrigin(tt.header...)
+			write.status = tt.status
+			ctx := WithIdentity(context.Background(), Identity{UserID: "1"})
+			rec := httptest.NewRecorder()
+			c.Handler(Policy{Route: "test", KeyBy: []string{KeyByUser}}, write).
+				ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, nil).WithContext(ctx))
+			if rec.Code != tt.status || rec.Header().Get("X-Cache") != "BYPASS" {
+				t.Fatalf("unsafe request: status %d, X-Cache %q", rec.Code, rec.Header().Get("X-Cache"))
+			}
+
+			for _, target := range targets {
+				invalidated := false
+				for _, uri := range tt.invalidated {
+					invalidated = invalidated || uri == target
+				}
+				// Entries of every caller are invalidated
+				for _, user := range []string{"1", "2"} {
+					if hit := get(user, target) == "HIT"; hit == invalidated {
+						t.Errorf("%s for user %s: hit = %v, want invalidated = %v", target, user, hit, invalidated)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestInvalidateOtherRoute(t *testing.T) {
+	c := newTestCache()
+	o := newOrigin("Cache-Control", "max-age=60")
+	do(c.Handler(Policy{Route: "a"}, o), http.MethodGet, "/items")
+	do(c.Handler(Policy{Route: "b"}, o), http.MethodPost, "/items")
+	if result, _ := do(c.Handler(Policy{Route: "a"}, o), http.MethodGet, "/items"); result != "HIT" {
+		t.Errorf("X-Cache = %s, want a HIT: a write on another route invalidated the entry", result)
+	}
+}
+
+func TestBypass(t *testing.T) {
+	o := newOrigin("Cache-Control", "max-age=60")
+
+	// Entries keyed by API key cannot be shared with callers without one
+	h := newTestCache().Handler(Policy{Route: "test", KeyBy: []string{KeyByAPIKey}}, o)
+	for i := 0; i < 2; i++ {
+		if result, _ := do(h, http.MethodGet, "/items"); result != "BYPASS" {
+			t.Errorf("unscoped caller: X-Cache = %s, want BYPASS", result)
+		}
+	}
+
+	h = newTestCache().Handler(Policy{Route: "test"}, o)
+	for _, header := range [][]string{{"Cache-Control", "no-store"}, {"Accept", "text/event-stream"}, {"Upgrade", "websocket"}} {
+		if result, _ := do(h, http.MethodGet, "/items", header...); result != "BYPASS" {
+			t.Errorf("request with %s: X-Cache = %s, want BYPASS", strings.Join(header, ": "), result)
+		}
+	}
+}
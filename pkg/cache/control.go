@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cacheControl holds the parsed directives of a Cache-Control header
+type cacheControl map[string]string
+
+func parseCacheControl(header http.Header) cacheControl {
+	cc := make(cacheControl)
+	for _, value := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			directive = strings.TrimSpace(directive)
+			if directive == "" {
+				continue
+			}
+			name, arg, _ := strings.Cut(directive, "=")
+			cc[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(arg), `"`)
+		}
+	}
+	return cc
+}
+
+func (cc cacheControl) has(directive string) bool {
+	_, ok := cc[directive]
+	return ok
+}
+
+// seconds returns a delta-seconds directive such as max-age
+func (cc cacheControl) seconds(directive string) (time.Duration, bool) {
+	value, ok := cc[directive]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return time.Duration(n) * time.Second, true
+}
+
+// cacheableStatus lists the status codes a cache may store, per RFC 9110
+var cacheableStatus = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusNotFound:             true,
+	http.StatusMethodNotAllowed:     true,
+	http.StatusGone:                 true,
+	http.StatusPermanentRedirect:    true,
+}
+
+// freshness decides whether a response may be stored by a shared cache and
+// for how long it is fresh. A stored response with a zero lifetime is always
+// revalidated before use.
+func freshness(status int, header http.Header, policy Policy, now time.Time) (time.Duration, bool) {
+	if !cacheableStatus[status] || header.Get("Set-Cookie") != "" {
+		return 0, false
+	}
+	for _, v := range header.Values("Vary") {
+		if strings.TrimSpace(v) == "*" {
+			return 0, false
+		}
+	}
+
+	cc := parseCacheControl(header)
+	if cc.has("no-store") {
+		return 0, false
+	}
+	// Private responses are only stored when the key is scoped to the caller
+	if cc.has("private") && len(policy.KeyBy) == 0 {
+		return 0, false
+	}
+	// Responses to authenticated requests are only shared between callers
+	// when the upstream says so explicitly
+	if policy.Authenticated && len(policy.KeyBy) == 0 && !cc.has("public") && !cc.has("s-maxage") {
+		return 0, false
+	}
+	if cc.has("no-cache") {
+		return 0, true
+	}
+
+	var lifetime time.Duration
+	if age, ok := cc.seconds("s-maxage"); ok {
+		lifetime = age
+	} else if age, ok := cc.seconds("max-age"); ok {
+		lifetime = age
+	} else if expires := header.Get("Expires"); expires != "" {
+		t, err := http.ParseTime(expires)
+		if err != nil {
+			return 0, true
+		}
+		lifetime = t.Sub(responseDate(header, now))
+	} else if policy.DefaultTTL > 0 {
+		lifetime = policy.DefaultTTL
+	} else {
+		return 0, false
+	}
+	if lifetime < 0 {
+		lifetime = 0
+	}
+	if policy.MaxTTL > 0 && lifetime > policy.MaxTTL {
+		lifetime = policy.MaxTTL
+	}
+	return lifetime, true
+}
+
+func responseDate(header http.Header, now time.Time) time.Time {
+	if date, err := http.ParseTime(header.Get("Date")); err == nil {
+		return date
+	}
+	return now
+}
+
+// etagMatches reports whether an If-None-Match header matches etag, using
+// the weak comparison RFC 9110 requires for If-None-Match
+func etagMatches(ifNoneMatch, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	weak := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == weak {
+			return true
+		}
+	}
+	return false
+}
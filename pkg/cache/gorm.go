@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"apisecurityplatform/pkg/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormBackend stores entries in the service database, so every instance
+// sharing the database shares the cache
+type GormBackend struct {
+	db *gorm.DB
+}
+
+// NewGormBackend creates a backend using the given connection
+func NewGormBackend(db *gorm.DB) *GormBackend {
+	return &GormBackend{db: db}
+}
+
+func (b *GormBackend) Get(ctx context.Context, key string) (*Entry, error) {
+	var row models.CacheEntry
+	err := b.db.WithContext(ctx).
+		Where("cache_key = ? AND expires_at > ?", key, time.Now()).
+		First(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(row.Data, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (b *GormBackend) Set(ctx context.Context, key string, entry *Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	row := models.CacheEntry{
+		Key:       key,
+		Route:     entry.Route,
+		URI:       entry.URI,
+		Data:      data,
+		ExpiresAt: entry.ExpiresAt,
+	}
+	if len(entry.Tags) > 0 {
+		row.Tags = " " + strings.Join(entry.Tags, " ") + " "
+	}
+	return b.db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(&row).Error
+}
+
+func (b *GormBackend) PurgeRoute(ctx context.Context, route string) (int, error) {
+	result := b.db.WithContext(ctx).Where("route = ?", route).Delete(&models.CacheEntry{})
+	return int(result.RowsAffected), result.Error
+}
+
+func (b *GormBackend) PurgeTag(ctx context.Context, tag string) (int, error) {
+	pattern := "% " + escapeLike(tag) + " %"
+	result := b.db.WithContext(ctx).Where(`tags LIKE ? ESCAPE '\'`, pattern).Delete(&models.CacheEntry{})
+	return int(result.RowsAffected), result.Error
+}
+
+func (b *GormBackend) PurgeURI(ctx context.Context, route, uri string) (int, error) {
+	result := b.db.WithContext(ctx).Where("route = ? AND uri = ?", route, uri).Delete(&models.CacheEntry{})
+	return int(result.RowsAffected), result.Error
+}
+
+// Watch deletes expired entries every interval until ctx is cancelled
+func (b *GormBackend) Watch(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				err := b.db.WithContext(ctx).Where("expires_at <= ?", time.Now()).Delete(&models.CacheEntry{}).Error
+				if err != nil && ctx.Err() == nil {
+					logger.Error("Failed to delete expired cache entries", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,40 @@
+package cache
+
+import (
+	"context"
+	"slices"
+)
+
+// Identity is the authenticated caller, used to scope cache keys
+type Identity struct {
+	UserID   string
+	APIKeyID string
+}
+
+type identityKey struct{}
+
+// WithIdentity attaches the caller's identity to ctx
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// scopeOf returns the part of the cache key identifying the caller. It
+// reports false when the policy scopes by an identity the request lacks, in
+// which case the request must bypass the cache.
+func scopeOf(policy Policy, ctx context.Context) (string, bool) {
+	identity, _ := ctx.Value(identityKey{}).(Identity)
+	scope := ""
+	if slices.Contains(policy.KeyBy, KeyByUser) {
+		if identity.UserID == "" {
+			return "", false
+		}
+		scope += "user=" + identity.UserID + ";"
+	}
+	if slices.Contains(policy.KeyBy, KeyByAPIKey) {
+		if identity.APIKeyID == "" {
+			return "", false
+		}
+		scope += "api_key=" + identity.APIKeyID + ";"
+	}
+	return scope, true
+}
@@ -0,0 +1,117 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// MemoryBackend is an in-process LRU backend bounded by the total size of
+// the stored responses
+type MemoryBackend struct {
+	mu       sync.Mutex
+	maxBytes int64
+	size     int64
+	order    *list.List
+	items    map[string]*list.Element
+}
+
+type memoryItem struct {
+	key   string
+	entry *Entry
+	size  int64
+}
+
+// NewMemoryBackend creates an LRU backend holding up to maxBytes of responses
+func NewMemoryBackend(maxBytes int64) *MemoryBackend {
+	return &MemoryBackend{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (b *MemoryBackend) Get(_ context.Context, key string) (*Entry, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	elem, ok := b.items[key]
+	if !ok {
+		return nil, nil
+	}
+	item := elem.Value.(*memoryItem)
+	if time.Now().After(item.entry.ExpiresAt) {
+		b.remove(elem)
+		return nil, nil
+	}
+	b.order.MoveToFront(elem)
+	return item.entry, nil
+}
+
+func (b *MemoryBackend) Set(_ context.Context, key string, entry *Entry) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if elem, ok := b.items[key]; ok {
+		b.remove(elem)
+	}
+	item := &memoryItem{key: key, entry: entry, size: entrySize(key, entry)}
+	if item.size > b.maxBytes {
+		return nil
+	}
+	b.items[key] = b.order.PushFront(item)
+	b.size += item.size
+
+	for b.size > b.maxBytes {
+		b.remove(b.order.Back())
+	}
+	return nil
+}
+
+func (b *MemoryBackend) PurgeRoute(_ context.Context, route string) (int, error) {
+	return b.purge(func(e *Entry) bool { return e.Route == route }), nil
+}
+
+func (b *MemoryBackend) PurgeTag(_ context.Context, tag string) (int, error) {
+	return b.purge(func(e *Entry) bool { return slices.Contains(e.Tags, tag) }), nil
+}
+
+func (b *MemoryBackend) PurgeURI(_ context.Context, route, uri string) (int, error) {
+	return b.purge(func(e *Entry) bool { return e.Route == route && e.URI == uri }), nil
+}
+
+func (b *MemoryBackend) purge(match func(*Entry) bool) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	purged := 0
+	for _, elem := range b.items {
+		if match(elem.Value.(*memoryItem).entry) {
+			b.remove(elem)
+			purged++
+		}
+	}
+	return purged
+}
+
+// remove drops an element; the caller holds mu
+func (b *MemoryBackend) remove(elem *list.Element) {
+	item := elem.Value.(*memoryItem)
+	b.order.Remove(elem)
+	delete(b.items, item.key)
+	b.size -= item.size
+}
+
+// entrySize approximates the memory held by an entry
+func entrySize(key string, entry *Entry) int64 {
+	size := int64(len(key) + len(entry.Body))
+	for name, values := range entry.Header {
+		size += int64(len(name))
+		for _, v := range values {
+			size += int64(len(v))
+		}
+	}
+	return size
+}
@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryBackendEviction(t *testing.T) {
+	ctx := context.Background()
+	entry := func(size int) *Entry {
+		return &Entry{Body: []byte(strings.Repeat("x", size)), ExpiresAt: time.Now().Add(time.Hour)}
+	}
+	// Keys are one byte, so each entry takes 100 bytes
+	b := NewMemoryBackend(300)
+	for _, key := range []string{"a", "b", "c"} {
+		b.Set(ctx, key, entry(99))
+	}
+	// Using a makes b the least recently used
+	if got, _ := b.Get(ctx, "a"); got == nil {
+		t.Fatal("a missing before the backend is full")
+	}
+	b.Set(ctx, "d", entry(99))
+
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true, "d": true} {
+		if got, _ := b.Get(ctx, key); (got != nil) != want {
+			t.Errorf("%s stored = %v, want %v", key, got != nil, want)
+		}
+	}
+
+	// Replacing an entry frees its old size
+	b.Set(ctx, "a", entry(9))
+	b.Set(ctx, "e", entry(89))
+	if b.size > b.maxBytes || len(b.items) != 4 {
+		t.Errorf("%d entries of %d bytes, want 4 within %d", len(b.items), b.size, b.maxBytes)
+	}
+
+	// An entry larger than the backend is not stored, and evicts nothing
+	b.Set(ctx, "f", entry(1000))
+	if got, _ := b.Get(ctx, "f"); got != nil || len(b.items) != 4 {
+		t.Errorf("oversized entry stored, or %d entries left", len(b.items))
+	}
+}
+
+func TestMemoryBackendExpiry(t *testing.T) {
+	ctx := context.Background()
+	b := NewMemoryBackend(1000)
+	b.Set(ctx, "old", &Entry{ExpiresAt: time.Now().Add(-time.Second)})
+	if got, _ := b.Get(ctx, "old"); got != nil || b.size != 0 {
+		t.Error("expired entry returned or kept")
+	}
+}
+
+func TestMemoryBackendPurge(t *testing.T) {
+	ctx := context.Background()
+	b := NewMemoryBackend(1000)
+	expires := time.Now().Add(time.Hour)
+	b.Set(ctx, "1", &Entry{Route: "a", URI: "/x", Tags: []string{"t"}, ExpiresAt: expires})
+	b.Set(ctx, "2", &Entry{Route: "a", URI: "/y", ExpiresAt: expires})
+	b.Set(ctx, "3", &Entry{Route: "b", URI: "/x", Tags: []string{"t"}, ExpiresAt: expires})
+	b.Set(ctx, "4", &Entry{Route: "b", URI: "/y", ExpiresAt: expires})
+
+	if n, _ := b.PurgeURI(ctx, "a", "/x"); n != 1 {
+		t.Errorf("PurgeURI = %d, want 1", n)
+	}
+	if n, _ := b.PurgeTag(ctx, "t"); n != 1 {
+		t.Errorf("PurgeTag = %d, want 1", n)
+	}
+	if n, _ := b.PurgeRoute(ctx, "b"); n != 1 {
+		t.Errorf("PurgeRoute = %d, want 1", n)
+	}
+	if got, _ := b.Get(ctx, "2"); got == nil {
+		t.Error("unrelated entry purged")
+	}
+}
@@ -0,0 +1,110 @@
+package cache
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// recorder passes a response through to the client while keeping a copy of
+// it for the cache, up to a size limit
+type recorder struct {
+	w      http.ResponseWriter
+	header http.Header
+	status int
+	body   bytes.Buffer
+	limit  int64
+	// overflow is set once the body exceeds limit and the copy is dropped
+	overflow bool
+	// intercept304 holds back a 304 answering a validator the cache added,
+	// since the client did not ask for one
+	intercept304 bool
+	notModified  bool
+	wroteHeader  bool
+}
+
+func newRecorder(w http.ResponseWriter, limit int64, intercept304 bool) *recorder {
+	return &recorder{w: w, header: make(http.Header), limit: limit, intercept304: intercept304}
+}
+
+func (r *recorder) Header() http.Header {
+	return r.header
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.wroteHeader {
+		return
+	}
+	r.wroteHeader = true
+	r.status = status
+	if r.intercept304 && status == http.StatusNotModified {
+		r.notModified = true
+		return
+	}
+
+	for name, values := range r.header {
+		r.w.Header()[name] = values
+	}
+	r.w.Header().Set("X-Cache", "MISS")
+	r.w.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	if r.notModified {
+		return len(b), nil
+	}
+	if !r.overflow {
+		if int64(r.body.Len()+len(b)) > r.limit {
+			r.overflow = true
+			r.body = bytes.Buffer{}
+		} else {
+			r.body.Write(b)
+		}
+	}
+	return r.w.Write(b)
+}
+
+// Flush keeps streamed responses streaming through the cache
+func (r *recorder) Flush() {
+	if r.notModified {
+		return
+	}
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	if f, ok := r.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// statusWriter passes a response through, noting its status
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,298 @@
+package certs
+
+import (
+	"apisecurityplatform/pkg/config"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA is a certificate authority issuing test certificates
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue signs a leaf certificate for commonName, returning its PEM encoded
+// certificate and key
+func (ca *testCA) issue(t *testing.T, commonName string, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// writeFile writes data to name in dir, with a modification time of
+// generation seconds from now so that a rewrite is always seen as a change
+func writeFile(t *testing.T, dir, name string, data []byte, generation int) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Now().Add(time.Duration(generation) * time.Second)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// newTLSServer serves r's TLS configuration and returns its address
+func newTLSServer(t *testing.T, r *Reloader) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+	ts.TLS = r.TLSConfig()
+	ts.StartTLS()
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// handshake connects to ts trusting ca, presenting client if set, and
+// returns the server's certificate
+func handshake(ts *httptest.Server, ca *testCA, client *tls.Certificate) (*x509.Certificate, error) {
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	cfg := &tls.Config{RootCAs: roots, ServerName: "localhost"}
+	if client != nil {
+		cfg.Certificates = []tls.Certificate{*client}
+	}
+	conn, err := tls.Dial("tcp", ts.Listener.Addr().String(), cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	// TLS 1.3 clients only learn of a rejected certificate on the first read
+	conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if _, err := conn.Read(make([]byte, 1)); err != nil {
+		if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+			return nil, err
+		}
+	}
+	return conn.ConnectionState().PeerCertificates[0], nil
+}
+
+func TestIdentityMapperLookup(t *testing.T) {
+	spiffe, _ := url.Parse("spiffe://example.com/billing")
+	m := NewIdentityMapper([]IdentityRule{
+		{Match: "spiffe://example.com/billing", Identity: Identity{Name: "billing", UserID: 7}},
+		{Match: "orders.internal", Identity: Identity{Name: "orders"}},
+		{Match: "reports@example.com", Identity: Identity{Name: "reports"}},
+		{Match: "legacy-batch", Identity: Identity{Name: "batch"}},
+	})
+
+	tests := []struct {
+		name string
+		cert *x509.Certificate
+		want string
+	}{
+		{"URI SAN", &x509.Certificate{URIs: []*url.URL{spiffe}}, "billing"},
+		{"DNS SAN", &x509.Certificate{DNSNames: []string{"unknown.internal", "orders.internal"}}, "orders"},
+		{"email SAN", &x509.Certificate{EmailAddresses: []string{"reports@example.com"}}, "reports"},
+		{"common name", &x509.Certificate{Subject: pkix.Name{CommonName: " legacy-batch "}}, "batch"},
+		{"SAN before common name", &x509.Certificate{DNSNames: []string{"orders.internal"}, Subject: pkix.Name{CommonName: "legacy-batch"}}, "orders"},
+		{"no rule", &x509.Certificate{DNSNames: []string{"unknown.internal"}, Subject: pkix.Name{CommonName: "unknown"}}, ""},
+	}
+	for _, tt := range tests {
+		identity, ok := m.Lookup(tt.cert)
+		if ok != (tt.want != "") || identity.Name != tt.want {
+			t.Errorf("%s: Lookup = %+v, %v; want %q", tt.name, identity, ok, tt.want)
+		}
+	}
+	if identity, _ := m.Lookup(&x509.Certificate{URIs: []*url.URL{spiffe}}); identity.UserID != 7 {
+		t.Errorf("UserID = %d, want 7", identity.UserID)
+	}
+}
+
+func TestReloaderClientAuth(t *testing.T) {
+	dir := t.TempDir()
+	ca, untrusted := newTestCA(t, "clients"), newTestCA(t, "elsewhere")
+	serverCert, serverKey := ca.issue(t, "server", x509.ExtKeyUsageServerAuth)
+	cfg := config.TLSConfig{
+		CertFile:     writeFile(t, dir, "tls.crt", serverCert, 0),
+		KeyFile:      writeFile(t, dir, "tls.key", serverKey, 0),
+		ClientCAFile: writeFile(t, dir, "ca.crt", ca.pem, 0),
+		ClientAuth:   "require",
+		MinVersion:   "1.2",
+	}
+	r, err := NewReloaderFromConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := newTLSServer(t, r)
+
+	clientCert := func(ca *testCA) *tls.Certificate {
+		certPEM, keyPEM := ca.issue(t, "client", x509.ExtKeyUsageClientAuth)
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &cert
+	}
+	tests := []struct {
+		name   string
+		client *tls.Certificate
+		ok     bool
+	}{
+		{"trusted certificate", clientCert(ca), true},
+		{"untrusted certificate", clientCert(untrusted), false},
+		{"no certificate", nil, false},
+	}
+	for _, tt := range tests {
+		if _, err := handshake(ts, ca, tt.client); (err == nil) != tt.ok {
+			t.Errorf("%s: handshake error %v, want success %v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestNewReloaderFromConfigWithoutClientAuth(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "server")
+	serverCert, serverKey := ca.issue(t, "server", x509.ExtKeyUsageServerAuth)
+	// The CA file is ignored without client authentication
+	r, err := NewReloaderFromConfig(config.TLSConfig{
+		CertFile:     writeFile(t, dir, "tls.crt", serverCert, 0),
+		KeyFile:      writeFile(t, dir, "tls.key", serverKey, 0),
+		ClientCAFile: filepath.Join(dir, "missing.crt"),
+		ClientAuth:   "none",
+		MinVersion:   "1.3",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := handshake(newTLSServer(t, r), ca, nil); err != nil {
+		t.Errorf("handshake without a client certificate: %v", err)
+	}
+}
+
+func TestNewReloaderErrors(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "server")
+	certPEM, keyPEM := ca.issue(t, "server", x509.ExtKeyUsageServerAuth)
+	certFile, keyFile := writeFile(t, dir, "tls.crt", certPEM, 0), writeFile(t, dir, "tls.key", keyPEM, 0)
+	_, otherKey := ca.issue(t, "other", x509.ExtKeyUsageServerAuth)
+
+	tests := []struct {
+		name                      string
+		certFile, keyFile, caFile string
+	}{
+		{"missing certificate", filepath.Join(dir, "missing.crt"), keyFile, ""},
+		{"mismatched key", certFile, writeFile(t, dir, "other.key", otherKey, 0), ""},
+		{"missing CA bundle", certFile, keyFile, filepath.Join(dir, "missing-ca.crt")},
+		{"empty CA bundle", certFile, keyFile, writeFile(t, dir, "empty.crt", []byte("no certificates"), 0)},
+	}
+	for _, tt := range tests {
+		if _, err := NewReloader(tt.certFile, tt.keyFile, tt.caFile, tls.RequireAndVerifyClientCert, tls.VersionTLS12); err == nil {
+			t.Errorf("%s: NewReloader succeeded", tt.name)
+		}
+	}
+}
+
+func TestReloaderPicksUpRotatedKeyPair(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "server")
+	certPEM, keyPEM := ca.issue(t, "first", x509.ExtKeyUsageServerAuth)
+	certFile, keyFile := writeFile(t, dir, "tls.crt", certPEM, 0), writeFile(t, dir, "tls.key", keyPEM, 0)
+	r, err := NewReloader(certFile, keyFile, "", tls.NoClientCert, tls.VersionTLS12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r.Watch(ctx, 10*time.Millisecond)
+	ts := newTLSServer(t, r)
+
+	served := func() string {
+		t.Helper()
+		cert, err := handshake(ts, ca, nil)
+		if err != nil {
+			t.Fatalf("handshake: %v", err)
+		}
+		return cert.Subject.CommonName
+	}
+	waitFor := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for served() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("served certificate is %q, want %q", served(), want)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	if got := served(); got != "first" {
+		t.Fatalf("served certificate is %q, want first", got)
+	}
+
+	// A key that does not match the certificate is not loaded
+	_, otherKey := ca.issue(t, "other", x509.ExtKeyUsageServerAuth)
+	writeFile(t, dir, "tls.key", otherKey, 1)
+	time.Sleep(50 * time.Millisecond)
+	if got := served(); got != "first" {
+		t.Errorf("served certificate is %q after a broken rotation, want first", got)
+	}
+
+	certPEM, keyPEM = ca.issue(t, "second", x509.ExtKeyUsageServerAuth)
+	writeFile(t, dir, "tls.crt", certPEM, 2)
+	writeFile(t, dir, "tls.key", keyPEM, 2)
+	waitFor("second")
+}
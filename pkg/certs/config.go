@@ -0,0 +1,38 @@
+package certs
+
+import (
+	"apisecurityplatform/pkg/config"
+	"crypto/tls"
+)
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"none":     tls.NoClientCert,
+	"optional": tls.VerifyClientCertIfGiven,
+	"require":  tls.RequireAndVerifyClientCert,
+}
+
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// NewReloaderFromConfig creates a Reloader from validated TLS settings
+func NewReloaderFromConfig(cfg config.TLSConfig) (*Reloader, error) {
+	caFile := cfg.ClientCAFile
+	if cfg.ClientAuth == "none" {
+		caFile = ""
+	}
+	return NewReloader(cfg.CertFile, cfg.KeyFile, caFile, clientAuthTypes[cfg.ClientAuth], tlsVersions[cfg.MinVersion])
+}
+
+// NewIdentityMapperFromConfig creates an IdentityMapper from the configured client identities
+func NewIdentityMapperFromConfig(cfg config.TLSConfig) *IdentityMapper {
+	rules := make([]IdentityRule, 0, len(cfg.ClientIdentities))
+	for _, identity := range cfg.ClientIdentities {
+		rules = append(rules, IdentityRule{
+			Match:    identity.Match,
+			Identity: Identity{Name: identity.Name, UserID: identity.UserID},
+		})
+	}
+	return NewIdentityMapper(rules)
+}
@@ -0,0 +1,57 @@
+package certs
+
+import (
+	"crypto/x509"
+	"strings"
+)
+
+// Identity is the machine principal a client certificate maps to
+type Identity struct {
+	Name   string
+	UserID uint
+}
+
+// IdentityRule maps a certificate name to an identity. Match is compared
+// against the URI, DNS and email SANs and the subject common name.
+type IdentityRule struct {
+	Match    string
+	Identity Identity
+}
+
+// IdentityMapper resolves verified client certificates to identities
+type IdentityMapper struct {
+	rules map[string]Identity
+}
+
+// NewIdentityMapper creates a mapper from the configured rules
+func NewIdentityMapper(rules []IdentityRule) *IdentityMapper {
+	m := &IdentityMapper{rules: make(map[string]Identity, len(rules))}
+	for _, rule := range rules {
+		m.rules[rule.Match] = rule.Identity
+	}
+	return m
+}
+
+// Lookup returns the identity of the first certificate name with a rule.
+// SANs are checked before the subject, which is only a fallback.
+func (m *IdentityMapper) Lookup(cert *x509.Certificate) (Identity, bool) {
+	for _, name := range certificateNames(cert) {
+		if identity, ok := m.rules[name]; ok {
+			return identity, true
+		}
+	}
+	return Identity{}, false
+}
+
+func certificateNames(cert *x509.Certificate) []string {
+	var names []string
+	for _, uri := range cert.URIs {
+		names = append(names, uri.String())
+	}
+	names = append(names, cert.DNSNames...)
+	names = append(names, cert.EmailAddresses...)
+	if cn := strings.TrimSpace(cert.Subject.CommonName); cn != "" {
+		names = append(names, cn)
+	}
+	return names
+}
@@ -1,41 +1,760 @@
 package config
 
 import (
+	"apisecurityplatform/pkg/secrets"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
-type Config struct {
-	Server struct {
-		Port int
-		Host string
+const (
+	// EnvironmentDevelopment relaxes validation for local runs
+	EnvironmentDevelopment = "development"
+	// EnvironmentProduction rejects insecure defaults at startup
+	EnvironmentProduction = "production"
+
+	// DefaultJWTSecret is the well-known development secret; it is refused in production
+	DefaultJWTSecret = "your_super_secret_key_here"
+
+	redacted = "[REDACTED]"
+)
+
+// ServerConfig controls the public HTTP listener
+type ServerConfig struct {
+	Host              string        `mapstructure:"host" json:"host"`
+	Port              int           `mapstructure:"port" json:"port"`
+	ReadTimeout       time.Duration `mapstructure:"read_timeout" json:"read_timeout"`
+	ReadHeaderTimeout time.Duration `mapstructure:"read_header_timeout" json:"read_header_timeout"`
+	WriteTimeout      time.Duration `mapstructure:"write_timeout" json:"write_timeout"`
+	IdleTimeout       time.Duration `mapstructure:"idle_timeout" json:"idle_timeout"`
+	MaxHeaderBytes    int           `mapstructure:"max_header_bytes" json:"max_header_bytes"`
+	// ShutdownTimeout bounds how long in-flight requests may drain after SIGTERM
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout" json:"shutdown_timeout"`
+	TLS             TLSConfig     `mapstructure:"tls" json:"tls"`
+}
+
+// TLSConfig enables TLS termination and optional client certificate authentication
+type TLSConfig struct {
+	Enabled  bool   `mapstructure:"enabled" json:"enabled"`
+	CertFile string `mapstructure:"cert_file" json:"cert_file"`
+	KeyFile  string `mapstructure:"key_file" json:"key_file"`
+	// MinVersion is "1.2" or "1.3"
+	MinVersion string `mapstructure:"min_version" json:"min_version"`
+	// ReloadInterval is how often the files are checked for changes
+	ReloadInterval time.Duration `mapstructure:"reload_interval" json:"reload_interval"`
+	// ClientAuth is "none", "optional" (verify if presented) or "require"
+	ClientAuth       string                 `mapstructure:"client_auth" json:"client_auth"`
+	ClientCAFile     string                 `mapstructure:"client_ca_file" json:"client_ca_file"`
+	ClientIdentities []ClientIdentityConfig `mapstructure:"client_identities" json:"client_identities"`
+}
+
+// ClientIdentityConfig maps a client certificate SAN or subject common name to an identity
+type ClientIdentityConfig struct {
+	Match  string `mapstructure:"match" json:"match"`
+	Name   string `mapstructure:"name" json:"name"`
+	UserID uint   `mapstructure:"user_id" json:"user_id"`
+}
+
+// Address returns the host:port the server listens on
+func (s ServerConfig) Address() string {
+	return net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+}
+
+// AdminConfig controls the internal listener serving /metrics, /docs and
+// admin routes, which must not be exposed publicly
+type AdminConfig struct {
+	Host string `mapstructure:"host" json:"host"`
+	Port int    `mapstructure:"port" json:"port"`
+}
+
+// Address returns the host:port the admin server listens on
+func (a AdminConfig) Address() string {
+	return net.JoinHostPort(a.Host, strconv.Itoa(a.Port))
+}
+
+// DatabaseConfig selects the database driver and how to reach it
+type DatabaseConfig struct {
+	Driver   string `mapstructure:"driver" json:"driver"`
+	Host     string `mapstructure:"host" json:"host"`
+	Port     int    `mapstructure:"port" json:"port"`
+	User     string `mapstructure:"user" json:"user"`
+	Password string `mapstructure:"password" json:"password"`
+	Name     string `mapstructure:"name" json:"name"`
+	// Path is the SQLite database file, or ":memory:" for a throwaway database
+	Path string `mapstructure:"path" json:"path"`
+}
+
+// JWTConfig controls token signing and lifetime
+type JWTConfig struct {
+	Secret       string `mapstructure:"secret" json:"secret"`
+	ExpiryHours  int    `mapstructure:"expiry_hours" json:"expiry_hours"`
+	RefreshHours int    `mapstructure:"refresh_hours" json:"refresh_hours"`
+}
+
+// Span and OTLP metric exporters
+const (
+	TracingExporterOTLP   = "otlp"
+	TracingExporterStdout = "stdout"
+	TracingExporterNone   = "none"
+)
+
+// OTLP transports
+const (
+	TracingProtocolGRPC = "grpc"
+	TracingProtocolHTTP = "http/protobuf"
+)
+
+// TracingConfig controls where spans and OTLP metrics are exported. The
+// standard OTEL_* variables are read as fallbacks of the settings below, and
+// the OTLP exporters read the ones not covered here themselves, such as
+// OTEL_EXPORTER_OTLP_HEADERS, OTEL_EXPORTER_OTLP_CERTIFICATE and
+// OTEL_EXPORTER_OTLP_TIMEOUT.
+type TracingConfig struct {
+	// Exporter is otlp, stdout (for local debugging) or none
+	Exporter string `mapstructure:"exporter" json:"exporter"`
+	// Protocol is the OTLP transport, grpc or http/protobuf
+	Protocol string `mapstructure:"protocol" json:"protocol"`
+	// Endpoint is a collector URL, whose scheme selects TLS, or a host:port.
+	// Empty uses the exporter's default, localhost on the protocol's port.
+	Endpoint string `mapstructure:"endpoint" json:"endpoint"`
+	// Insecure disables TLS for host:port endpoints
+	Insecure bool `mapstructure:"insecure" json:"insecure"`
+	// SampleRatio is the fraction of root traces sampled, reloadable at
+	// runtime. Spans with a parent follow the parent's decision.
+	SampleRatio float64 `mapstructure:"sample_ratio" json:"sample_ratio"`
+}
+
+// RateLimitConfig controls the per-client request rate limit. A zero rate disables limiting.
+type RateLimitConfig struct {
+	RequestsPerSecond float64 `mapstructure:"requests_per_second" json:"requests_per_second"`
+	Burst             int     `mapstructure:"burst" json:"burst"`
+}
+
+// CORSConfig lists the browser origins allowed to call the API
+type CORSConfig struct {
+	AllowedOrigins []string `mapstructure:"allowed_origins" json:"allowed_origins"`
+}
+
+// SecretsConfig controls how secret references such as "file:/run/secrets/jwt"
+// or "vault:apiplatform#jwt_secret" in jwt.secret and database.password are resolved
+type SecretsConfig struct {
+	// RefreshInterval re-resolves secret references so they can rotate; zero disables it
+	RefreshInterval time.Duration `mapstructure:"refresh_interval" json:"refresh_interval"`
+	Vault           VaultConfig   `mapstructure:"vault" json:"vault"`
+}
+
+// VaultConfig points at a Vault-compatible KV version 2 API
+type VaultConfig struct {
+	Address string `mapstructure:"address" json:"address"`
+	Mount   string `mapstructure:"mount" json:"mount"`
+	// Token may itself be a "file:" or "env:" reference
+	Token string `mapstructure:"token" json:"token"`
+}
+
+// LogConfig controls log output
+type LogConfig struct {
+	Level string `mapstructure:"level" json:"level"`
+	// Packages overrides Level for the loggers of single packages, such as
+	// {"gateway": "debug"}
+	Packages map[string]string `mapstructure:"packages" json:"packages"`
+}
+
+// OpenAPIConfig validates requests against an OpenAPI document before they
+// reach handlers or upstreams
+type OpenAPIConfig struct {
+	Enabled bool `mapstructure:"enabled" json:"enabled"`
+	// Spec is the path of an OpenAPI 3 or Swagger 2 document, in JSON or
+	// YAML. The platform's own endpoints default to the generated docs.
+	Spec string `mapstructure:"spec" json:"spec"`
+	// ValidateResponses logs responses that drift from the document. It
+	// buffers response bodies, so it is meant for staging.
+	ValidateResponses bool `mapstructure:"validate_responses" json:"validate_responses"`
+	// MaxBodyBytes is the largest request body buffered for validation;
+	// larger bodies are passed through with only their parameters checked
+	MaxBodyBytes int64 `mapstructure:"max_body_bytes" json:"max_body_bytes"`
+}
+
+// Validate checks the validation settings
+func (o OpenAPIConfig) Validate() error {
+	if o.Enabled && o.MaxBodyBytes <= 0 {
+		return errors.New("max_body_bytes must be positive")
 	}
-	Database struct {
-		Host     string
-		Port     int
-		User     string
-		Password string
-		Name     string
+	return nil
+}
+
+// UsageConfig controls per-tenant usage metering
+type UsageConfig struct {
+	// FlushInterval is how often metered usage is written to the database,
+	// and so how far behind the usage API can be
+	FlushInterval time.Duration `mapstructure:"flush_interval" json:"flush_interval"`
+	// Retention is how long usage records are kept; 0 keeps them forever
+	Retention time.Duration `mapstructure:"retention" json:"retention"`
+}
+
+// Validate checks the metering settings
+func (u UsageConfig) Validate() error {
+	if u.FlushInterval <= 0 {
+		return errors.New("flush_interval must be positive")
+	}
+	if u.Retention < 0 {
+		return errors.New("retention must not be negative")
+	}
+	return nil
+}
+
+// ProfilingConfig controls the CPU and heap profiles captured through the
+// admin API
+type ProfilingConfig struct {
+	// Dir holds the captured profiles; empty means a directory under the
+	// system temporary directory
+	Dir string `mapstructure:"dir" json:"dir"`
+	// MaxProfiles is how many profiles are kept; older ones are deleted
+	MaxProfiles int `mapstructure:"max_profiles" json:"max_profiles"`
+	// MaxDuration caps how long a CPU profile may run
+	MaxDuration time.Duration `mapstructure:"max_duration" json:"max_duration"`
+}
+
+// Validate checks the profiling settings
+func (p ProfilingConfig) Validate() error {
+	if p.MaxProfiles <= 0 {
+		return errors.New("max_profiles must be positive")
+	}
+	if p.MaxDuration <= 0 {
+		return errors.New("max_duration must be positive")
 	}
-	JWT struct {
-		Secret       string
-		ExpiryHours  int
-		RefreshHours int
+	return nil
+}
+
+// SLOConfig declares service level objectives for routes. Error budgets are
+// measured over Window, and the generated burn-rate alerts are scaled to it.
+type SLOConfig struct {
+	Window     time.Duration  `mapstructure:"window" json:"window"`
+	Objectives []SLOObjective `mapstructure:"objectives" json:"objectives"`
+}
+
+// SLOObjective is the availability, and optionally latency, objective of a route
+type SLOObjective struct {
+	// Name identifies the objective in metrics and alerts
+	Name string `mapstructure:"name" json:"name"`
+	// Route is a gateway route name, or a platform endpoint's path pattern
+	// such as /users/me
+	Route string `mapstructure:"route" json:"route"`
+	// Availability is the fraction of requests that must not fail with a
+	// 5xx, such as 0.999
+	Availability float64 `mapstructure:"availability" json:"availability"`
+	// Latency, when set, is the duration requests must complete within. It
+	// must be a bucket bound of http_server_duration_seconds, such as 250ms.
+	Latency time.Duration `mapstructure:"latency" json:"latency"`
+	// LatencyTarget is the fraction of requests that must complete within
+	// Latency, 0.95 for a p95 objective
+	LatencyTarget float64 `mapstructure:"latency_target" json:"latency_target"`
+}
+
+// validSLOName matches names usable as label values and in rule names
+var validSLOName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// Validate checks the window and every objective
+func (s SLOConfig) Validate() error {
+	var errs []error
+	if s.Window < time.Hour {
+		errs = append(errs, errors.New("window must be at least 1h"))
+	}
+	names := make(map[string]bool)
+	for i, o := range s.Objectives {
+		if !validSLOName.MatchString(o.Name) {
+			errs = append(errs, fmt.Errorf("objectives[%d]: name must be letters, digits, '_' or '-', got %q", i, o.Name))
+		} else if names[o.Name] {
+			errs = append(errs, fmt.Errorf("objectives[%d]: duplicate name %q", i, o.Name))
+		}
+		names[o.Name] = true
+		if o.Route == "" {
+			errs = append(errs, fmt.Errorf("objectives[%d]: route is required", i))
+		}
+		if o.Availability <= 0 || o.Availability >= 1 {
+			errs = append(errs, fmt.Errorf("objectives[%d]: availability must be between 0 and 1 exclusive, got %g", i, o.Availability))
+		}
+		if o.Latency < 0 {
+			errs = append(errs, fmt.Errorf("objectives[%d]: latency must not be negative", i))
+		}
+		if o.Latency > 0 && (o.LatencyTarget <= 0 || o.LatencyTarget >= 1) {
+			errs = append(errs, fmt.Errorf("objectives[%d]: latency_target must be between 0 and 1 exclusive, got %g", i, o.LatencyTarget))
+		}
 	}
+	return errors.Join(errs...)
+}
+
+// Config is the complete service configuration
+type Config struct {
+	Environment string          `mapstructure:"environment" json:"environment"`
+	Server      ServerConfig    `mapstructure:"server" json:"server"`
+	Admin       AdminConfig     `mapstructure:"admin" json:"admin"`
+	Database    DatabaseConfig  `mapstructure:"database" json:"database"`
+	JWT         JWTConfig       `mapstructure:"jwt" json:"jwt"`
+	Tracing     TracingConfig   `mapstructure:"tracing" json:"tracing"`
+	RateLimit   RateLimitConfig `mapstructure:"rate_limit" json:"rate_limit"`
+	CORS        CORSConfig      `mapstructure:"cors" json:"cors"`
+	Log         LogConfig       `mapstructure:"log" json:"log"`
+	Secrets     SecretsConfig   `mapstructure:"secrets" json:"secrets"`
+	Gateway     GatewayConfig   `mapstructure:"gateway" json:"gateway"`
+	OpenAPI     OpenAPIConfig   `mapstructure:"openapi" json:"openapi"`
+	Usage       UsageConfig     `mapstructure:"usage" json:"usage"`
+	SLO         SLOConfig       `mapstructure:"slo" json:"slo"`
+	Profiling   ProfilingConfig `mapstructure:"profiling" json:"profiling"`
+}
+
+// envBindings keeps the environment variable names the service has always used
+var envBindings = map[string]string{
+	"environment":                    "APP_ENV",
+	"server.host":                    "SERVER_HOST",
+	"server.port":                    "SERVER_PORT",
+	"server.shutdown_timeout":        "SERVER_SHUTDOWN_TIMEOUT",
+	"server.tls.enabled":             "TLS_ENABLED",
+	"server.tls.cert_file":           "TLS_CERT_FILE",
+	"server.tls.key_file":            "TLS_KEY_FILE",
+	"server.tls.client_auth":         "TLS_CLIENT_AUTH",
+	"server.tls.client_ca_file":      "TLS_CLIENT_CA_FILE",
+	"admin.host":                     "ADMIN_HOST",
+	"admin.port":                     "ADMIN_PORT",
+	"database.driver":                "DB_DRIVER",
+	"database.host":                  "DB_HOST",
+	"database.port":                  "DB_PORT",
+	"database.user":                  "DB_USER",
+	"database.password":              "DB_PASSWORD",
+	"database.name":                  "DB_NAME",
+	"database.path":                  "DB_PATH",
+	"jwt.secret":                     "JWT_SECRET",
+	"jwt.expiry_hours":               "JWT_EXPIRY_HOURS",
+	"jwt.refresh_hours":              "JWT_REFRESH_HOURS",
+	"tracing.exporter":               "TRACING_EXPORTER",
+	"tracing.protocol":               "TRACING_PROTOCOL",
+	"tracing.endpoint":               "TRACING_ENDPOINT",
+	"tracing.insecure":               "TRACING_INSECURE",
+	"tracing.sample_ratio":           "TRACING_SAMPLE_RATIO",
+	"rate_limit.requests_per_second": "RATE_LIMIT_RPS",
+	"rate_limit.burst":               "RATE_LIMIT_BURST",
+	"cors.allowed_origins":           "CORS_ALLOWED_ORIGINS",
+	"log.level":                      "LOG_LEVEL",
+	"secrets.refresh_interval":       "SECRETS_REFRESH_INTERVAL",
+	"secrets.vault.address":          "VAULT_ADDR",
+	"secrets.vault.mount":            "VAULT_KV_MOUNT",
+	"secrets.vault.token":            "VAULT_TOKEN",
+	"gateway.cache.backend":          "GATEWAY_CACHE_BACKEND",
+	"openapi.enabled":                "OPENAPI_VALIDATION_ENABLED",
+}
+
+// otelEnvBindings are the OpenTelemetry SDK variables read when the
+// service's own variable for the key is unset, most specific first
+var otelEnvBindings = map[string][]string{
+	"tracing.exporter":     {"OTEL_TRACES_EXPORTER"},
+	"tracing.protocol":     {"OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", "OTEL_EXPORTER_OTLP_PROTOCOL"},
+	"tracing.endpoint":     {"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "OTEL_EXPORTER_OTLP_ENDPOINT"},
+	"tracing.insecure":     {"OTEL_EXPORTER_OTLP_TRACES_INSECURE", "OTEL_EXPORTER_OTLP_INSECURE"},
+	"tracing.sample_ratio": {"OTEL_TRACES_SAMPLER_ARG"},
+}
+
+// fileEnvBindings follow the Docker convention of NAME_FILE pointing at a file
+// holding the value; it is turned into a "file:" secret reference
+var fileEnvBindings = map[string]string{
+	"database.password":   "DB_PASSWORD_FILE",
+	"jwt.secret":          "JWT_SECRET_FILE",
+	"secrets.vault.token": "VAULT_TOKEN_FILE",
+}
+
+func setDefaults(v *viper.Viper) {
+	v.SetDefault("environment", EnvironmentDevelopment)
+	v.SetDefault("server.host", "")
+	v.SetDefault("server.port", 8080)
+	v.SetDefault("server.read_timeout", "15s")
+	v.SetDefault("server.read_header_timeout", "5s")
+	v.SetDefault("server.write_timeout", "30s")
+	v.SetDefault("server.idle_timeout", "120s")
+	v.SetDefault("server.max_header_bytes", 1<<20)
+	v.SetDefault("server.shutdown_timeout", "30s")
+	v.SetDefault("server.tls.enabled", false)
+	v.SetDefault("server.tls.min_version", "1.2")
+	v.SetDefault("server.tls.reload_interval", "30s")
+	v.SetDefault("server.tls.client_auth", "none")
+	v.SetDefault("admin.host", "")
+	v.SetDefault("admin.port", 8090)
+	v.SetDefault("database.driver", "postgres")
+	v.SetDefault("database.host", "localhost")
+	v.SetDefault("database.port", 5432)
+	v.SetDefault("database.user", "postgres")
+	v.SetDefault("database.password", "postgres")
+	v.SetDefault("database.name", "apisecurity")
+	v.SetDefault("database.path", "apisecurity.db")
+	v.SetDefault("jwt.secret", DefaultJWTSecret)
+	v.SetDefault("jwt.expiry_hours", 72)
+	v.SetDefault("jwt.refresh_hours", 168)
+	v.SetDefault("tracing.exporter", TracingExporterOTLP)
+	v.SetDefault("tracing.protocol", TracingProtocolGRPC)
+	v.SetDefault("tracing.endpoint", "")
+	v.SetDefault("tracing.insecure", false)
+	v.SetDefault("tracing.sample_ratio", 1.0)
+	v.SetDefault("rate_limit.requests_per_second", 50)
+	v.SetDefault("rate_limit.burst", 100)
+	v.SetDefault("cors.allowed_origins", []string{})
+	v.SetDefault("log.level", "info")
+	v.SetDefault("secrets.refresh_interval", "5m")
+	v.SetDefault("secrets.vault.mount", "secret")
+	v.SetDefault("gateway.registry_refresh_interval", "30s")
+	v.SetDefault("gateway.cache.backend", "memory")
+	v.SetDefault("gateway.cache.max_bytes", 64<<20)
+	v.SetDefault("gateway.cache.max_entry_bytes", 1<<20)
+	v.SetDefault("gateway.cache.cleanup_interval", "1m")
+	v.SetDefault("openapi.enabled", false)
+	v.SetDefault("openapi.validate_responses", false)
+	v.SetDefault("openapi.max_body_bytes", 1<<20)
+	v.SetDefault("usage.flush_interval", "30s")
+	v.SetDefault("usage.retention", "2160h")
+	v.SetDefault("slo.window", "720h")
+	v.SetDefault("profiling.max_profiles", 10)
+	v.SetDefault("profiling.max_duration", "5m")
+}
+
+// newFlagSet declares the command line overrides. Flag names map onto config keys.
+func newFlagSet() *pflag.FlagSet {
+	fs := pflag.NewFlagSet("apisecurityplatform", pflag.ContinueOnError)
+	fs.String("config", "", "path to the configuration file")
+	fs.String("environment", "", "runtime environment (development or production)")
+	fs.String("server.host", "", "interface the HTTP server binds to")
+	fs.Int("server.port", 0, "port the HTTP server listens on")
+	fs.Int("admin.port", 0, "port the internal admin server listens on")
+	fs.String("database.driver", "", "database driver (postgres or sqlite)")
+	fs.String("database.path", "", "SQLite database file")
+	fs.String("tracing.exporter", "", "span exporter (otlp, stdout or none)")
+	fs.String("tracing.endpoint", "", "OTLP collector URL or host:port")
+	fs.String("log.level", "", "log level (debug, info, warn or error)")
+	return fs
+}
+
+// LoadConfig builds the configuration from defaults, the config file, the
+// environment and the command line flags, each overriding the one before it
+func LoadConfig(args []string) (*Config, error) {
+	config, _, err := load(args)
+	return config, err
 }
 
-func LoadConfig() (*Config, error) {
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
-	viper.AddConfigPath(".")
+// load returns the validated configuration together with the viper instance
+// that read it, so callers can watch the config file it found
+func load(args []string) (*Config, *viper.Viper, error) {
+	v := viper.New()
+	setDefaults(v)
+
+	fs := newFlagSet()
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	// Config file: explicit path from --config or CONFIG_FILE, otherwise search
+	configFile, _ := fs.GetString("config")
+	if configFile == "" {
+		v.BindEnv("config_file", "CONFIG_FILE")
+		configFile = v.GetString("config_file")
+	}
+	if configFile != "" {
+		v.SetConfigFile(configFile)
+	} else {
+		v.SetConfigName("config")
+		v.SetConfigType("yaml")
+		v.AddConfigPath(".")
+		v.AddConfigPath("./configs")
+	}
+	if err := v.ReadInConfig(); err != nil {
+		var notFound viper.ConfigFileNotFoundError
+		if configFile != "" || !errors.As(err, &notFound) {
+			return nil, nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+	}
+
+	for key, env := range envBindings {
+		if err := v.BindEnv(append([]string{key, env}, otelEnvBindings[key]...)...); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for key, env := range fileEnvBindings {
+		if path := os.Getenv(env); path != "" {
+			v.Set(key, "file:"+path)
+		}
+	}
+
+	// Only flags given explicitly override the lower layers
+	fs.Visit(func(f *pflag.Flag) {
+		if f.Name != "config" {
+			v.Set(f.Name, f.Value.String())
+		}
+	})
 
 	var config Config
-	if err := viper.ReadInConfig(); err != nil {
-		return nil, err
+	if err := v.Unmarshal(&config); err != nil {
+		return nil, nil, err
+	}
+	for i := range config.Gateway.Routes {
+		config.Gateway.Routes[i].ApplyDefaults()
+	}
+	config.Tracing.applyOTelConventions()
+
+	if err := config.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	return &config, v, nil
+}
+
+// applyOTelConventions maps values the OpenTelemetry SDK variables spell
+// differently onto the service's own
+func (t *TracingConfig) applyOTelConventions() {
+	if t.Exporter == "console" {
+		t.Exporter = TracingExporterStdout
+	}
+	if disabled, _ := strconv.ParseBool(os.Getenv("OTEL_SDK_DISABLED")); disabled {
+		t.Exporter = TracingExporterNone
+	}
+}
+
+// Validate checks the configuration for missing, malformed or insecure values
+func (c *Config) Validate() error {
+	var errs []error
+
+	switch c.Environment {
+	case EnvironmentDevelopment, EnvironmentProduction:
+	default:
+		errs = append(errs, fmt.Errorf("environment must be %q or %q, got %q",
+			EnvironmentDevelopment, EnvironmentProduction, c.Environment))
+	}
+
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		errs = append(errs, fmt.Errorf("server.port must be between 1 and 65535, got %d", c.Server.Port))
+	}
+	if c.Admin.Port < 1 || c.Admin.Port > 65535 {
+		errs = append(errs, fmt.Errorf("admin.port must be between 1 and 65535, got %d", c.Admin.Port))
+	} else if c.Admin.Port == c.Server.Port {
+		errs = append(errs, errors.New("admin.port must differ from server.port"))
+	}
+	for name, d := range map[string]time.Duration{
+		"server.read_timeout":        c.Server.ReadTimeout,
+		"server.read_header_timeout": c.Server.ReadHeaderTimeout,
+		"server.write_timeout":       c.Server.WriteTimeout,
+		"server.idle_timeout":        c.Server.IdleTimeout,
+		"server.shutdown_timeout":    c.Server.ShutdownTimeout,
+	} {
+		if d <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive", name))
+		}
+	}
+
+	if c.Server.TLS.Enabled {
+		errs = append(errs, c.Server.TLS.validate()...)
+	}
+
+	switch c.Database.Driver {
+	case "postgres":
+		if c.Database.Host == "" || c.Database.Name == "" || c.Database.User == "" {
+			errs = append(errs, errors.New("database.host, database.name and database.user are required for postgres"))
+		}
+	case "sqlite":
+		if c.Database.Path == "" {
+			errs = append(errs, errors.New("database.path is required for sqlite"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("database.driver must be postgres or sqlite, got %q", c.Database.Driver))
+	}
+
+	if c.JWT.Secret == "" {
+		errs = append(errs, errors.New("jwt.secret is required"))
+	}
+	if c.Secrets.RefreshInterval < 0 {
+		errs = append(errs, errors.New("secrets.refresh_interval must not be negative"))
+	}
+	for _, ref := range []string{c.JWT.Secret, c.Database.Password, c.Secrets.Vault.Token} {
+		if scheme, _, ok := secrets.ParseReference(ref); ok && scheme == "vault" && c.Secrets.Vault.Address == "" {
+			errs = append(errs, errors.New("secrets.vault.address is required for vault secret references"))
+			break
+		}
+	}
+	if c.JWT.ExpiryHours <= 0 {
+		errs = append(errs, errors.New("jwt.expiry_hours must be positive"))
+	}
+
+	switch c.Tracing.Exporter {
+	case TracingExporterOTLP, TracingExporterStdout, TracingExporterNone:
+	default:
+		errs = append(errs, fmt.Errorf("tracing.exporter must be otlp, stdout or none, got %q", c.Tracing.Exporter))
+	}
+	if c.Tracing.Protocol != TracingProtocolGRPC && c.Tracing.Protocol != TracingProtocolHTTP {
+		errs = append(errs, fmt.Errorf("tracing.protocol must be grpc or http/protobuf, got %q", c.Tracing.Protocol))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("tracing.sample_ratio must be between 0 and 1, got %g", c.Tracing.SampleRatio))
+	}
+
+	if c.RateLimit.RequestsPerSecond < 0 {
+		errs = append(errs, errors.New("rate_limit.requests_per_second must not be negative"))
+	}
+	if c.RateLimit.RequestsPerSecond > 0 && c.RateLimit.Burst < 1 {
+		errs = append(errs, errors.New("rate_limit.burst must be at least 1 when rate limiting is enabled"))
+	}
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin != "*" && !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://") {
+			errs = append(errs, fmt.Errorf("cors.allowed_origins entry %q must be \"*\" or an http(s) origin", origin))
+		}
+	}
+
+	if !validLogLevel(c.Log.Level) {
+		errs = append(errs, fmt.Errorf("log.level must be debug, info, warn or error, got %q", c.Log.Level))
 	}
+	for pkg, level := range c.Log.Packages {
+		if !validLogLevel(level) {
+			errs = append(errs, fmt.Errorf("log.packages.%s must be debug, info, warn or error, got %q", pkg, level))
+		}
+	}
+
+	if err := c.OpenAPI.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("openapi: %w", err))
+	}
+	if err := c.SLO.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("slo: %w", err))
+	}
+	if err := c.Usage.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("usage: %w", err))
+	}
+	if err := c.Profiling.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("profiling: %w", err))
+	}
+	if err := c.Gateway.Cache.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("gateway.cache: %w", err))
+	}
+	if err := ValidateRoutes(c.Gateway.Routes); err != nil {
+		errs = append(errs, fmt.Errorf("gateway: %w", err))
+	}
+
+	// Secret references are checked once resolved, through ValidateSecrets
+	jwtSecret, dbPassword := c.JWT.Secret, c.Database.Password
+	if secrets.IsReference(jwtSecret) {
+		jwtSecret = ""
+	}
+	if secrets.IsReference(dbPassword) {
+		dbPassword = ""
+	}
+	if err := c.ValidateSecrets(jwtSecret, dbPassword); err != nil {
+		errs = append(errs, err)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+func (t TLSConfig) validate() []error {
+	var errs []error
+	if t.CertFile == "" || t.KeyFile == "" {
+		errs = append(errs, errors.New("server.tls.cert_file and server.tls.key_file are required when TLS is enabled"))
+	}
+	if t.MinVersion != "1.2" && t.MinVersion != "1.3" {
+		errs = append(errs, fmt.Errorf("server.tls.min_version must be 1.2 or 1.3, got %q", t.MinVersion))
+	}
+	if t.ReloadInterval <= 0 {
+		errs = append(errs, errors.New("server.tls.reload_interval must be positive"))
+	}
+	switch t.ClientAuth {
+	case "none":
+	case "optional", "require":
+		if t.ClientCAFile == "" {
+			errs = append(errs, errors.New("server.tls.client_ca_file is required for client certificate authentication"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("server.tls.client_auth must be none, optional or require, got %q", t.ClientAuth))
+	}
+	for _, identity := range t.ClientIdentities {
+		if identity.Match == "" || identity.Name == "" {
+			errs = append(errs, errors.New("server.tls.client_identities entries need a match and a name"))
+			break
+		}
+	}
+	return errs
+}
+
+// ValidateSecrets rejects insecure secret values in production. Empty values
+// are skipped, so it can be called with only the secrets resolved so far.
+func (c *Config) ValidateSecrets(jwtSecret, dbPassword string) error {
+	if c.Environment != EnvironmentProduction {
+		return nil
+	}
+
+	var errs []error
+	if jwtSecret == DefaultJWTSecret {
+		errs = append(errs, errors.New("jwt.secret must be changed from the default in production"))
+	} else if jwtSecret != "" && len(jwtSecret) < 32 {
+		errs = append(errs, errors.New("jwt.secret must be at least 32 characters in production"))
+	}
+	if c.Database.Driver == "postgres" && dbPassword == "postgres" {
+		errs = append(errs, errors.New("database.password must be changed from the default in production"))
+	}
+	return errors.Join(errs...)
+}
+
+// Redacted returns a copy of the configuration with secrets masked, including
+// the header and query values gateway routes set, which often carry upstream
+// credentials. Secret references are kept, since they only name where the
+// secret lives.
+func (c Config) Redacted() Config {
+	c.Database.Password = redact(c.Database.Password)
+	c.JWT.Secret = redact(c.JWT.Secret)
+	c.Secrets.Vault.Token = redact(c.Secrets.Vault.Token)
+	routes := make([]RouteConfig, len(c.Gateway.Routes))
+	for i, route := range c.Gateway.Routes {
+		route.Transform.Request.Headers.Set = redactValues(route.Transform.Request.Headers.Set)
+		route.Transform.Request.Query.Set = redactValues(route.Transform.Request.Query.Set)
+		route.Transform.Response.Headers.Set = redactValues(route.Transform.Response.Headers.Set)
+		routes[i] = route
+	}
+	c.Gateway.Routes = routes
+	return c
+}
+
+func redactValues(values []NameValueConfig) []NameValueConfig {
+	if values == nil {
+		return nil
+	}
+	redacted := make([]NameValueConfig, len(values))
+	for i, value := range values {
+		redacted[i] = NameValueConfig{Name: value.Name, Value: redact(value.Value)}
+	}
+	return redacted
+}
 
-	if err := viper.Unmarshal(&config); err != nil {
-		return nil, err
+func redact(value string) string {
+	if value == "" || secrets.IsReference(value) {
+		return value
 	}
+	return redacted
+}
 
-	return &config, nil
+func validLogLevel(level string) bool {
+	switch strings.ToLower(level) {
+	case "debug", "info", "warn", "error":
+		return true
+	}
+	return false
+}
+
+// String renders the redacted configuration as indented JSON
+func (c Config) String() string {
+	out, err := json.MarshalIndent(c.Redacted(), "", "  ")
+	if err != nil {
+		return fmt.Sprintf("<unprintable config: %v>", err)
+	}
+	return strings.TrimSpace(string(out))
 }
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	tests := []struct {
+		name string
+		file bool
+		env  bool
+		flag bool
+		want int
+	}{
+		{"defaults", false, false, false, 8080},
+		{"file over defaults", true, false, false, 8081},
+		{"environment over file", true, true, false, 8082},
+		{"flags over environment", true, true, true, 8083},
+		{"flags over defaults", false, false, true, 8083},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var args []string
+			if tt.file {
+				args = append(args, "--config", writeConfig(t, t.TempDir(), "server:\n  port: 8081\nlog:\n  level: warn\n"))
+			}
+			if tt.env {
+				t.Setenv("SERVER_PORT", "8082")
+			}
+			if tt.flag {
+				args = append(args, "--server.port", "8083")
+			}
+
+			cfg, err := LoadConfig(args)
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if cfg.Server.Port != tt.want {
+				t.Errorf("server.port = %d, want %d", cfg.Server.Port, tt.want)
+			}
+			// Settings a higher layer leaves alone keep their lower value
+			if wantLevel := map[bool]string{false: "info", true: "warn"}[tt.file]; cfg.Log.Level != wantLevel {
+				t.Errorf("log.level = %q, want %q", cfg.Log.Level, wantLevel)
+			}
+		})
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"unknown flag", []string{"--no-such-flag"}},
+		{"missing config file", []string{"--config", "/nonexistent/config.yaml"}},
+		{"invalid value", []string{"--log.level", "verbose"}},
+	}
+	for _, tt := range tests {
+		if _, err := LoadConfig(tt.args); err == nil {
+			t.Errorf("%s: LoadConfig accepted %v", tt.name, tt.args)
+		}
+	}
+}
+
+func TestValidateProductionSecrets(t *testing.T) {
+	strong := strings.Repeat("s", 32)
+	tests := []struct {
+		name        string
+		environment string
+		jwtSecret   string
+		dbPassword  string
+		wantErr     string
+	}{
+		{"development defaults", EnvironmentDevelopment, DefaultJWTSecret, "postgres", ""},
+		{"default JWT secret", EnvironmentProduction, DefaultJWTSecret, "db-password", "jwt.secret must be changed"},
+		{"short JWT secret", EnvironmentProduction, "too-short", "db-password", "at least 32 characters"},
+		{"default database password", EnvironmentProduction, strong, "postgres", "database.password must be changed"},
+		{"secret references", EnvironmentProduction, "env:JWT_SECRET_VALUE", "file:/run/secrets/db", ""},
+		{"strong secrets", EnvironmentProduction, strong, "db-password", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("APP_ENV", tt.environment)
+			t.Setenv("JWT_SECRET", tt.jwtSecret)
+			t.Setenv("DB_PASSWORD", tt.dbPassword)
+
+			_, err := LoadConfig(nil)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("LoadConfig: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadConfig error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfigStringIsRedacted(t *testing.T) {
+	cfg := Config{
+		JWT:      JWTConfig{Secret: "jwt-secret-value"},
+		Database: DatabaseConfig{Password: "db-password-value"},
+	}
+	cfg.Secrets.Vault.Token = "vault:secret/data/vault#token"
+	route := RouteConfig{Name: "orders"}
+	route.Transform.Request.Headers.Set = []NameValueConfig{{Name: "Authorization", Value: "Bearer upstream-token"}}
+	route.Transform.Request.Query.Set = []NameValueConfig{{Name: "api_key", Value: "upstream-key"}}
+	cfg.Gateway.Routes = []RouteConfig{route}
+
+	out := cfg.String()
+	for _, secret := range []string{"jwt-secret-value", "db-password-value", "upstream-token", "upstream-key"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("String() contains %q", secret)
+		}
+	}
+	for _, kept := range []string{"vault:secret/data/vault#token", "Authorization", "api_key", redacted} {
+		if !strings.Contains(out, kept) {
+			t.Errorf("String() lacks %q", kept)
+		}
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal([]byte(out), &decoded); err != nil {
+		t.Errorf("String() is not JSON: %v", err)
+	}
+	// Redacting works on a copy
+	if cfg.JWT.Secret != "jwt-secret-value" || cfg.Gateway.Routes[0].Transform.Request.Headers.Set[0].Value != "Bearer upstream-token" {
+		t.Error("String() changed the configuration")
+	}
+}
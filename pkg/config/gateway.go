@@ -0,0 +1,521 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// GatewayConfig declares the upstream services proxied by the gateway.
+// Routes defined here are read-only; more can be added through the admin API.
+type GatewayConfig struct {
+	Routes []RouteConfig `mapstructure:"routes" json:"routes"`
+	// RegistryRefreshInterval is how often routes stored in the database are
+	// re-read, so changes made through another instance are picked up
+	RegistryRefreshInterval time.Duration `mapstructure:"registry_refresh_interval" json:"registry_refresh_interval"`
+	Cache                   CacheConfig   `mapstructure:"cache" json:"cache"`
+}
+
+// CacheConfig selects where the response cache of the gateway stores entries
+type CacheConfig struct {
+	// Backend is "memory", private to each instance, or "database", shared by
+	// all instances using the same database
+	Backend string `mapstructure:"backend" json:"backend"`
+	// MaxBytes bounds the memory backend
+	MaxBytes int64 `mapstructure:"max_bytes" json:"max_bytes"`
+	// MaxEntryBytes is the largest response body stored
+	MaxEntryBytes int64 `mapstructure:"max_entry_bytes" json:"max_entry_bytes"`
+	// CleanupInterval is how often the database backend deletes expired entries
+	CleanupInterval time.Duration `mapstructure:"cleanup_interval" json:"cleanup_interval"`
+}
+
+// Cache backends
+const (
+	CacheBackendMemory   = "memory"
+	CacheBackendDatabase = "database"
+)
+
+// Validate checks the cache settings
+func (c CacheConfig) Validate() error {
+	var errs []error
+	switch c.Backend {
+	case CacheBackendMemory, CacheBackendDatabase:
+	default:
+		errs = append(errs, fmt.Errorf("backend must be memory or database, got %q", c.Backend))
+	}
+	if c.MaxBytes <= 0 || c.MaxEntryBytes <= 0 {
+		errs = append(errs, errors.New("max_bytes and max_entry_bytes must be positive"))
+	}
+	if c.CleanupInterval <= 0 {
+		errs = append(errs, errors.New("cleanup_interval must be positive"))
+	}
+	return errors.Join(errs...)
+}
+
+// RouteConfig maps a path prefix to upstream services
+type RouteConfig struct {
+	Name       string           `mapstructure:"name" json:"name"`
+	PathPrefix string           `mapstructure:"path_prefix" json:"path_prefix"`
+	Upstreams  []UpstreamConfig `mapstructure:"upstreams" json:"upstreams"`
+	// Auth is the credential required: "jwt", "api_key", "any" or "none"
+	Auth string `mapstructure:"auth" json:"auth"`
+	// StripPrefix removes PathPrefix before forwarding
+	StripPrefix bool `mapstructure:"strip_prefix" json:"strip_prefix"`
+	// Protocol is "http" or "grpc". gRPC routes reach http upstreams over
+	// cleartext HTTP/2 (h2c).
+	Protocol string `mapstructure:"protocol" json:"protocol"`
+	// LoadBalancing is "round_robin", "least_connections" or "weighted"
+	LoadBalancing string `mapstructure:"load_balancing" json:"load_balancing"`
+	// Timeout bounds each attempt against an upstream, retries included
+	Timeout          time.Duration          `mapstructure:"timeout" json:"timeout"`
+	HealthCheck      HealthCheckConfig      `mapstructure:"health_check" json:"health_check"`
+	OutlierDetection OutlierDetectionConfig `mapstructure:"outlier_detection" json:"outlier_detection"`
+	CircuitBreaker   CircuitBreakerConfig   `mapstructure:"circuit_breaker" json:"circuit_breaker"`
+	Retry            RetryConfig            `mapstructure:"retry" json:"retry"`
+	Cache            RouteCacheConfig       `mapstructure:"cache" json:"cache"`
+	Transform        TransformConfig        `mapstructure:"transform" json:"transform"`
+	// OpenAPI validates requests before they are transformed. With
+	// StripPrefix, paths in the document are relative to PathPrefix.
+	OpenAPI   OpenAPIConfig   `mapstructure:"openapi" json:"openapi"`
+	Streaming StreamingConfig `mapstructure:"streaming" json:"streaming"`
+}
+
+// UpstreamConfig is a single upstream target
+type UpstreamConfig struct {
+	URL string `mapstructure:"url" json:"url"`
+	// Weight is only used by the weighted strategy
+	Weight int `mapstructure:"weight" json:"weight"`
+}
+
+// HealthCheckConfig configures active HTTP probes. Probing is off unless Path is set.
+type HealthCheckConfig struct {
+	Path               string        `mapstructure:"path" json:"path"`
+	Interval           time.Duration `mapstructure:"interval" json:"interval"`
+	Timeout            time.Duration `mapstructure:"timeout" json:"timeout"`
+	HealthyThreshold   int           `mapstructure:"healthy_threshold" json:"healthy_threshold"`
+	UnhealthyThreshold int           `mapstructure:"unhealthy_threshold" json:"unhealthy_threshold"`
+}
+
+// OutlierDetectionConfig configures passive ejection of upstreams that keep
+// failing live traffic. ConsecutiveFailures of 0 disables it.
+type OutlierDetectionConfig struct {
+	ConsecutiveFailures int           `mapstructure:"consecutive_failures" json:"consecutive_failures"`
+	BaseEjectionTime    time.Duration `mapstructure:"base_ejection_time" json:"base_ejection_time"`
+	// MaxEjectionPercent caps the share of a route's upstreams ejected at once
+	MaxEjectionPercent int `mapstructure:"max_ejection_percent" json:"max_ejection_percent"`
+}
+
+// CircuitBreakerConfig configures the per-upstream circuit breaker, which
+// opens when the failure ratio over Window exceeds FailureRatio.
+// FailureRatio of 0 disables it.
+type CircuitBreakerConfig struct {
+	FailureRatio float64       `mapstructure:"failure_ratio" json:"failure_ratio"`
+	MinRequests  int           `mapstructure:"min_requests" json:"min_requests"`
+	Window       time.Duration `mapstructure:"window" json:"window"`
+	// OpenTimeout is how long the breaker stays open before letting a probe through
+	OpenTimeout time.Duration `mapstructure:"open_timeout" json:"open_timeout"`
+}
+
+// RetryConfig configures retries of idempotent requests on another upstream
+type RetryConfig struct {
+	// Attempts is the total number of tries, so 1 disables retries
+	Attempts int `mapstructure:"attempts" json:"attempts"`
+	// BudgetRatio caps retries as a share of the route's requests
+	BudgetRatio float64 `mapstructure:"budget_ratio" json:"budget_ratio"`
+	// MinRetriesPerSecond is allowed regardless of the ratio, for low-traffic routes
+	MinRetriesPerSecond int `mapstructure:"min_retries_per_second" json:"min_retries_per_second"`
+}
+
+// RouteCacheConfig configures response caching of a route's GET requests.
+// Freshness follows the upstream's Cache-Control and Expires headers.
+type RouteCacheConfig struct {
+	Enabled bool `mapstructure:"enabled" json:"enabled"`
+	// DefaultTTL applies to responses without explicit freshness; 0 leaves them uncached
+	DefaultTTL time.Duration `mapstructure:"default_ttl" json:"default_ttl"`
+	// MaxTTL caps the freshness of any response; 0 means no cap
+	MaxTTL time.Duration `mapstructure:"max_ttl" json:"max_ttl"`
+	// KeyBy scopes entries to the caller: "user" and/or "api_key"
+	KeyBy []string `mapstructure:"key_by" json:"key_by"`
+	// Tags are attached to every entry of the route, for purging
+	Tags []string `mapstructure:"tags" json:"tags"`
+}
+
+// StreamingConfig limits WebSocket connections and server-sent event streams
+type StreamingConfig struct {
+	// MaxMessageBytes caps the WebSocket messages a client sends; a larger
+	// message closes the connection
+	MaxMessageBytes int64 `mapstructure:"max_message_bytes" json:"max_message_bytes"`
+	// MessagesPerSecond throttles the WebSocket messages a client sends on
+	// each connection; 0 disables the limit
+	MessagesPerSecond float64 `mapstructure:"messages_per_second" json:"messages_per_second"`
+	MessageBurst      int     `mapstructure:"message_burst" json:"message_burst"`
+	// RevocationCheckInterval is how often the credential of an open stream
+	// is checked, so the stream can be closed once it is revoked
+	RevocationCheckInterval time.Duration `mapstructure:"revocation_check_interval" json:"revocation_check_interval"`
+}
+
+// TransformConfig rewrites requests before they reach the upstream and
+// responses before they reach the caller
+type TransformConfig struct {
+	Request  RequestTransformConfig  `mapstructure:"request" json:"request"`
+	Response ResponseTransformConfig `mapstructure:"response" json:"response"`
+}
+
+// RequestTransformConfig is applied after authentication, in the order
+// headers, path, query
+type RequestTransformConfig struct {
+	Headers HeaderTransformConfig `mapstructure:"headers" json:"headers"`
+	Path    PathRewriteConfig     `mapstructure:"path" json:"path"`
+	Query   QueryTransformConfig  `mapstructure:"query" json:"query"`
+}
+
+// ResponseTransformConfig is applied to every response of the route,
+// including cached ones
+type ResponseTransformConfig struct {
+	Headers HeaderTransformConfig `mapstructure:"headers" json:"headers"`
+	// Mask removes or redacts fields of JSON bodies
+	Mask []MaskRuleConfig `mapstructure:"mask" json:"mask"`
+	// MaxBodyBytes is the largest body that is masked; larger responses
+	// are refused rather than passed through unmasked
+	MaxBodyBytes int64 `mapstructure:"max_body_bytes" json:"max_body_bytes"`
+}
+
+// HeaderTransformConfig removes headers, then sets headers. Entries are a
+// list rather than a map because config keys are case-insensitive.
+type HeaderTransformConfig struct {
+	Set    []NameValueConfig `mapstructure:"set" json:"set"`
+	Remove []string          `mapstructure:"remove" json:"remove"`
+}
+
+// NameValueConfig is a header or query parameter and its value
+type NameValueConfig struct {
+	Name  string `mapstructure:"name" json:"name"`
+	Value string `mapstructure:"value" json:"value"`
+}
+
+// PathRewriteConfig replaces matches of Pattern in the request path with
+// Replacement, which may refer to capture groups as $1. It applies before
+// strip_prefix. Rewriting is off unless Pattern is set.
+type PathRewriteConfig struct {
+	Pattern     string `mapstructure:"pattern" json:"pattern"`
+	Replacement string `mapstructure:"replacement" json:"replacement"`
+}
+
+// QueryTransformConfig maps query parameters, in the order rename, remove, set
+type QueryTransformConfig struct {
+	Rename []QueryRenameConfig `mapstructure:"rename" json:"rename"`
+	Remove []string            `mapstructure:"remove" json:"remove"`
+	Set    []NameValueConfig   `mapstructure:"set" json:"set"`
+}
+
+// QueryRenameConfig renames a query parameter
+type QueryRenameConfig struct {
+	From string `mapstructure:"from" json:"from"`
+	To   string `mapstructure:"to" json:"to"`
+}
+
+// MaskRuleConfig masks a JSON field. Field is a dot-separated path in which
+// arrays are traversed transparently and * matches any key, so "items.email"
+// masks the email of every element of items.
+type MaskRuleConfig struct {
+	Field string `mapstructure:"field" json:"field"`
+	// Action is "remove", the default, or "redact", which keeps the field
+	// with a placeholder value
+	Action string `mapstructure:"action" json:"action"`
+	// UnlessScope exempts callers whose API key has this scope
+	UnlessScope string `mapstructure:"unless_scope" json:"unless_scope"`
+}
+
+// Mask actions
+const (
+	MaskActionRemove = "remove"
+	MaskActionRedact = "redact"
+)
+
+// Validate checks the transformation rules
+func (t TransformConfig) Validate() error {
+	var errs []error
+	headers := append(append([]NameValueConfig{}, t.Request.Headers.Set...), t.Response.Headers.Set...)
+	for _, header := range headers {
+		if header.Name == "" {
+			errs = append(errs, errors.New("transform header names are required"))
+		}
+	}
+	if t.Request.Path.Pattern != "" {
+		if _, err := regexp.Compile(t.Request.Path.Pattern); err != nil {
+			errs = append(errs, fmt.Errorf("transform.request.path.pattern: %w", err))
+		}
+	} else if t.Request.Path.Replacement != "" {
+		errs = append(errs, errors.New("transform.request.path.replacement requires a pattern"))
+	}
+	for _, rename := range t.Request.Query.Rename {
+		if rename.From == "" || rename.To == "" {
+			errs = append(errs, errors.New("transform.request.query.rename entries need from and to"))
+		}
+	}
+	for _, param := range t.Request.Query.Set {
+		if param.Name == "" {
+			errs = append(errs, errors.New("transform query parameter names are required"))
+		}
+	}
+	for _, rule := range t.Response.Mask {
+		if rule.Field == "" || strings.HasPrefix(rule.Field, ".") || strings.HasSuffix(rule.Field, ".") || strings.Contains(rule.Field, "..") {
+			errs = append(errs, fmt.Errorf("transform.response.mask field %q is not a valid path", rule.Field))
+		}
+		switch rule.Action {
+		case MaskActionRemove, MaskActionRedact:
+		default:
+			errs = append(errs, fmt.Errorf("transform.response.mask action must be remove or redact, got %q", rule.Action))
+		}
+	}
+	if t.Response.MaxBodyBytes <= 0 {
+		errs = append(errs, errors.New("transform.response.max_body_bytes must be positive"))
+	}
+	return errors.Join(errs...)
+}
+
+// Route auth modes
+const (
+	RouteAuthJWT    = "jwt"
+	RouteAuthAPIKey = "api_key"
+	RouteAuthAny    = "any"
+	RouteAuthNone   = "none"
+)
+
+// Route protocols
+const (
+	RouteProtocolHTTP = "http"
+	RouteProtocolGRPC = "grpc"
+)
+
+// Load balancing strategies
+const (
+	LoadBalancingRoundRobin       = "round_robin"
+	LoadBalancingLeastConnections = "least_connections"
+	LoadBalancingWeighted         = "weighted"
+)
+
+// ApplyDefaults fills in the settings a route left empty. Routes are a list,
+// so they cannot use the viper defaults.
+func (r *RouteConfig) ApplyDefaults() {
+	if r.Protocol == "" {
+		r.Protocol = RouteProtocolHTTP
+	}
+	if r.LoadBalancing == "" {
+		r.LoadBalancing = LoadBalancingRoundRobin
+	}
+	if r.Timeout == 0 {
+		r.Timeout = 30 * time.Second
+	}
+	for i := range r.Upstreams {
+		if r.Upstreams[i].Weight == 0 {
+			r.Upstreams[i].Weight = 1
+		}
+	}
+
+	hc := &r.HealthCheck
+	if hc.Interval == 0 {
+		hc.Interval = 10 * time.Second
+	}
+	if hc.Timeout == 0 {
+		hc.Timeout = 2 * time.Second
+	}
+	if hc.HealthyThreshold == 0 {
+		hc.HealthyThreshold = 2
+	}
+	if hc.UnhealthyThreshold == 0 {
+		hc.UnhealthyThreshold = 3
+	}
+
+	od := &r.OutlierDetection
+	if od.BaseEjectionTime == 0 {
+		od.BaseEjectionTime = 30 * time.Second
+	}
+	if od.MaxEjectionPercent == 0 {
+		od.MaxEjectionPercent = 50
+	}
+
+	cb := &r.CircuitBreaker
+	if cb.MinRequests == 0 {
+		cb.MinRequests = 20
+	}
+	if cb.Window == 0 {
+		cb.Window = 10 * time.Second
+	}
+	if cb.OpenTimeout == 0 {
+		cb.OpenTimeout = 30 * time.Second
+	}
+
+	if r.Retry.Attempts == 0 {
+		r.Retry.Attempts = 1
+	}
+	if r.Retry.BudgetRatio == 0 {
+		r.Retry.BudgetRatio = 0.2
+	}
+	if r.Retry.MinRetriesPerSecond == 0 {
+		r.Retry.MinRetriesPerSecond = 1
+	}
+
+	for i := range r.Transform.Response.Mask {
+		if r.Transform.Response.Mask[i].Action == "" {
+			r.Transform.Response.Mask[i].Action = MaskActionRemove
+		}
+	}
+	if r.Transform.Response.MaxBodyBytes == 0 {
+		r.Transform.Response.MaxBodyBytes = 1 << 20
+	}
+	if r.OpenAPI.MaxBodyBytes == 0 {
+		r.OpenAPI.MaxBodyBytes = 1 << 20
+	}
+
+	st := &r.Streaming
+	if st.MaxMessageBytes == 0 {
+		st.MaxMessageBytes = 1 << 20
+	}
+	if st.MessageBurst == 0 {
+		st.MessageBurst = 10
+	}
+	if st.RevocationCheckInterval == 0 {
+		st.RevocationCheckInterval = 30 * time.Second
+	}
+}
+
+// UnmarshalJSON decodes a route from the same keys as the config file, so
+// durations may be given as strings such as "30s". Unknown keys are rejected.
+func (r *RouteConfig) UnmarshalJSON(data []byte) error {
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	var route RouteConfig
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:  mapstructure.StringToTimeDurationHookFunc(),
+		ErrorUnused: true,
+		Result:      &route,
+	})
+	if err != nil {
+		return err
+	}
+	if err := decoder.Decode(raw); err != nil {
+		return err
+	}
+	*r = route
+	return nil
+}
+
+// ValidateRoutes checks each route and that no two share a name or path prefix
+func ValidateRoutes(routes []RouteConfig) error {
+	var errs []error
+	names := make(map[string]bool)
+	prefixes := make(map[string]bool)
+	for _, route := range routes {
+		if err := route.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+		prefix := strings.TrimSuffix(route.PathPrefix, "/")
+		if names[route.Name] || prefixes[prefix] {
+			errs = append(errs, fmt.Errorf("route %q duplicates the name or path_prefix of another route", route.Name))
+		}
+		names[route.Name] = true
+		prefixes[prefix] = true
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks a single route definition
+func (r RouteConfig) Validate() error {
+	var errs []error
+	if r.Name == "" {
+		errs = append(errs, errors.New("name is required"))
+	}
+	if !strings.HasPrefix(r.PathPrefix, "/") || r.PathPrefix == "/" {
+		errs = append(errs, fmt.Errorf("path_prefix must start with / and not be the root, got %q", r.PathPrefix))
+	}
+	if len(r.Upstreams) == 0 {
+		errs = append(errs, errors.New("at least one upstream is required"))
+	}
+	for _, upstream := range r.Upstreams {
+		u, err := url.Parse(upstream.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("upstream %q must be an absolute http(s) URL", upstream.URL))
+		}
+		if upstream.Weight < 0 {
+			errs = append(errs, fmt.Errorf("upstream %q weight must not be negative", upstream.URL))
+		}
+	}
+	switch r.Auth {
+	case RouteAuthJWT, RouteAuthAPIKey, RouteAuthAny, RouteAuthNone:
+	default:
+		errs = append(errs, fmt.Errorf("auth must be jwt, api_key, any or none, got %q", r.Auth))
+	}
+	switch r.Protocol {
+	case RouteProtocolHTTP:
+	case RouteProtocolGRPC:
+		// gRPC messages are framed protobuf, which these features cannot read
+		if r.Cache.Enabled || r.OpenAPI.Enabled || len(r.Transform.Response.Mask) > 0 {
+			errs = append(errs, errors.New("grpc routes cannot enable cache, openapi or transform.response.mask"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("protocol must be http or grpc, got %q", r.Protocol))
+	}
+	switch r.LoadBalancing {
+	case LoadBalancingRoundRobin, LoadBalancingLeastConnections, LoadBalancingWeighted:
+	default:
+		errs = append(errs, fmt.Errorf("load_balancing must be round_robin, least_connections or weighted, got %q", r.LoadBalancing))
+	}
+	if r.Timeout < 0 {
+		errs = append(errs, errors.New("timeout must not be negative"))
+	}
+	if r.HealthCheck.Path != "" && !strings.HasPrefix(r.HealthCheck.Path, "/") {
+		errs = append(errs, fmt.Errorf("health_check.path must start with /, got %q", r.HealthCheck.Path))
+	}
+	if r.OutlierDetection.ConsecutiveFailures < 0 {
+		errs = append(errs, errors.New("outlier_detection.consecutive_failures must not be negative"))
+	}
+	if p := r.OutlierDetection.MaxEjectionPercent; p < 0 || p > 100 {
+		errs = append(errs, fmt.Errorf("outlier_detection.max_ejection_percent must be between 0 and 100, got %d", p))
+	}
+	if ratio := r.CircuitBreaker.FailureRatio; ratio < 0 || ratio > 1 {
+		errs = append(errs, fmt.Errorf("circuit_breaker.failure_ratio must be between 0 and 1, got %v", ratio))
+	}
+	for _, key := range r.Cache.KeyBy {
+		if key != "user" && key != "api_key" {
+			errs = append(errs, fmt.Errorf("cache.key_by entries must be user or api_key, got %q", key))
+		}
+	}
+	if r.Cache.DefaultTTL < 0 || r.Cache.MaxTTL < 0 {
+		errs = append(errs, errors.New("cache ttls must not be negative"))
+	}
+	if err := r.Transform.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if r.OpenAPI.Enabled && r.OpenAPI.Spec == "" {
+		errs = append(errs, errors.New("openapi.spec is required when openapi is enabled"))
+	}
+	if err := r.OpenAPI.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("openapi: %w", err))
+	}
+	if r.Streaming.MaxMessageBytes <= 0 || r.Streaming.RevocationCheckInterval <= 0 {
+		errs = append(errs, errors.New("streaming.max_message_bytes and streaming.revocation_check_interval must be positive"))
+	}
+	if r.Streaming.MessagesPerSecond < 0 || r.Streaming.MessageBurst < 1 {
+		errs = append(errs, errors.New("streaming.messages_per_second must not be negative and streaming.message_burst must be at least 1"))
+	}
+	if r.Retry.Attempts < 1 {
+		errs = append(errs, fmt.Errorf("retry.attempts must be at least 1, got %d", r.Retry.Attempts))
+	}
+	if r.Retry.BudgetRatio < 0 || r.Retry.MinRetriesPerSecond < 0 {
+		errs = append(errs, errors.New("retry budget must not be negative"))
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("route %q: %w", r.Name, errors.Join(errs...))
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package config
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	// TriggerFile marks a reload caused by a change to the config file
+	TriggerFile = "file"
+	// TriggerSignal marks a reload caused by SIGHUP
+	TriggerSignal = "signal"
+)
+
+// ReloadEvent describes the outcome of a configuration reload
+type ReloadEvent struct {
+	Trigger  string
+	Previous *Config
+	// Current is the configuration in effect after the reload. When Err is
+	// set the reload was rejected and Current is the previous configuration.
+	Current *Config
+	Err     error
+	// RestartRequired lists changed sections that are only read at startup
+	RestartRequired []string
+}
+
+// Manager holds the live configuration and reloads it when the config file
+// changes or the process receives SIGHUP. Readers always see a complete,
+// validated Config; a reload that fails validation leaves the previous one in place.
+type Manager struct {
+	args    []string
+	current atomic.Pointer[Config]
+	watched string
+
+	mu       sync.Mutex
+	checks   []func(*Config) error
+	onReload []func(ReloadEvent)
+}
+
+// NewManager loads the initial configuration from the given command line arguments
+func NewManager(args []string) (*Manager, error) {
+	cfg, v, err := load(args)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manager{args: args, watched: v.ConfigFileUsed()}
+	m.current.Store(cfg)
+	return m, nil
+}
+
+// Current returns the configuration currently in effect
+func (m *Manager) Current() *Config {
+	return m.current.Load()
+}
+
+// OnReload registers a callback run after every reload attempt, successful or not
+func (m *Manager) OnReload(fn func(ReloadEvent)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onReload = append(m.onReload, fn)
+}
+
+// Check registers a validation run on every reloaded configuration before it
+// is swapped in, for settings only their consumer can vouch for. A failing
+// check rejects the reload.
+func (m *Manager) Check(fn func(*Config) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.checks = append(m.checks, fn)
+}
+
+// Reload re-reads every configuration layer and swaps in the result if it is
+// valid. The OnReload callbacks run once the swap is done.
+func (m *Manager) Reload(trigger string) error {
+	m.mu.Lock()
+	previous := m.current.Load()
+	event := ReloadEvent{Trigger: trigger, Previous: previous, Current: previous}
+	next, err := m.loadChecked()
+	if err != nil {
+		event.Err = err
+	} else {
+		m.current.Store(next)
+		event.Current = next
+		event.RestartRequired = restartRequired(previous, next)
+	}
+	callbacks := slices.Clone(m.onReload)
+	m.mu.Unlock()
+
+	for _, fn := range callbacks {
+		fn(event)
+	}
+	return event.Err
+}
+
+// loadChecked loads the configuration and runs the registered checks on it;
+// the caller holds mu
+func (m *Manager) loadChecked() (*Config, error) {
+	next, _, err := load(m.args)
+	if err != nil {
+		return nil, err
+	}
+	for _, check := range m.checks {
+		if err := check(next); err != nil {
+			return nil, err
+		}
+	}
+	return next, nil
+}
+
+// Watch reloads on config file changes and SIGHUP until ctx is cancelled
+func (m *Manager) Watch(ctx context.Context) error {
+	var watcher *fsnotify.Watcher
+	var fileEvents <-chan fsnotify.Event
+	if m.watched != "" {
+		var err error
+		if watcher, err = newFileWatcher(m.watched); err != nil {
+			return err
+		}
+		fileEvents = watcher.Events
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(signals)
+		if watcher != nil {
+			defer watcher.Close()
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-signals:
+				m.Reload(TriggerSignal)
+			case event, ok := <-fileEvents:
+				if !ok {
+					fileEvents = nil
+					continue
+				}
+				if isConfigChange(event, m.watched) {
+					m.Reload(TriggerFile)
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// restartRequired reports the sections that changed but are only applied at startup
+func restartRequired(previous, next *Config) []string {
+	var sections []string
+	if previous.Environment != next.Environment {
+		sections = append(sections, "environment")
+	}
+	if !reflect.DeepEqual(previous.Server, next.Server) {
+		sections = append(sections, "server")
+	}
+	if !reflect.DeepEqual(previous.Admin, next.Admin) {
+		sections = append(sections, "admin")
+	}
+	if !reflect.DeepEqual(previous.Database, next.Database) {
+		sections = append(sections, "database")
+	}
+	if !reflect.DeepEqual(previous.JWT, next.JWT) {
+		sections = append(sections, "jwt")
+	}
+	if previous.Gateway.RegistryRefreshInterval != next.Gateway.RegistryRefreshInterval {
+		sections = append(sections, "gateway.registry_refresh_interval")
+	}
+	if !reflect.DeepEqual(previous.Gateway.Cache, next.Gateway.Cache) {
+		sections = append(sections, "gateway.cache")
+	}
+	if !reflect.DeepEqual(previous.OpenAPI, next.OpenAPI) {
+		sections = append(sections, "openapi")
+	}
+	if !reflect.DeepEqual(previous.SLO, next.SLO) {
+		sections = append(sections, "slo")
+	}
+	if previous.Usage != next.Usage {
+		sections = append(sections, "usage")
+	}
+	if previous.Profiling != next.Profiling {
+		sections = append(sections, "profiling")
+	}
+	// Only the sample ratio is applied at runtime
+	exporter := previous.Tracing
+	exporter.SampleRatio = next.Tracing.SampleRatio
+	if exporter != next.Tracing {
+		sections = append(sections, "tracing")
+	}
+	return sections
+}
+
+// newFileWatcher watches the directory holding the config file, so that
+// editors replacing the file and Kubernetes ConfigMap symlink swaps are seen
+func newFileWatcher(path string) (*fsnotify.Watcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	return watcher, nil
+}
+
+func isConfigChange(event fsnotify.Event, path string) bool {
+	if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) && !event.Has(fsnotify.Rename) {
+		return false
+	}
+	name := filepath.Clean(event.Name)
+	// ConfigMap volumes update by re-pointing the "..data" symlink
+	return name == filepath.Clean(path) || filepath.Base(name) == "..data"
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// writeConfig writes a YAML config file into dir and returns its path
+func writeConfig(t *testing.T, dir, yaml string) string {
+	t.Helper()
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// newTestManager loads a Manager from a config file holding yaml, recording
+// the events of every reload
+func newTestManager(t *testing.T, yaml string) (*Manager, string, *[]ReloadEvent) {
+	t.Helper()
+	dir := t.TempDir()
+	path := writeConfig(t, dir, yaml)
+	m, err := NewManager([]string{"--config", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []ReloadEvent
+	m.OnReload(func(event ReloadEvent) {
+		events = append(events, event)
+	})
+	return m, dir, &events
+}
+
+func TestReload(t *testing.T) {
+	m, dir, events := newTestManager(t, "rate_limit:\n  requests_per_second: 10\n")
+	initial := m.Current()
+
+	writeConfig(t, dir, "rate_limit:\n  requests_per_second: 20\nlog:\n  level: debug\n")
+	if err := m.Reload(TriggerSignal); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+
+	cfg := m.Current()
+	if cfg.RateLimit.RequestsPerSecond != 20 || cfg.Log.Level != "debug" {
+		t.Errorf("reloaded config: rate %g, log level %q", cfg.RateLimit.RequestsPerSecond, cfg.Log.Level)
+	}
+	if len(*events) != 1 {
+		t.Fatalf("%d reload events, want 1", len(*events))
+	}
+	event := (*events)[0]
+	if event.Trigger != TriggerSignal || event.Err != nil || event.Previous != initial || event.Current != cfg || len(event.RestartRequired) != 0 {
+		t.Errorf("event = %+v", event)
+	}
+}
+
+func TestReloadRejected(t *testing.T) {
+	m, dir, events := newTestManager(t, "rate_limit:\n  requests_per_second: 10\n")
+	initial := m.Current()
+
+	writeConfig(t, dir, "rate_limit:\n  requests_per_second: 20\nlog:\n  level: verbose\n")
+	if err := m.Reload(TriggerFile); err == nil {
+		t.Fatal("Reload accepted an invalid log level")
+	}
+
+	if m.Current() != initial {
+		t.Error("rejected reload replaced the configuration")
+	}
+	if len(*events) != 1 {
+		t.Fatalf("%d reload events, want 1", len(*events))
+	}
+	if event := (*events)[0]; event.Err == nil || event.Current != initial || event.Previous != initial {
+		t.Errorf("event = %+v, want the error and the previous configuration", event)
+	}
+}
+
+func TestReloadCheck(t *testing.T) {
+	m, dir, events := newTestManager(t, "rate_limit:\n  requests_per_second: 10\n")
+	initial := m.Current()
+	errRejected := errors.New("rejected by check")
+	m.Check(func(cfg *Config) error {
+		if cfg.RateLimit.RequestsPerSecond > 15 {
+			return errRejected
+		}
+		return nil
+	})
+
+	writeConfig(t, dir, "rate_limit:\n  requests_per_second: 20\n")
+	if err := m.Reload(TriggerFile); !errors.Is(err, errRejected) {
+		t.Fatalf("Reload = %v, want the check's error", err)
+	}
+	if m.Current() != initial || !errors.Is((*events)[0].Err, errRejected) {
+		t.Error("configuration failing a check was swapped in")
+	}
+
+	writeConfig(t, dir, "rate_limit:\n  requests_per_second: 12\n")
+	if err := m.Reload(TriggerFile); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if m.Current().RateLimit.RequestsPerSecond != 12 {
+		t.Error("configuration passing the checks was not swapped in")
+	}
+}
+
+func TestReloadCallbacksRunUnlocked(t *testing.T) {
+	m, dir, _ := newTestManager(t, "log:\n  level: info\n")
+	// Callbacks may use the Manager, e.g. to register further callbacks
+	m.OnReload(func(ReloadEvent) {
+		m.OnReload(func(ReloadEvent) {})
+	})
+
+	writeConfig(t, dir, "log:\n  level: warn\n")
+	if err := m.Reload(TriggerSignal); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+}
+
+func TestReloadRestartRequired(t *testing.T) {
+	tests := []struct {
+		name   string
+		change string
+		want   []string
+	}{
+		{"reloadable settings", "log:\n  level: debug\ncors:\n  allowed_origins: [\"https://example.com\"]\ntracing:\n  sample_ratio: 0.5\n", nil},
+		{"server", "server:\n  port: 9080\n", []string{"server"}},
+		{"database", "database:\n  driver: sqlite\n", []string{"database"}},
+		{"tracing exporter", "tracing:\n  exporter: none\n", []string{"tracing"}},
+		{"several sections", "admin:\n  port: 9090\nprofiling:\n  max_profiles: 3\n", []string{"admin", "profiling"}},
+		{"gateway cache", "gateway:\n  cache:\n    max_bytes: 1024\n", []string{"gateway.cache"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, dir, events := newTestManager(t, "")
+			writeConfig(t, dir, tt.change)
+			if err := m.Reload(TriggerFile); err != nil {
+				t.Fatalf("Reload: %v", err)
+			}
+			if got := (*events)[0].RestartRequired; !slices.Equal(got, tt.want) {
+				t.Errorf("RestartRequired = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
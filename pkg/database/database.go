@@ -2,76 +2,81 @@ package database
 
 import (
 	"fmt"
-	"log"
-	"os"
 
+	"apisecurityplatform/pkg/config"
 	"apisecurityplatform/pkg/models"
 	"apisecurityplatform/pkg/observability"
 	"context"
 
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
-	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-var DB *gorm.DB
+// schema is the set of models migrated at startup
+var schema = []any{&models.User{}, &models.APIKey{}, &models.Route{}, &models.RouteVersion{}, &models.CacheEntry{}, &models.UsageRecord{}}
 
-func ConnectDatabase() {
+// Open connects to the configured database and migrates the schema. password
+// supplies the current password; when nil the configured password is used.
+func Open(settings config.DatabaseConfig, password PasswordFunc) (*gorm.DB, error) {
 	ctx := context.Background()
 	tracer := observability.GetTracer()
 	ctx, span := tracer.Start(ctx, "database.connect")
 	defer span.End()
 
-	host := os.Getenv("DB_HOST")
-	user := os.Getenv("DB_USER")
-	password := os.Getenv("DB_PASSWORD")
-	dbname := os.Getenv("DB_NAME")
-	port := os.Getenv("DB_PORT")
-
-	// Set default values if environment variables are not set
-	if host == "" {
-		host = "localhost"
-	}
-	if user == "" {
-		user = "postgres"
-	}
-	if password == "" {
-		password = "postgres"
-	}
-	if dbname == "" {
-		dbname = "apisecurity"
-	}
-	if port == "" {
-		port = "5432"
+	dialect, err := dialectFor(settings.Driver)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Unsupported database driver")
+		return nil, err
 	}
 
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
-		host, user, password, dbname, port)
-
 	// Add database info to span
-	span.SetAttributes(
-		attribute.String("db.system", "postgresql"),
-		attribute.String("db.name", dbname),
-		attribute.String("db.host", host),
-		attribute.String("db.port", port),
-	)
+	span.SetAttributes(attribute.String("db.system", dialect.system))
+	span.SetAttributes(dialect.attributes(settings)...)
 
-	database, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if password == nil {
+		password = func() string { return settings.Password }
+	}
+	dialector, err := dialect.open(settings, password)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Invalid database settings")
+		return nil, fmt.Errorf("invalid database settings: %w", err)
+	}
+
+	database, err := gorm.Open(dialector, &gorm.Config{
+		// Map driver specific errors such as unique violations to gorm errors
+		TranslateError: true,
+		Logger:         gormLogger{},
+	})
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to connect to database")
-		log.Fatal("Failed to connect to database:", err)
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	// Trace every query as a child of the caller's span, such as the request's
+	if err := database.Use(newInstrumentation(dialect.system)); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to instrument database")
+		return nil, fmt.Errorf("failed to instrument database: %w", err)
+	}
+
+	if err := dialect.configure(database, settings); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to configure database")
+		return nil, fmt.Errorf("failed to configure database: %w", err)
 	}
 
 	// Auto-migrate the database schemas
-	err = database.AutoMigrate(&models.User{}, &models.APIKey{})
+	err = database.WithContext(ctx).AutoMigrate(schema...)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to migrate database")
-		log.Fatal("Failed to migrate database:", err)
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
 	}
 
 	span.SetStatus(codes.Ok, "Connected to database")
-	DB = database
+	return database, nil
 }
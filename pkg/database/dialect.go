@@ -0,0 +1,106 @@
+package database
+
+import (
+	"apisecurityplatform/pkg/config"
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+const (
+	// DriverPostgres selects PostgreSQL, used in production
+	DriverPostgres = "postgres"
+	// DriverSQLite selects the pure-Go SQLite driver for local and embedded use
+	DriverSQLite = "sqlite"
+)
+
+// PasswordFunc returns the current database password. It is called for every
+// new connection, so a rotated password is picked up without a restart.
+type PasswordFunc func() string
+
+// dialect bundles the driver specific parts of opening a database
+type dialect struct {
+	system     string
+	open       func(config.DatabaseConfig, PasswordFunc) (gorm.Dialector, error)
+	configure  func(*gorm.DB, config.DatabaseConfig) error
+	attributes func(config.DatabaseConfig) []attribute.KeyValue
+}
+
+var dialects = map[string]dialect{
+	DriverPostgres: {
+		system: "postgresql",
+		open: func(s config.DatabaseConfig, password PasswordFunc) (gorm.Dialector, error) {
+			dsn := fmt.Sprintf("host=%s user=%s dbname=%s port=%d sslmode=disable",
+				s.Host, s.User, s.Name, s.Port)
+			connConfig, err := pgx.ParseConfig(dsn)
+			if err != nil {
+				return nil, err
+			}
+			conn := stdlib.OpenDB(*connConfig, stdlib.OptionBeforeConnect(
+				func(_ context.Context, cc *pgx.ConnConfig) error {
+					cc.Password = password()
+					return nil
+				}))
+			return postgres.New(postgres.Config{Conn: conn}), nil
+		},
+		configure: func(db *gorm.DB, s config.DatabaseConfig) error {
+			sqlDB, err := db.DB()
+			if err != nil {
+				return err
+			}
+			// Recycle connections so credential rotation reaches the whole pool
+			sqlDB.SetConnMaxLifetime(30 * time.Minute)
+			return nil
+		},
+		attributes: func(s config.DatabaseConfig) []attribute.KeyValue {
+			return []attribute.KeyValue{
+				attribute.String("db.name", s.Name),
+				attribute.String("db.host", s.Host),
+				attribute.String("db.port", strconv.Itoa(s.Port)),
+			}
+		},
+	},
+	DriverSQLite: {
+		system: "sqlite",
+		open: func(s config.DatabaseConfig, _ PasswordFunc) (gorm.Dialector, error) {
+			// Foreign keys are off by default in SQLite, and the busy timeout
+			// avoids spurious "database is locked" errors under concurrent writes
+			dsn := s.Path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+			if s.Path != ":memory:" {
+				dsn += "&_pragma=journal_mode(WAL)"
+			}
+			return sqlite.Open(dsn), nil
+		},
+		configure: func(db *gorm.DB, s config.DatabaseConfig) error {
+			sqlDB, err := db.DB()
+			if err != nil {
+				return err
+			}
+			// Every connection to ":memory:" gets its own database, and SQLite
+			// only allows a single writer, so share one connection
+			sqlDB.SetMaxOpenConns(1)
+			return nil
+		},
+		attributes: func(s config.DatabaseConfig) []attribute.KeyValue {
+			return []attribute.KeyValue{
+				attribute.String("db.name", s.Path),
+			}
+		},
+	},
+}
+
+func dialectFor(driver string) (dialect, error) {
+	d, ok := dialects[driver]
+	if !ok {
+		return dialect{}, fmt.Errorf("unsupported database driver %q", driver)
+	}
+	return d, nil
+}
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"apisecurityplatform/pkg/logging"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+var logger = logging.For("database")
+
+// slowQueryThreshold is how long a query may take before it is logged as slow
+const slowQueryThreshold = 200 * time.Millisecond
+
+// gormLogger writes gorm's messages through the database package logger,
+// whose level decides what is logged. Failed queries are logged as errors,
+// slow ones as warnings and every other one at debug level.
+type gormLogger struct{}
+
+func (gormLogger) LogMode(gormlogger.LogLevel) gormlogger.Interface {
+	return gormLogger{}
+}
+
+func (gormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	logger.InfoContext(ctx, fmt.Sprintf(msg, data...))
+}
+
+func (gormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	logger.WarnContext(ctx, fmt.Sprintf(msg, data...))
+}
+
+func (gormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	logger.ErrorContext(ctx, fmt.Sprintf(msg, data...))
+}
+
+func (gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	elapsed := time.Since(begin)
+	level, msg := slog.LevelDebug, "Query"
+	switch {
+	// A missing record is an expected outcome, reported to the caller
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		level, msg = slog.LevelError, "Query failed"
+	case elapsed > slowQueryThreshold:
+		level, msg = slog.LevelWarn, "Slow query"
+	}
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+
+	sql, rows := fc()
+	attrs := []slog.Attr{
+		slog.String("sql", sql),
+		slog.Int64("rows", rows),
+		slog.Float64("duration_ms", float64(elapsed.Microseconds())/1000),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// ParamsFilter keeps query parameters, such as password hashes and emails,
+// out of the logged SQL
+func (gormLogger) ParamsFilter(_ context.Context, sql string, _ ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
@@ -0,0 +1,134 @@
+package database
+
+import (
+	"apisecurityplatform/pkg/observability"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// Keys of the per-statement values the callbacks pass on
+const (
+	spanKey  = "platform:span"
+	startKey = "platform:start"
+)
+
+// stringLiteral matches quoted values written into raw SQL. Values passed as
+// parameters are already placeholders.
+var stringLiteral = regexp.MustCompile(`'(?:[^']|'')*'`)
+
+// instrumentation is a GORM plugin tracing each query as a child span of the
+// context it was run with, and recording query latency and pool usage
+type instrumentation struct {
+	system string
+
+	mu sync.Mutex
+	// waits and waited are the pool totals already added to the counters
+	waits  int64
+	waited time.Duration
+}
+
+func newInstrumentation(system string) *instrumentation {
+	return &instrumentation{system: system}
+}
+
+func (p *instrumentation) Name() string {
+	return "platform:instrumentation"
+}
+
+func (p *instrumentation) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("platform:before_create", p.before),
+		cb.Create().After("gorm:create").Register("platform:after_create", p.after),
+		cb.Query().Before("gorm:query").Register("platform:before_query", p.before),
+		cb.Query().After("gorm:query").Register("platform:after_query", p.after),
+		cb.Update().Before("gorm:update").Register("platform:before_update", p.before),
+		cb.Update().After("gorm:update").Register("platform:after_update", p.after),
+		cb.Delete().Before("gorm:delete").Register("platform:before_delete", p.before),
+		cb.Delete().After("gorm:delete").Register("platform:after_delete", p.after),
+		cb.Row().Before("gorm:row").Register("platform:before_row", p.before),
+		cb.Row().After("gorm:row").Register("platform:after_row", p.after),
+		cb.Raw().Before("gorm:raw").Register("platform:before_raw", p.before),
+		cb.Raw().After("gorm:raw").Register("platform:after_raw", p.after),
+	)
+}
+
+func (p *instrumentation) before(db *gorm.DB) {
+	_, span := observability.GetTracer().Start(db.Statement.Context, "db.query",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemKey.String(p.system)),
+	)
+	db.InstanceSet(spanKey, span)
+	db.InstanceSet(startKey, time.Now())
+}
+
+func (p *instrumentation) after(db *gorm.DB) {
+	value, ok := db.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+	start, _ := db.InstanceGet(startKey)
+	elapsed := time.Since(start.(time.Time))
+
+	statement := stringLiteral.ReplaceAllString(db.Statement.SQL.String(), "?")
+	operation := strings.ToUpper(strings.SplitN(strings.TrimSpace(statement), " ", 2)[0])
+	table := db.Statement.Table
+
+	name := operation
+	if table != "" {
+		name += " " + table
+	}
+	span.SetName(name)
+	span.SetAttributes(
+		semconv.DBStatement(statement),
+		semconv.DBOperation(operation),
+		semconv.DBSQLTable(table),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+
+	result := "success"
+	// A missing record is an expected outcome, not a failed query
+	if err := db.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		result = "error"
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+	}
+	span.End()
+
+	observability.ObserveWithTrace(observability.DatabaseQueryDuration.WithLabelValues(operation, table, result), elapsed.Seconds(), span.SpanContext())
+	p.recordPool(db)
+}
+
+// recordPool updates the pool metrics from the connection pool statistics
+func (p *instrumentation) recordPool(db *gorm.DB) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return
+	}
+	stats := sqlDB.Stats()
+	observability.DatabasePoolConnections.WithLabelValues("open").Set(float64(stats.OpenConnections))
+	observability.DatabasePoolConnections.WithLabelValues("in_use").Set(float64(stats.InUse))
+	observability.DatabasePoolConnections.WithLabelValues("idle").Set(float64(stats.Idle))
+	observability.DatabasePoolMaxOpenConnections.Set(float64(stats.MaxOpenConnections))
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if stats.WaitCount > p.waits {
+		observability.DatabasePoolWaits.Add(float64(stats.WaitCount - p.waits))
+		p.waits = stats.WaitCount
+	}
+	if stats.WaitDuration > p.waited {
+		observability.DatabasePoolWaitDuration.Add((stats.WaitDuration - p.waited).Seconds())
+		p.waited = stats.WaitDuration
+	}
+}
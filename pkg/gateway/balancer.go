@@ -0,0 +1,70 @@
+package gateway
+
+import "apisecurityplatform/pkg/config"
+
+// balancer chooses among the available upstreams of a route. Implementations
+// are called with the pool's mutex held and need no locking of their own.
+type balancer interface {
+	pick(candidates []*Upstream) *Upstream
+}
+
+func newBalancer(strategy string) balancer {
+	switch strategy {
+	case config.LoadBalancingLeastConnections:
+		return &leastConnections{}
+	case config.LoadBalancingWeighted:
+		return &weighted{current: make(map[*Upstream]int)}
+	default:
+		return &roundRobin{}
+	}
+}
+
+type roundRobin struct {
+	next int
+}
+
+func (b *roundRobin) pick(candidates []*Upstream) *Upstream {
+	u := candidates[b.next%len(candidates)]
+	b.next++
+	return u
+}
+
+// leastConnections picks the upstream with the fewest in-flight requests,
+// rotating the starting point so ties are spread evenly
+type leastConnections struct {
+	next int
+}
+
+func (b *leastConnections) pick(candidates []*Upstream) *Upstream {
+	start := b.next % len(candidates)
+	b.next++
+
+	best := candidates[start]
+	for i := 1; i < len(candidates); i++ {
+		u := candidates[(start+i)%len(candidates)]
+		if u.active < best.active {
+			best = u
+		}
+	}
+	return best
+}
+
+// weighted is the smooth weighted round-robin used by nginx, which
+// interleaves upstreams instead of sending bursts to the heaviest one
+type weighted struct {
+	current map[*Upstream]int
+}
+
+func (b *weighted) pick(candidates []*Upstream) *Upstream {
+	var best *Upstream
+	total := 0
+	for _, u := range candidates {
+		b.current[u] += u.Weight
+		total += u.Weight
+		if best == nil || b.current[u] > b.current[best] {
+			best = u
+		}
+	}
+	b.current[best] -= total
+	return best
+}
@@ -0,0 +1,95 @@
+package gateway
+
+import (
+	"apisecurityplatform/pkg/config"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func testUpstreams(weights ...int) []*Upstream {
+	upstreams := make([]*Upstream, len(weights))
+	for i, weight := range weights {
+		upstreams[i] = &Upstream{Weight: weight}
+	}
+	return upstreams
+}
+
+// picks returns the indexes of n successive picks among upstreams
+func picks(b balancer, upstreams []*Upstream, n int) string {
+	var order []string
+	for i := 0; i < n; i++ {
+		picked := b.pick(upstreams)
+		for j, u := range upstreams {
+			if u == picked {
+				order = append(order, fmt.Sprint(j))
+			}
+		}
+	}
+	return strings.Join(order, "")
+}
+
+func TestRoundRobin(t *testing.T) {
+	upstreams := testUpstreams(1, 1, 1)
+	if got := picks(newBalancer(config.LoadBalancingRoundRobin), upstreams, 7); got != "0120120" {
+		t.Errorf("pick order = %s, want 0120120", got)
+	}
+}
+
+func TestLeastConnections(t *testing.T) {
+	upstreams := testUpstreams(1, 1, 1)
+	b := newBalancer(config.LoadBalancingLeastConnections)
+
+	upstreams[0].active = 2
+	upstreams[1].active = 0
+	upstreams[2].active = 1
+	for i := 0; i < 3; i++ {
+		if got := b.pick(upstreams); got != upstreams[1] {
+			t.Fatalf("pick %d chose an upstream with %d active requests, want the idle one", i, got.active)
+		}
+	}
+
+	// Ties are spread rather than always going to the first upstream
+	for _, u := range upstreams {
+		u.active = 0
+	}
+	if got := picks(b, upstreams, 3); got != "012" && got != "120" && got != "201" {
+		t.Errorf("pick order on ties = %s, want a rotation", got)
+	}
+}
+
+func TestWeighted(t *testing.T) {
+	tests := []struct {
+		weights []int
+		want    string
+	}{
+		// nginx's smooth weighted round-robin interleaves instead of bursting
+		{[]int{5, 1, 1}, "0010200"},
+		{[]int{2, 1}, "010010"},
+		{[]int{1, 1, 1}, "012012"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.weights), func(t *testing.T) {
+			b := newBalancer(config.LoadBalancingWeighted)
+			if got := picks(b, testUpstreams(tt.weights...), len(tt.want)); got != tt.want {
+				t.Errorf("pick order = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWeightedDistribution(t *testing.T) {
+	weights := []int{3, 2, 5}
+	upstreams := testUpstreams(weights...)
+	b := newBalancer(config.LoadBalancingWeighted)
+
+	counts := make(map[*Upstream]int)
+	for i := 0; i < 1000; i++ {
+		counts[b.pick(upstreams)]++
+	}
+	for i, u := range upstreams {
+		if want := weights[i] * 100; counts[u] != want {
+			t.Errorf("upstream %d with weight %d got %d of 1000 picks, want %d", i, weights[i], counts[u], want)
+		}
+	}
+}
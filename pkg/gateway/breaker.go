@@ -0,0 +1,131 @@
+package gateway
+
+import (
+	"apisecurityplatform/pkg/config"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type breakerState int
+
+// Values exported by the gateway_circuit_breaker_state gauge
+const (
+	breakerClosed breakerState = iota
+	breakerHalfOpen
+	breakerOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerHalfOpen:
+		return "half_open"
+	case breakerOpen:
+		return "open"
+	default:
+		return "closed"
+	}
+}
+
+// circuitBreaker stops traffic to an upstream whose failure ratio over a
+// window crosses the threshold. After OpenTimeout a single probe request is
+// let through; its result closes the breaker or opens it again.
+// It is guarded by the owning pool's mutex.
+type circuitBreaker struct {
+	config config.CircuitBreakerConfig
+	gauge  prometheus.Gauge
+
+	state       breakerState
+	openedAt    time.Time
+	windowStart time.Time
+	successes   int
+	failures    int
+	probing     bool
+}
+
+func (b *circuitBreaker) enabled() bool {
+	return b.config.FailureRatio > 0
+}
+
+// ready reports whether the breaker would admit a request now
+func (b *circuitBreaker) ready(now time.Time) bool {
+	switch {
+	case !b.enabled() || b.state == breakerClosed:
+		return true
+	case b.state == breakerOpen:
+		return now.Sub(b.openedAt) >= b.config.OpenTimeout
+	default:
+		return !b.probing
+	}
+}
+
+// acquire admits a request, moving an open breaker to half-open once its
+// timeout has passed
+func (b *circuitBreaker) acquire(now time.Time) bool {
+	if !b.ready(now) {
+		return false
+	}
+	if b.enabled() && b.state != breakerClosed {
+		b.setState(breakerHalfOpen)
+		b.probing = true
+	}
+	return true
+}
+
+func (b *circuitBreaker) record(now time.Time, success bool) {
+	if !b.enabled() {
+		return
+	}
+
+	if b.state == breakerHalfOpen {
+		b.probing = false
+		if success {
+			b.reset(now)
+			b.setState(breakerClosed)
+		} else {
+			b.open(now)
+		}
+		return
+	}
+	if b.state == breakerOpen {
+		// A request admitted before the breaker opened
+		return
+	}
+
+	if now.Sub(b.windowStart) >= b.config.Window {
+		b.reset(now)
+	}
+	if success {
+		b.successes++
+	} else {
+		b.failures++
+	}
+	total := b.successes + b.failures
+	if total >= b.config.MinRequests && float64(b.failures)/float64(total) >= b.config.FailureRatio {
+		b.open(now)
+	}
+}
+
+// abandon ends a half-open probe that produced no result, such as one
+// whose client disconnected, so that the next request probes instead
+func (b *circuitBreaker) abandon() {
+	if b.state == breakerHalfOpen {
+		b.probing = false
+	}
+}
+
+func (b *circuitBreaker) open(now time.Time) {
+	b.openedAt = now
+	b.setState(breakerOpen)
+}
+
+func (b *circuitBreaker) reset(now time.Time) {
+	b.windowStart = now
+	b.successes = 0
+	b.failures = 0
+}
+
+func (b *circuitBreaker) setState(state breakerState) {
+	b.state = state
+	b.gauge.Set(float64(state))
+}
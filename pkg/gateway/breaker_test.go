@@ -0,0 +1,159 @@
+package gateway
+
+import (
+	"apisecurityplatform/pkg/config"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func newTestBreaker() *circuitBreaker {
+	return &circuitBreaker{
+		config: config.CircuitBreakerConfig{FailureRatio: 0.5, MinRequests: 4, Window: time.Minute, OpenTimeout: 10 * time.Second},
+		gauge:  prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_breaker_state"}),
+	}
+}
+
+// openBreaker fails enough requests at now to open b
+func openBreaker(t *testing.T, b *circuitBreaker, now time.Time) {
+	t.Helper()
+	b.reset(now)
+	for i := 0; i < b.config.MinRequests; i++ {
+		if !b.acquire(now) {
+			t.Fatal("closed breaker refused a request")
+		}
+		b.record(now, false)
+	}
+	if b.state != breakerOpen {
+		t.Fatalf("state = %s after %d failures, want open", b.state, b.config.MinRequests)
+	}
+}
+
+func TestCircuitBreakerOpensOnFailureRatio(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := newTestBreaker()
+	b.reset(now)
+
+	// Three failures out of four stay closed until MinRequests is reached
+	for _, success := range []bool{false, true, false} {
+		b.acquire(now)
+		b.record(now, success)
+	}
+	if b.state != breakerClosed {
+		t.Fatalf("state = %s below min_requests, want closed", b.state)
+	}
+	b.acquire(now)
+	b.record(now, false)
+	if b.state != breakerOpen {
+		t.Fatalf("state = %s at a 75%% failure ratio, want open", b.state)
+	}
+	if b.ready(now.Add(9 * time.Second)) {
+		t.Error("open breaker admitted a request before open_timeout")
+	}
+}
+
+func TestCircuitBreakerWindowResets(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := newTestBreaker()
+	b.reset(now)
+	for i := 0; i < 3; i++ {
+		b.record(now, false)
+	}
+	// The fourth failure falls in a new window
+	b.record(now.Add(time.Minute), false)
+	if b.state != breakerClosed {
+		t.Errorf("state = %s, want closed: failures of a past window counted", b.state)
+	}
+}
+
+func TestCircuitBreakerHalfOpenProbe(t *testing.T) {
+	tests := []struct {
+		name    string
+		success bool
+		want    breakerState
+	}{
+		{"probe succeeds", true, breakerClosed},
+		{"probe fails", false, breakerOpen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Unix(1000, 0)
+			b := newTestBreaker()
+			openBreaker(t, b, now)
+
+			probeAt := now.Add(b.config.OpenTimeout)
+			if !b.acquire(probeAt) {
+				t.Fatal("breaker refused the probe after open_timeout")
+			}
+			if b.state != breakerHalfOpen {
+				t.Fatalf("state = %s during the probe, want half_open", b.state)
+			}
+			if b.acquire(probeAt) {
+				t.Fatal("half-open breaker admitted a second concurrent request")
+			}
+
+			b.record(probeAt, tt.success)
+			if b.state != tt.want {
+				t.Errorf("state = %s, want %s", b.state, tt.want)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerAbandonedProbe(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := newTestBreaker()
+	openBreaker(t, b, now)
+
+	probeAt := now.Add(b.config.OpenTimeout)
+	if !b.acquire(probeAt) {
+		t.Fatal("breaker refused the probe after open_timeout")
+	}
+	// The probe's client disconnects, so no result is recorded
+	b.abandon()
+
+	later := probeAt.Add(time.Hour)
+	if !b.ready(later) || !b.acquire(later) {
+		t.Fatal("breaker stays shut after its probe was abandoned")
+	}
+	b.record(later, true)
+	if b.state != breakerClosed {
+		t.Errorf("state = %s after a successful probe, want closed", b.state)
+	}
+}
+
+func TestCircuitBreakerAbandonWhileClosed(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := newTestBreaker()
+	b.reset(now)
+	b.acquire(now)
+	b.abandon()
+	if b.state != breakerClosed || !b.ready(now) {
+		t.Errorf("state = %s, want closed and ready", b.state)
+	}
+}
+
+func TestPoolAbandonedProbe(t *testing.T) {
+	p := newTestPool(t, config.RouteConfig{
+		CircuitBreaker: config.CircuitBreakerConfig{FailureRatio: 0.5, MinRequests: 1, Window: time.Minute, OpenTimeout: time.Millisecond},
+	}, 1)
+	u := p.pick(nil)
+	p.done(u, false)
+	p.release(u)
+	time.Sleep(2 * time.Millisecond)
+
+	probe := p.pick(nil)
+	if probe == nil {
+		t.Fatal("no probe after open_timeout")
+	}
+	if p.pick(nil) != nil {
+		t.Fatal("a second request got through the half-open breaker")
+	}
+	p.abandoned(probe)
+	p.release(probe)
+
+	if p.pick(nil) == nil {
+		t.Error("upstream unusable after its probe was abandoned")
+	}
+}
@@ -0,0 +1,206 @@
+package gateway
+
+import (
+	"apisecurityplatform/pkg/cache"
+	"apisecurityplatform/pkg/config"
+	"apisecurityplatform/pkg/logging"
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+var logger = logging.For("gateway")
+
+// Authenticators are the middlewares a route can require. Each either aborts
+// the request or records the caller's identity on the gin context.
+type Authenticators struct {
+	JWT    gin.HandlerFunc
+	APIKey gin.HandlerFunc
+	// Revoked is polled while a WebSocket or event stream is open, which is
+	// closed once it reports the request's credential revoked. Optional.
+	Revoked RevocationCheck
+}
+
+// Gateway proxies requests matching a route's path prefix to its upstreams
+// once the route's authentication has succeeded
+type Gateway struct {
+	auth  Authenticators
+	cache *cache.Cache
+	table atomic.Pointer[[]*Route]
+
+	// mu serialises Update and Start
+	mu sync.Mutex
+	// ctx is set by Start; health checks of routes added later run under it
+	ctx context.Context
+}
+
+// New builds a gateway from validated route definitions. Routes with caching
+// enabled store responses in responseCache.
+func New(routes []config.RouteConfig, auth Authenticators, responseCache *cache.Cache) (*Gateway, error) {
+	g := &Gateway{auth: auth, cache: responseCache}
+	g.table.Store(&[]*Route{})
+	if err := g.Update(routes); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// Update replaces the routing table. Requests see either the old or the new
+// table, never a mix. Routes whose definition is unchanged keep their
+// upstream state, such as health and circuit breakers.
+func (g *Gateway) Update(routes []config.RouteConfig) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	previous := make(map[string]*Route)
+	for _, route := range *g.table.Load() {
+		previous[route.Name] = route
+	}
+
+	next := make([]*Route, 0, len(routes))
+	var added []*Route
+	for _, rc := range routes {
+		if route, ok := previous[rc.Name]; ok && reflect.DeepEqual(route.config, rc) {
+			next = append(next, route)
+			delete(previous, rc.Name)
+			continue
+		}
+		route, err := newRoute(rc, g.cache, g.auth.Revoked)
+		if err != nil {
+			return fmt.Errorf("route %q: %w", rc.Name, err)
+		}
+		next = append(next, route)
+		added = append(added, route)
+	}
+	// Longest prefix first, so the most specific route wins
+	sort.SliceStable(next, func(i, j int) bool {
+		return len(next[i].PathPrefix) > len(next[j].PathPrefix)
+	})
+
+	g.table.Store(&next)
+
+	// Routes left in previous were removed or replaced. Their cached
+	// responses may come from upstreams or settings no longer in effect.
+	for _, route := range previous {
+		route.stop(next)
+		if g.cache != nil && route.config.Cache.Enabled {
+			if _, err := g.cache.Purge(context.Background(), route.Name, ""); err != nil {
+				logger.Error("Failed to purge cache of gateway route", "route", route.Name, "error", err)
+			}
+		}
+	}
+	if g.ctx != nil {
+		for _, route := range added {
+			route.start(g.ctx)
+		}
+	}
+	return nil
+}
+
+// Routes returns the routes in effect, most specific first
+func (g *Gateway) Routes() []*Route {
+	return *g.table.Load()
+}
+
+// CheckUpstreams returns an error naming the routes none of whose upstreams
+// can take a request: all unhealthy, ejected or behind an open breaker
+func (g *Gateway) CheckUpstreams(context.Context) error {
+	var unavailable []string
+	for _, route := range *g.table.Load() {
+		if !route.pool.hasAvailable() {
+			unavailable = append(unavailable, route.Name)
+		}
+	}
+	if len(unavailable) > 0 {
+		return fmt.Errorf("no available upstream for routes: %s", strings.Join(unavailable, ", "))
+	}
+	return nil
+}
+
+// Start runs the active health checks until ctx is cancelled
+func (g *Gateway) Start(ctx context.Context) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.ctx = ctx
+	for _, route := range *g.table.Load() {
+		route.start(ctx)
+	}
+}
+
+// Match returns the route whose prefix covers path
+func (g *Gateway) Match(path string) (*Route, bool) {
+	for _, route := range *g.table.Load() {
+		if path == route.PathPrefix || strings.HasPrefix(path, route.PathPrefix+"/") {
+			return route, true
+		}
+	}
+	return nil, false
+}
+
+// routeKey is the gin context key of the matched *Route
+const routeKey = "gateway_route_entry"
+
+// Handlers serve requests no other route matched. They are meant for the gin
+// NoRoute hook, so the platform's own endpoints always take precedence. Each
+// step is its own handler so the auth, validation and transform middlewares
+// wrap the proxy through c.Next, as they would on any other gin route.
+func (g *Gateway) Handlers() gin.HandlersChain {
+	return gin.HandlersChain{g.match, g.authenticate, g.validate, g.transform, g.proxy}
+}
+
+func (g *Gateway) match(c *gin.Context) {
+	route, ok := g.Match(c.Request.URL.Path)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Not found"})
+		return
+	}
+	c.Set("gateway_route", route.Name)
+	// The route template of the server span, as gin has none for proxied paths
+	c.Set("http_route", route.PathPrefix+"/*")
+	c.Set(routeKey, route)
+}
+
+// authenticate runs the route's auth middleware, which either aborts the
+// request or continues the chain. Stream requests may carry their
+// credential in the query string or a WebSocket subprotocol.
+func (g *Gateway) authenticate(c *gin.Context) {
+	route := c.MustGet(routeKey).(*Route)
+	if route.Auth != config.RouteAuthNone {
+		liftCredentials(c.Request)
+	}
+	switch route.Auth {
+	case config.RouteAuthJWT:
+		g.auth.JWT(c)
+	case config.RouteAuthAPIKey:
+		g.auth.APIKey(c)
+	case config.RouteAuthAny:
+		if c.GetHeader("Authorization") != "" {
+			g.auth.JWT(c)
+		} else {
+			g.auth.APIKey(c)
+		}
+	}
+}
+
+// validate checks the request as the client sent it, before transformation
+func (g *Gateway) validate(c *gin.Context) {
+	if validate := c.MustGet(routeKey).(*Route).validate; validate != nil {
+		validate(c)
+	}
+}
+
+func (g *Gateway) transform(c *gin.Context) {
+	c.MustGet(routeKey).(*Route).transform(c)
+}
+
+func (g *Gateway) proxy(c *gin.Context) {
+	c.MustGet(routeKey).(*Route).handler.ServeHTTP(c.Writer, withIdentity(c))
+}
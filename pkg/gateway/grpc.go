@@ -0,0 +1,174 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/http2"
+	"google.golang.org/grpc/codes"
+)
+
+// grpcTransport reaches gRPC upstreams over HTTP/2: cleartext (h2c) for http
+// upstreams, negotiated through TLS for https ones
+type grpcTransport struct{}
+
+// h2cTransport is shared by every gRPC route, so connections to an upstream
+// outlive changes to the routes using it
+var h2cTransport = &http2.Transport{
+	AllowHTTP: true,
+	DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, network, addr)
+	},
+}
+
+func (grpcTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme == "http" {
+		return h2cTransport.RoundTrip(req)
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// IsGRPC reports whether r is a gRPC call
+func IsGRPC(r *http.Request) bool {
+	return r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc")
+}
+
+// GRPCErrors turns the JSON error responses of the middlewares and the
+// gateway into gRPC statuses for gRPC calls, which clients can only read
+// from the grpc-status and grpc-message headers. It must run before the
+// middlewares whose errors it translates.
+func GRPCErrors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !IsGRPC(c.Request) {
+			c.Next()
+			return
+		}
+		w := &grpcErrorWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+		w.finish()
+	}
+}
+
+// grpcErrorWriter holds back a response with an HTTP error status. Upstream
+// gRPC responses always have status 200 and pass straight through.
+type grpcErrorWriter struct {
+	gin.ResponseWriter
+	// status is the held back error status, 0 while there is none
+	status int
+	body   bytes.Buffer
+}
+
+func (w *grpcErrorWriter) WriteHeader(code int) {
+	if code == http.StatusOK || w.status != 0 || w.ResponseWriter.Written() {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.status = code
+}
+
+func (w *grpcErrorWriter) WriteHeaderNow() {
+	if w.status == 0 {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *grpcErrorWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		return w.ResponseWriter.Write(data)
+	}
+	// Only the error message is kept, so a small buffer will do
+	if w.body.Len() < 4<<10 {
+		w.body.Write(data)
+	}
+	return len(data), nil
+}
+
+func (w *grpcErrorWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *grpcErrorWriter) Status() int {
+	if w.status != 0 {
+		return w.status
+	}
+	return w.ResponseWriter.Status()
+}
+
+func (w *grpcErrorWriter) Written() bool {
+	return w.status != 0 || w.ResponseWriter.Written()
+}
+
+func (w *grpcErrorWriter) Flush() {
+	if w.status == 0 {
+		w.ResponseWriter.Flush()
+	}
+}
+
+// finish sends a held back error as a trailers-only gRPC response
+func (w *grpcErrorWriter) finish() {
+	if w.status == 0 {
+		return
+	}
+	var body struct {
+		Error string `json:"error"`
+	}
+	message := http.StatusText(w.status)
+	if json.Unmarshal(w.body.Bytes(), &body) == nil && body.Error != "" {
+		message = body.Error
+	}
+
+	header := w.ResponseWriter.Header()
+	header.Del("Content-Length")
+	header.Set("Content-Type", "application/grpc")
+	header.Set("Grpc-Status", strconv.Itoa(int(grpcCode(w.status))))
+	header.Set("Grpc-Message", encodeGRPCMessage(message))
+	w.ResponseWriter.WriteHeader(http.StatusOK)
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// grpcCode maps the HTTP statuses the platform answers with to gRPC codes
+func grpcCode(status int) codes.Code {
+	switch status {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.Unimplemented
+	case http.StatusRequestEntityTooLarge, http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	}
+	return codes.Unknown
+}
+
+// encodeGRPCMessage percent-encodes a grpc-message value, as the gRPC over
+// HTTP/2 protocol requires
+func encodeGRPCMessage(message string) string {
+	var b strings.Builder
+	for i := 0; i < len(message); i++ {
+		c := message[i]
+		if c >= ' ' && c <= '~' && c != '%' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
@@ -0,0 +1,63 @@
+package gateway
+
+import (
+	"apisecurityplatform/pkg/config"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// healthChecker actively probes the upstreams of a route
+type healthChecker struct {
+	config config.HealthCheckConfig
+	pool   *pool
+	client *http.Client
+}
+
+// run probes every upstream each interval until ctx is cancelled
+func (h *healthChecker) run(ctx context.Context) {
+	ticker := time.NewTicker(h.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		h.probeAll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *healthChecker) probeAll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, u := range h.pool.upstreams {
+		wg.Add(1)
+		go func(u *Upstream) {
+			defer wg.Done()
+			ok := h.probe(ctx, u)
+			if ctx.Err() == nil {
+				h.pool.probed(u, ok, h.config)
+			}
+		}(u)
+	}
+	wg.Wait()
+}
+
+// probe reports whether the upstream answered the health path with a 2xx
+func (h *healthChecker) probe(ctx context.Context, u *Upstream) bool {
+	ctx, cancel := context.WithTimeout(ctx, h.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.URL.JoinPath(h.config.Path).String(), nil)
+	if err != nil {
+		return false
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode >= 200 && resp.StatusCode < 300
+}
@@ -0,0 +1,318 @@
+package gateway
+
+import (
+	"apisecurityplatform/pkg/config"
+	"apisecurityplatform/pkg/models"
+	"apisecurityplatform/pkg/repository"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Route sources
+const (
+	// SourceConfig marks a route from the config file, which the API cannot change
+	SourceConfig = "config"
+	// SourceRegistry marks a route stored in the database through the admin API
+	SourceRegistry = "registry"
+)
+
+// ErrStaticRoute is returned when a change targets a route from the config file
+var ErrStaticRoute = errors.New("route is defined in the config file")
+
+// ValidationError reports a route definition, or a routing table it would
+// produce, that failed validation
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// RouteEntry is a route in the routing table
+type RouteEntry struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+	// Version and UpdatedAt are only set for registry routes
+	Version    int                `json:"version,omitempty"`
+	UpdatedAt  *time.Time         `json:"updated_at,omitempty"`
+	Definition config.RouteConfig `json:"definition"`
+}
+
+// Registry combines the routes of the config file with those stored in the
+// database, and installs the result in the gateway whenever either changes
+type Registry struct {
+	routes  repository.RouteRepository
+	gateway *Gateway
+
+	// mu serialises changes, so each is validated against the table it replaces
+	mu      sync.Mutex
+	static  []config.RouteConfig
+	entries atomic.Pointer[[]RouteEntry]
+}
+
+// NewRegistry creates a registry serving static and stored routes through gw
+func NewRegistry(routes repository.RouteRepository, gw *Gateway, static []config.RouteConfig) *Registry {
+	r := &Registry{routes: routes, gateway: gw, static: static}
+	r.entries.Store(&[]RouteEntry{})
+	return r
+}
+
+// Load reads the stored routes and installs the combined routing table
+func (r *Registry) Load(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reload(ctx)
+}
+
+// Watch re-reads the stored routes every interval until ctx is cancelled, so
+// changes made through other instances take effect here too
+func (r *Registry) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := r.Load(ctx); err != nil {
+					logger.Error("Failed to refresh gateway routes", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// CheckStatic reports whether static would be accepted by SetStatic, without
+// putting it into effect, so a config reload can be rejected as a whole
+func (r *Registry) CheckStatic(ctx context.Context, static []config.RouteConfig) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, err := r.routes.List(ctx)
+	if err != nil {
+		return err
+	}
+	if _, _, err := r.build(static, stored); err != nil {
+		return err
+	}
+	for _, route := range static {
+		if _, _, err := routeMiddlewares(route); err != nil {
+			return &ValidationError{Err: fmt.Errorf("route %q: %w", route.Name, err)}
+		}
+	}
+	return nil
+}
+
+// SetStatic replaces the routes from the config file, after a config reload
+func (r *Registry) SetStatic(ctx context.Context, static []config.RouteConfig) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	previous := r.static
+	r.static = static
+	if err := r.reload(ctx); err != nil {
+		r.static = previous
+		return err
+	}
+	return nil
+}
+
+// Routes returns every route in effect
+func (r *Registry) Routes() []RouteEntry {
+	return *r.entries.Load()
+}
+
+// Route returns the route in effect with the given name
+func (r *Registry) Route(name string) (RouteEntry, bool) {
+	for _, entry := range r.Routes() {
+		if entry.Name == name {
+			return entry, true
+		}
+	}
+	return RouteEntry{}, false
+}
+
+// Versions returns the change history of a stored route
+func (r *Registry) Versions(ctx context.Context, name string) ([]models.RouteVersion, error) {
+	return r.routes.Versions(ctx, name)
+}
+
+// Create stores a new route and puts it into effect
+func (r *Registry) Create(ctx context.Context, route config.RouteConfig, changedBy uint) (RouteEntry, error) {
+	return r.change(ctx, route, func(stored *models.Route) error {
+		return r.routes.Create(ctx, stored, changedBy)
+	})
+}
+
+// Update replaces the definition of a stored route and puts it into effect
+func (r *Registry) Update(ctx context.Context, route config.RouteConfig, changedBy uint) (RouteEntry, error) {
+	return r.change(ctx, route, func(stored *models.Route) error {
+		return r.routes.Update(ctx, stored, models.RouteActionUpdate, changedBy)
+	})
+}
+
+// Rollback restores the definition a route had at an earlier version, as a
+// new version. A deleted route is recreated.
+func (r *Registry) Rollback(ctx context.Context, name string, version int, changedBy uint) (RouteEntry, error) {
+	previous, err := r.routes.FindVersion(ctx, name, version)
+	if err != nil {
+		return RouteEntry{}, err
+	}
+	route, err := decodeRoute(name, previous.Definition)
+	if err != nil {
+		return RouteEntry{}, err
+	}
+
+	return r.change(ctx, route, func(stored *models.Route) error {
+		err := r.routes.Update(ctx, stored, models.RouteActionRollback, changedBy)
+		if errors.Is(err, repository.ErrNotFound) {
+			return r.routes.Create(ctx, stored, changedBy)
+		}
+		return err
+	})
+}
+
+// Delete removes a stored route
+func (r *Registry) Delete(ctx context.Context, name string, changedBy uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.isStatic(name) {
+		return ErrStaticRoute
+	}
+	if err := r.routes.Delete(ctx, name, changedBy); err != nil {
+		return err
+	}
+	return r.reload(ctx)
+}
+
+// change validates the table route would produce, persists route through
+// save and installs the new table
+func (r *Registry) change(ctx context.Context, route config.RouteConfig, save func(*models.Route) error) (RouteEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.isStatic(route.Name) {
+		return RouteEntry{}, ErrStaticRoute
+	}
+	definition, err := json.Marshal(route)
+	if err != nil {
+		return RouteEntry{}, err
+	}
+
+	stored, err := r.routes.List(ctx)
+	if err != nil {
+		return RouteEntry{}, err
+	}
+	candidate := models.Route{Name: route.Name, Definition: string(definition)}
+	replaced := false
+	for i := range stored {
+		if stored[i].Name == route.Name {
+			stored[i] = candidate
+			replaced = true
+		}
+	}
+	if !replaced {
+		stored = append(stored, candidate)
+	}
+	if _, _, err := r.build(r.static, stored); err != nil {
+		return RouteEntry{}, err
+	}
+	// Catch what only shows when the route is built, before it is stored
+	built := route
+	built.ApplyDefaults()
+	if _, _, err := routeMiddlewares(built); err != nil {
+		return RouteEntry{}, &ValidationError{Err: fmt.Errorf("route %q: %w", route.Name, err)}
+	}
+
+	if err := save(&candidate); err != nil {
+		return RouteEntry{}, err
+	}
+	if err := r.reload(ctx); err != nil {
+		return RouteEntry{}, err
+	}
+	entry, _ := r.Route(route.Name)
+	return entry, nil
+}
+
+// reload installs the routing table built from the static and stored
+// routes; the caller holds mu
+func (r *Registry) reload(ctx context.Context) error {
+	stored, err := r.routes.List(ctx)
+	if err != nil {
+		return err
+	}
+	routes, entries, err := r.build(r.static, stored)
+	if err != nil {
+		return err
+	}
+	if err := r.gateway.Update(routes); err != nil {
+		return err
+	}
+	r.entries.Store(&entries)
+	return nil
+}
+
+// build combines the static and stored routes and validates the result
+func (r *Registry) build(static []config.RouteConfig, stored []models.Route) ([]config.RouteConfig, []RouteEntry, error) {
+	routes := make([]config.RouteConfig, 0, len(static)+len(stored))
+	entries := make([]RouteEntry, 0, cap(routes))
+
+	for _, route := range static {
+		routes = append(routes, route)
+		entries = append(entries, RouteEntry{Name: route.Name, Source: SourceConfig, Definition: route})
+	}
+	for _, s := range stored {
+		route, err := decodeRoute(s.Name, s.Definition)
+		if err != nil {
+			return nil, nil, err
+		}
+		routes = append(routes, route)
+
+		entry := RouteEntry{Name: s.Name, Source: SourceRegistry, Version: s.Version, Definition: route}
+		if !s.UpdatedAt.IsZero() {
+			updatedAt := s.UpdatedAt
+			entry.UpdatedAt = &updatedAt
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := config.ValidateRoutes(routes); err != nil {
+		return nil, nil, &ValidationError{Err: err}
+	}
+	return routes, entries, nil
+}
+
+func (r *Registry) isStatic(name string) bool {
+	for _, route := range r.static {
+		if route.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeRoute parses a stored definition and fills in the defaults
+func decodeRoute(name, definition string) (config.RouteConfig, error) {
+	var route config.RouteConfig
+	if err := json.Unmarshal([]byte(definition), &route); err != nil {
+		return route, &ValidationError{Err: fmt.Errorf("route %q: %w", name, err)}
+	}
+	route.Name = name
+	route.ApplyDefaults()
+	return route, nil
+}
@@ -0,0 +1,74 @@
+package gateway
+
+import (
+	"apisecurityplatform/pkg/config"
+	"apisecurityplatform/pkg/models"
+	"apisecurityplatform/pkg/repository"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// storedRoutes is a RouteRepository listing fixed routes
+type storedRoutes struct {
+	repository.RouteRepository
+	routes []models.Route
+	err    error
+}
+
+func (s *storedRoutes) List(context.Context) ([]models.Route, error) {
+	return s.routes, s.err
+}
+
+func testRoute(name, prefix string) config.RouteConfig {
+	route := config.RouteConfig{
+		Name:       name,
+		PathPrefix: prefix,
+		Upstreams:  []config.UpstreamConfig{{URL: "http://" + name + ".test"}},
+		Auth:       config.RouteAuthNone,
+	}
+	route.ApplyDefaults()
+	return route
+}
+
+func TestCheckStatic(t *testing.T) {
+	definition, _ := json.Marshal(testRoute("orders", "/orders"))
+	repo := &storedRoutes{routes: []models.Route{{Name: "orders", Version: 1, Definition: string(definition)}}}
+	gw, err := New(nil, Authenticators{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRegistry(repo, gw, []config.RouteConfig{testRoute("users", "/users")})
+	if err := r.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		static  []config.RouteConfig
+		invalid bool
+	}{
+		{"new route", []config.RouteConfig{testRoute("users", "/users"), testRoute("billing", "/billing")}, false},
+		{"name of a stored route", []config.RouteConfig{testRoute("orders", "/v2/orders")}, true},
+		{"path prefix of a stored route", []config.RouteConfig{testRoute("legacy-orders", "/orders/")}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := r.CheckStatic(context.Background(), tt.static)
+			var validation *ValidationError
+			if tt.invalid != errors.As(err, &validation) {
+				t.Errorf("CheckStatic = %v, want a validation error: %v", err, tt.invalid)
+			}
+			// Checking never changes the routing table
+			if routes := r.Routes(); len(routes) != 2 || routes[0].Name != "users" || routes[1].Name != "orders" {
+				t.Errorf("routes = %+v, want users and orders", routes)
+			}
+		})
+	}
+
+	repo.err = errors.New("database unavailable")
+	if err := r.CheckStatic(context.Background(), nil); !errors.Is(err, repo.err) {
+		t.Errorf("CheckStatic = %v, want the repository error", err)
+	}
+}
@@ -0,0 +1,67 @@
+package gateway
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// retryBudgetWindow is the period over which requests and retries are counted
+const retryBudgetWindow = 10 * time.Second
+
+// retryBudget caps retries to a share of a route's traffic, so retries
+// cannot multiply the load on upstreams that are already struggling
+type retryBudget struct {
+	ratio        float64
+	minPerSecond int
+
+	mu          sync.Mutex
+	windowStart time.Time
+	requests    int
+	retries     int
+}
+
+func (b *retryBudget) request(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.roll(now)
+	b.requests++
+}
+
+// allow reserves a retry if the budget has room for one
+func (b *retryBudget) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.roll(now)
+
+	limit := int(b.ratio*float64(b.requests)) + b.minPerSecond*int(retryBudgetWindow/time.Second)
+	if b.retries >= limit {
+		return false
+	}
+	b.retries++
+	return true
+}
+
+func (b *retryBudget) roll(now time.Time) {
+	if now.Sub(b.windowStart) >= retryBudgetWindow {
+		b.windowStart = now
+		b.requests = 0
+		b.retries = 0
+	}
+}
+
+// retryable reports whether a request can safely be sent again: it must be
+// idempotent and have no body, since the body has already been consumed
+func retryable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return req.Body == nil || req.Body == http.NoBody
+	}
+	return false
+}
+
+// retryableStatus reports whether a response indicates the upstream, rather
+// than the request, was at fault
+func retryableStatus(code int) bool {
+	return code == http.StatusBadGateway || code == http.StatusServiceUnavailable || code == http.StatusGatewayTimeout
+}
@@ -0,0 +1,85 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryBudget(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := &retryBudget{ratio: 0.2, minPerSecond: 0}
+	for i := 0; i < 10; i++ {
+		b.request(now)
+	}
+
+	// 20% of 10 requests
+	for i := 0; i < 2; i++ {
+		if !b.allow(now) {
+			t.Fatalf("retry %d refused within the budget", i+1)
+		}
+	}
+	if b.allow(now) {
+		t.Fatal("retry allowed beyond the budget")
+	}
+
+	// More traffic grows the budget
+	for i := 0; i < 5; i++ {
+		b.request(now)
+	}
+	if !b.allow(now) || b.allow(now) {
+		t.Error("budget did not grow by 20% of the new requests")
+	}
+
+	// A new window starts over
+	next := now.Add(retryBudgetWindow)
+	b.request(next)
+	if b.allow(next) {
+		t.Error("retries of the last window still available")
+	}
+}
+
+func TestRetryBudgetMinimum(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := &retryBudget{ratio: 0, minPerSecond: 1}
+	// One retry per second of the window, even without traffic
+	allowed := 0
+	for b.allow(now) {
+		allowed++
+		if allowed > 100 {
+			t.Fatal("budget never exhausted")
+		}
+	}
+	if want := int(retryBudgetWindow / time.Second); allowed != want {
+		t.Errorf("%d retries allowed, want %d", allowed, want)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		method string
+		body   string
+		want   bool
+	}{
+		{http.MethodGet, "", true},
+		{http.MethodHead, "", true},
+		{http.MethodPut, "", true},
+		{http.MethodDelete, "", true},
+		{http.MethodPut, "{}", false},
+		{http.MethodPost, "", false},
+		{http.MethodPatch, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.body, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", strings.NewReader(tt.body))
+			if tt.body == "" {
+				req.Body = http.NoBody
+			}
+			if got := retryable(req); got != tt.want {
+				t.Errorf("retryable = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package gateway
+
+import (
+	"apisecurityplatform/pkg/cache"
+	"apisecurityplatform/pkg/config"
+	"apisecurityplatform/pkg/observability"
+	"apisecurityplatform/pkg/openapi"
+	"apisecurityplatform/pkg/transform"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Identity headers set for upstreams. Any copy sent by the client is removed
+// first so callers cannot impersonate another user.
+const (
+	HeaderUserID         = "X-User-ID"
+	HeaderUserRole       = "X-User-Role"
+	HeaderAPIKeyID       = "X-API-Key-ID"
+	HeaderScopes         = "X-Scopes"
+	HeaderAuthMethod     = "X-Auth-Method"
+	HeaderClientIdentity = "X-Client-Identity"
+)
+
+var identityHeaders = []string{
+	HeaderUserID, HeaderUserRole, HeaderAPIKeyID, HeaderScopes, HeaderAuthMethod, HeaderClientIdentity,
+}
+
+// Route is a path prefix proxied to a set of upstreams
+type Route struct {
+	Name        string
+	PathPrefix  string
+	Auth        string
+	StripPrefix bool
+	Upstreams   []*Upstream
+
+	config      config.RouteConfig
+	pool        *pool
+	healthCheck *healthChecker
+	// stopHealthCheck is set while the health check runs
+	stopHealthCheck context.CancelFunc
+	// validate checks requests against the route's OpenAPI document, if any
+	validate gin.HandlerFunc
+	// transform is the middleware applying the route's transformation policy
+	transform gin.HandlerFunc
+	// handler is the proxy, behind the response cache when the route enables it
+	handler http.Handler
+	// revoked is polled by the route's open streams; nil disables the check
+	revoked RevocationCheck
+}
+
+func newRoute(rc config.RouteConfig, responseCache *cache.Cache, revoked RevocationCheck) (*Route, error) {
+	validate, transform, err := routeMiddlewares(rc)
+	if err != nil {
+		return nil, err
+	}
+	upstreams, err := newPool(rc)
+	if err != nil {
+		return nil, err
+	}
+
+	route := &Route{
+		Name:        rc.Name,
+		PathPrefix:  strings.TrimSuffix(rc.PathPrefix, "/"),
+		Auth:        rc.Auth,
+		StripPrefix: rc.StripPrefix,
+		Upstreams:   upstreams.upstreams,
+		config:      rc,
+		pool:        upstreams,
+		validate:    validate,
+		transform:   transform,
+		revoked:     revoked,
+	}
+	var base http.RoundTripper = http.DefaultTransport
+	if rc.Protocol == config.RouteProtocolGRPC {
+		base = grpcTransport{}
+	}
+	if rc.HealthCheck.Path != "" {
+		route.healthCheck = &healthChecker{
+			config: rc.HealthCheck,
+			pool:   upstreams,
+			client: &http.Client{Transport: base},
+		}
+	}
+	proxy := &httputil.ReverseProxy{
+		Rewrite: route.rewrite,
+		Transport: &transport{
+			route:    route,
+			base:     base,
+			timeout:  rc.Timeout,
+			attempts: rc.Retry.Attempts,
+			budget:   &retryBudget{ratio: rc.Retry.BudgetRatio, minPerSecond: rc.Retry.MinRetriesPerSecond},
+		},
+		ErrorHandler: route.proxyError,
+	}
+
+	route.handler = proxy
+	if rc.Cache.Enabled && responseCache != nil {
+		route.handler = responseCache.Handler(cache.Policy{
+			Route:         rc.Name,
+			DefaultTTL:    rc.Cache.DefaultTTL,
+			MaxTTL:        rc.Cache.MaxTTL,
+			KeyBy:         rc.Cache.KeyBy,
+			Tags:          rc.Cache.Tags,
+			Authenticated: rc.Auth != config.RouteAuthNone,
+		}, proxy)
+	}
+	return route, nil
+}
+
+// routeMiddlewares builds the validation and transformation middlewares of a
+// route. They can fail for a route that passed config validation, such as
+// when its OpenAPI document cannot be read.
+func routeMiddlewares(rc config.RouteConfig) (validate, transformation gin.HandlerFunc, err error) {
+	pipeline, err := transform.New(rc.Transform)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !rc.OpenAPI.Enabled {
+		return nil, pipeline.Middleware(), nil
+	}
+
+	doc, err := openapi.Load(rc.OpenAPI.Spec)
+	if err != nil {
+		return nil, nil, err
+	}
+	basePath := ""
+	if rc.StripPrefix {
+		basePath = strings.TrimSuffix(rc.PathPrefix, "/")
+	}
+	validator, err := openapi.New("route:"+rc.Name, doc, basePath, rc.OpenAPI.ValidateResponses, rc.OpenAPI.MaxBodyBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return validator.Middleware(), pipeline.Middleware(), nil
+}
+
+// start runs the route's health check, if it has one, until ctx is cancelled
+// or the route is removed
+func (r *Route) start(ctx context.Context) {
+	if r.healthCheck == nil {
+		return
+	}
+	ctx, r.stopHealthCheck = context.WithCancel(ctx)
+	go r.healthCheck.run(ctx)
+}
+
+// stop ends the health check of a route taken out of the table, and drops
+// the gauges of upstreams the new table no longer has. In-flight requests
+// still complete.
+func (r *Route) stop(table []*Route) {
+	if r.stopHealthCheck != nil {
+		r.stopHealthCheck()
+	}
+
+	kept := make(map[string]bool)
+	for _, route := range table {
+		if route.Name == r.Name {
+			for _, u := range route.Upstreams {
+				kept[u.URL.Host] = true
+			}
+		}
+	}
+	for _, u := range r.Upstreams {
+		if !kept[u.URL.Host] {
+			observability.DeleteGatewayUpstreamGauges(r.Name, u.URL.Host)
+		}
+	}
+}
+
+func (r *Route) rewrite(pr *httputil.ProxyRequest) {
+	if r.StripPrefix {
+		pr.Out.URL.Path = strings.TrimPrefix(pr.In.URL.Path, r.PathPrefix)
+		pr.Out.URL.RawPath = ""
+		if pr.Out.URL.Path == "" {
+			pr.Out.URL.Path = "/"
+		}
+	}
+	// The transport sets the upstream for each attempt
+	pr.Out.Host = ""
+	pr.SetXForwarded()
+
+	// Credentials are consumed by the gateway and not passed upstream
+	pr.Out.Header.Del("Authorization")
+	pr.Out.Header.Del("X-API-Key")
+
+	for _, header := range identityHeaders {
+		pr.Out.Header.Del(header)
+	}
+	if identity, ok := pr.In.Context().Value(identityKey{}).(http.Header); ok {
+		for header, values := range identity {
+			pr.Out.Header[header] = values
+		}
+	}
+}
+
+type identityKey struct{}
+
+// withIdentity carries the identity established by the auth middlewares to
+// the proxy, as the headers to set on the upstream request
+func withIdentity(c *gin.Context) *http.Request {
+	h := make(http.Header)
+	if userID, ok := c.Get("user_id"); ok {
+		h.Set(HeaderUserID, strconv.FormatUint(uint64(userID.(uint)), 10))
+	}
+	if role := c.GetString("role"); role != "" {
+		h.Set(HeaderUserRole, role)
+	}
+	if keyID, ok := c.Get("api_key_id"); ok {
+		h.Set(HeaderAPIKeyID, strconv.FormatUint(uint64(keyID.(uint)), 10))
+	}
+	if scopes := c.GetStringSlice("scopes"); len(scopes) > 0 {
+		h.Set(HeaderScopes, strings.Join(scopes, " "))
+	}
+	if method := c.GetString("auth_method"); method != "" {
+		h.Set(HeaderAuthMethod, method)
+	}
+	if identity := c.GetString("client_identity"); identity != "" {
+		h.Set(HeaderClientIdentity, identity)
+	}
+	ctx := context.WithValue(c.Request.Context(), identityKey{}, h)
+	credential := Credential{Method: c.GetString("auth_method"), APIKeyID: c.GetUint("api_key_id")}
+	if credential.Method == "jwt" {
+		credential.Token = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	}
+	ctx = context.WithValue(ctx, credentialKey{}, credential)
+	ctx = cache.WithIdentity(ctx, cache.Identity{
+		UserID:   h.Get(HeaderUserID),
+		APIKeyID: h.Get(HeaderAPIKeyID),
+	})
+	return c.Request.WithContext(ctx)
+}
+
+func (r *Route) proxyError(w http.ResponseWriter, req *http.Request, err error) {
+	status, message := http.StatusBadGateway, "Upstream unavailable"
+	switch {
+	case errors.Is(err, errNoUpstream):
+		status, message = http.StatusServiceUnavailable, "No healthy upstream"
+	case errors.Is(err, context.DeadlineExceeded):
+		status, message = http.StatusGatewayTimeout, "Upstream timed out"
+	}
+	logger.ErrorContext(req.Context(), "Gateway upstream error", "route", r.Name, "error", err)
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"error":%q}`, message)
+}
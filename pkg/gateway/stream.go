@@ -0,0 +1,274 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"apisecurityplatform/pkg/observability"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Stream protocols, as reported by the stream metrics
+const (
+	protocolWebSocket = "websocket"
+	protocolSSE       = "sse"
+)
+
+// Credential is what a request was authenticated with. Streams keep it so
+// they can be closed once it is revoked.
+type Credential struct {
+	// Method is the auth_method recorded by the auth middleware
+	Method string
+	// Token is the bearer token of a JWT-authenticated request
+	Token    string
+	APIKeyID uint
+}
+
+// RevocationCheck reports whether a credential has been revoked since the
+// request authenticated with it
+type RevocationCheck func(ctx context.Context, credential Credential) bool
+
+type credentialKey struct{}
+
+// Query parameters and WebSocket subprotocol prefixes that may carry a
+// credential, for browser clients that cannot set headers on WebSocket and
+// EventSource requests
+const (
+	queryAccessToken  = "access_token"
+	queryAPIKey       = "api_key"
+	subprotocolBearer = "bearer."
+	subprotocolAPIKey = "api-key."
+)
+
+func isWebSocketUpgrade(r *http.Request) bool {
+	if r.Method != http.MethodGet || !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return false
+	}
+	for _, value := range r.Header.Values("Connection") {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func acceptsEventStream(r *http.Request) bool {
+	for _, value := range r.Header.Values("Accept") {
+		if strings.Contains(value, "text/event-stream") {
+			return true
+		}
+	}
+	return false
+}
+
+func isEventStream(header http.Header) bool {
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	return mediaType == "text/event-stream"
+}
+
+// liftCredentials moves a credential passed in the query string or in a
+// WebSocket subprotocol into the header the auth middlewares read. It is
+// removed from where it was found, so it is not forwarded upstream. Only
+// stream requests may pass credentials this way.
+func liftCredentials(r *http.Request) {
+	websocket := isWebSocketUpgrade(r)
+	if !websocket && !acceptsEventStream(r) {
+		return
+	}
+
+	if websocket {
+		var kept []string
+		lifted := false
+		for _, value := range r.Header.Values("Sec-WebSocket-Protocol") {
+			for _, protocol := range strings.Split(value, ",") {
+				protocol = strings.TrimSpace(protocol)
+				switch {
+				case strings.HasPrefix(protocol, subprotocolBearer):
+					setCredential(r, "Authorization", "Bearer "+strings.TrimPrefix(protocol, subprotocolBearer))
+					lifted = true
+				case strings.HasPrefix(protocol, subprotocolAPIKey):
+					setCredential(r, "X-API-Key", strings.TrimPrefix(protocol, subprotocolAPIKey))
+					lifted = true
+				case protocol != "":
+					kept = append(kept, protocol)
+				}
+			}
+		}
+		if lifted {
+			r.Header.Del("Sec-WebSocket-Protocol")
+			if len(kept) > 0 {
+				r.Header.Set("Sec-WebSocket-Protocol", strings.Join(kept, ", "))
+			}
+		}
+	}
+
+	query := r.URL.Query()
+	if token := query.Get(queryAccessToken); token != "" {
+		setCredential(r, "Authorization", "Bearer "+token)
+	}
+	if key := query.Get(queryAPIKey); key != "" {
+		setCredential(r, "X-API-Key", key)
+	}
+	r.URL.RawQuery = removeQueryParams(r.URL.RawQuery, queryAccessToken, queryAPIKey)
+}
+
+// removeQueryParams drops the named parameters from a raw query and keeps
+// the others as written. Parameters are also split on semicolons, which
+// url.ParseQuery rejects but some upstreams accept as separators.
+func removeQueryParams(rawQuery string, names ...string) string {
+	var kept []string
+	for _, pair := range strings.Split(rawQuery, "&") {
+		var segments []string
+		for _, segment := range strings.Split(pair, ";") {
+			key, _, _ := strings.Cut(segment, "=")
+			if unescaped, err := url.QueryUnescape(key); err == nil {
+				key = unescaped
+			}
+			if !slices.Contains(names, key) {
+				segments = append(segments, segment)
+			}
+		}
+		if pair := strings.Join(segments, ";"); pair != "" {
+			kept = append(kept, pair)
+		}
+	}
+	return strings.Join(kept, "&")
+}
+
+// setCredential sets a credential header unless the client already sent one
+func setCredential(r *http.Request, header, value string) {
+	if r.Header.Get(header) == "" {
+		r.Header.Set(header, value)
+	}
+}
+
+// stream tracks an open WebSocket connection or event stream for the
+// metrics, and watches its credential
+type stream struct {
+	route           string
+	protocol        string
+	upstreamBytes   prometheus.Counter
+	downstreamBytes prometheus.Counter
+	// release ends the upstream attempt once the stream is closed
+	release   func()
+	stopWatch context.CancelFunc
+
+	mu sync.Mutex
+	// reason is set when the gateway ends the stream itself
+	reason string
+	once   sync.Once
+}
+
+// newStream starts tracking a stream opened by the request with ctx.
+// onRevoked is called if the request's credential is revoked while the
+// stream is open.
+func newStream(ctx context.Context, route *Route, protocol string, release, onRevoked func()) *stream {
+	s := &stream{
+		route:           route.Name,
+		protocol:        protocol,
+		upstreamBytes:   observability.GatewayStreamBytes.WithLabelValues(route.Name, protocol, "upstream"),
+		downstreamBytes: observability.GatewayStreamBytes.WithLabelValues(route.Name, protocol, "downstream"),
+		release:         release,
+	}
+	observability.GatewayStreamConnections.WithLabelValues(route.Name, protocol).Inc()
+
+	ctx, s.stopWatch = context.WithCancel(ctx)
+	credential, _ := ctx.Value(credentialKey{}).(Credential)
+	if route.revoked != nil && (credential.Method == "jwt" || credential.Method == "api_key") {
+		go s.watch(ctx, route.revoked, credential, route.config.Streaming.RevocationCheckInterval, onRevoked)
+	}
+	return s
+}
+
+func (s *stream) watch(ctx context.Context, revoked RevocationCheck, credential Credential, interval time.Duration, onRevoked func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if revoked(ctx, credential) {
+				onRevoked()
+				return
+			}
+		}
+	}
+}
+
+// end records why the gateway is ending the stream. It reports false if the
+// stream was already being ended.
+func (s *stream) end(reason string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.reason != "" {
+		return false
+	}
+	s.reason = reason
+	return true
+}
+
+func (s *stream) ended() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reason != ""
+}
+
+func (s *stream) close() {
+	s.once.Do(func() {
+		s.stopWatch()
+		reason := "closed"
+		s.mu.Lock()
+		if s.reason != "" {
+			reason = s.reason
+		}
+		s.mu.Unlock()
+		observability.GatewayStreamConnections.WithLabelValues(s.route, s.protocol).Dec()
+		observability.GatewayStreamsClosed.WithLabelValues(s.route, s.protocol, reason).Inc()
+		s.release()
+	})
+}
+
+// eventStream is the body of a server-sent event response
+type eventStream struct {
+	io.ReadCloser
+	*stream
+}
+
+// newEventStream wraps an event stream body. Revoking the credential
+// cancels the upstream request through cancel.
+func newEventStream(ctx context.Context, body io.ReadCloser, route *Route, release, cancel func()) *eventStream {
+	s := &eventStream{ReadCloser: body}
+	s.stream = newStream(ctx, route, protocolSSE, release, func() {
+		if s.end("revoked") {
+			cancel()
+		}
+	})
+	return s
+}
+
+func (s *eventStream) Read(p []byte) (int, error) {
+	n, err := s.ReadCloser.Read(p)
+	s.downstreamBytes.Add(float64(n))
+	// A stream the gateway ended finishes like one the upstream ended
+	if err != nil && s.ended() {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (s *eventStream) Close() error {
+	err := s.ReadCloser.Close()
+	s.close()
+	return err
+}
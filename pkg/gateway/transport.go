@@ -0,0 +1,223 @@
+package gateway
+
+import (
+	"apisecurityplatform/pkg/config"
+	"apisecurityplatform/pkg/logging"
+	"apisecurityplatform/pkg/observability"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// errNoUpstream is returned when every upstream of a route is unhealthy,
+// ejected or behind an open circuit breaker
+var errNoUpstream = errors.New("no upstream available")
+
+// transport sends proxied requests to a route's upstreams, choosing one per
+// attempt and retrying idempotent requests on another upstream
+type transport struct {
+	route   *Route
+	base    http.RoundTripper
+	timeout time.Duration
+	budget  *retryBudget
+	// attempts is the total number of tries allowed per request
+	attempts int
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.budget.request(time.Now())
+	canRetry := retryable(req)
+
+	tried := make(map[*Upstream]bool)
+	upstream := t.route.pool.pick(tried)
+	if upstream == nil {
+		return nil, errNoUpstream
+	}
+
+	for attempt := 1; ; attempt++ {
+		tried[upstream] = true
+		resp, err := t.try(req, upstream, attempt)
+
+		failed := err != nil || retryableStatus(resp.StatusCode)
+		if !failed || !canRetry || attempt >= t.attempts || req.Context().Err() != nil {
+			return resp, err
+		}
+
+		if !t.budget.allow(time.Now()) {
+			observability.GatewayRetries.WithLabelValues(t.route.Name, "budget_exhausted").Inc()
+			return resp, err
+		}
+		next := t.route.pool.pick(tried)
+		if next == nil {
+			return resp, err
+		}
+		observability.GatewayRetries.WithLabelValues(t.route.Name, "retried").Inc()
+
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		upstream = next
+	}
+}
+
+// try makes a single attempt against upstream. The attempt's timeout, span
+// and in-flight count last until the response body is closed. The timeout
+// does not apply to WebSocket connections, event streams and gRPC calls once
+// their response has started.
+func (t *transport) try(req *http.Request, upstream *Upstream, attempt int) (*http.Response, error) {
+	method, methodAttrs := observability.HTTPMethod(req.Method)
+	ctx, span := observability.GetTracer().Start(req.Context(), method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(methodAttrs...),
+		trace.WithAttributes(
+			attribute.String("gateway.route", t.route.Name),
+			attribute.String("gateway.upstream", upstream.URL.Host),
+			attribute.Int("gateway.attempt", attempt),
+		),
+	)
+	if attempt > 1 {
+		span.SetAttributes(semconv.HTTPRequestResendCount(attempt - 1))
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	var timedOut atomic.Bool
+	timer := time.AfterFunc(t.timeout, func() {
+		timedOut.Store(true)
+		cancel()
+	})
+
+	out := req.Clone(ctx)
+	out.URL.Scheme = upstream.URL.Scheme
+	out.URL.Host = upstream.URL.Host
+	out.URL.Path = joinPath(upstream.URL.Path, req.URL.Path)
+	out.URL.RawPath = ""
+	out.Host = ""
+	span.SetAttributes(upstreamAttributes(out.URL)...)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(out.Header))
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(out)
+	if err != nil && timedOut.Load() {
+		err = fmt.Errorf("%w: %w", context.DeadlineExceeded, err)
+	}
+	duration := time.Since(start).Seconds()
+
+	success := err == nil && resp.StatusCode < http.StatusInternalServerError
+	result := "error"
+	if err == nil {
+		result = strconv.Itoa(resp.StatusCode/100) + "xx"
+		span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	} else {
+		span.RecordError(err)
+	}
+	if !success {
+		errorType := "_OTHER"
+		switch {
+		case err == nil:
+			errorType = strconv.Itoa(resp.StatusCode)
+		case errors.Is(err, context.DeadlineExceeded):
+			errorType = "timeout"
+		}
+		span.SetAttributes(semconv.ErrorTypeKey.String(errorType))
+		span.SetStatus(codes.Error, "upstream failure")
+	}
+	observability.GatewayUpstreamRequests.WithLabelValues(t.route.Name, upstream.URL.Host, result).Inc()
+	observability.ObserveWithTrace(observability.GatewayUpstreamDuration.WithLabelValues(t.route.Name, upstream.URL.Host), duration, span.SpanContext())
+
+	// A request the client gave up on says nothing about the upstream
+	if req.Context().Err() == nil {
+		state := t.route.pool.done(upstream, success)
+		span.SetAttributes(attribute.String("gateway.circuit_breaker", state.breakerState.String()))
+		if state.ejected {
+			span.AddEvent("upstream ejected")
+		}
+	} else {
+		t.route.pool.abandoned(upstream)
+	}
+
+	release := func() {
+		timer.Stop()
+		t.route.pool.release(upstream)
+		cancel()
+		span.End()
+	}
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	// The proxy relays an upgraded connection through its body, which must
+	// stay an io.ReadWriteCloser
+	if conn, ok := resp.Body.(io.ReadWriteCloser); ok && resp.StatusCode == http.StatusSwitchingProtocols {
+		timer.Stop()
+		resp.Body = newWebSocketConn(req.Context(), conn, t.route, release)
+		return resp, nil
+	}
+	if isEventStream(resp.Header) {
+		timer.Stop()
+		resp.Body = newEventStream(req.Context(), resp.Body, t.route, release, cancel)
+		return resp, nil
+	}
+	// Streaming calls can last indefinitely; their deadline is the client's
+	if t.route.config.Protocol == config.RouteProtocolGRPC {
+		timer.Stop()
+	}
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// releaseBody runs release once the proxied response has been consumed
+type releaseBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// joinPath appends the request path to the upstream's base path
+func joinPath(base, path string) string {
+	if base == "" || base == "/" {
+		return path
+	}
+	return strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(path, "/")
+}
+
+// upstreamAttributes describe the upstream request of a client span, with
+// sensitive query parameters redacted
+func upstreamAttributes(u *url.URL) []attribute.KeyValue {
+	full := *u
+	full.RawQuery = logging.RedactQuery(u.RawQuery)
+	full.User = nil
+	attrs := []attribute.KeyValue{
+		semconv.URLFull(full.String()),
+		semconv.ServerAddress(u.Hostname()),
+	}
+	port := u.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[u.Scheme]
+	}
+	if p, err := strconv.Atoi(port); err == nil {
+		attrs = append(attrs, semconv.ServerPort(p))
+	}
+	return attrs
+}
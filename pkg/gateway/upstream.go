@@ -0,0 +1,216 @@
+package gateway
+
+import (
+	"apisecurityplatform/pkg/config"
+	"apisecurityplatform/pkg/observability"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Upstream is a single target of a route, with the state used to decide
+// whether it can take traffic
+type Upstream struct {
+	URL    *url.URL
+	Weight int
+
+	// Fields below are guarded by the owning pool's mutex
+	active  int
+	healthy bool
+	// Consecutive active probe results, for the health check thresholds
+	probeSuccesses int
+	probeFailures  int
+	// Passive outlier detection
+	consecutiveFailures int
+	ejections           int
+	ejectedUntil        time.Time
+	breaker             circuitBreaker
+
+	activeGauge  prometheus.Gauge
+	healthyGauge prometheus.Gauge
+	ejectedGauge prometheus.Gauge
+}
+
+// pool tracks the upstreams of one route and picks one for each attempt
+type pool struct {
+	route    string
+	outlier  config.OutlierDetectionConfig
+	balancer balancer
+
+	mu        sync.Mutex
+	upstreams []*Upstream
+}
+
+func newPool(rc config.RouteConfig) (*pool, error) {
+	p := &pool{
+		route:    rc.Name,
+		outlier:  rc.OutlierDetection,
+		balancer: newBalancer(rc.LoadBalancing),
+	}
+	for _, uc := range rc.Upstreams {
+		target, err := url.Parse(uc.URL)
+		if err != nil {
+			return nil, err
+		}
+		u := &Upstream{
+			URL:          target,
+			Weight:       uc.Weight,
+			healthy:      true,
+			activeGauge:  observability.GatewayUpstreamActiveRequests.WithLabelValues(rc.Name, target.Host),
+			healthyGauge: observability.GatewayUpstreamHealthy.WithLabelValues(rc.Name, target.Host),
+			ejectedGauge: observability.GatewayUpstreamEjected.WithLabelValues(rc.Name, target.Host),
+		}
+		u.breaker = circuitBreaker{
+			config: rc.CircuitBreaker,
+			gauge:  observability.GatewayCircuitBreakerState.WithLabelValues(rc.Name, target.Host),
+		}
+		u.healthyGauge.Set(1)
+		u.ejectedGauge.Set(0)
+		u.breaker.gauge.Set(float64(breakerClosed))
+		p.upstreams = append(p.upstreams, u)
+	}
+	return p, nil
+}
+
+// pick chooses an available upstream not in tried, or nil if there is none
+func (p *pool) pick(tried map[*Upstream]bool) *Upstream {
+	now := time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	candidates := make([]*Upstream, 0, len(p.upstreams))
+	for _, u := range p.upstreams {
+		if !tried[u] && p.available(u, now) {
+			candidates = append(candidates, u)
+		}
+	}
+
+	for len(candidates) > 0 {
+		u := p.balancer.pick(candidates)
+		// A half-open breaker admits a single probe request at a time
+		if u.breaker.acquire(now) {
+			u.active++
+			u.activeGauge.Inc()
+			return u
+		}
+		candidates = remove(candidates, u)
+	}
+	return nil
+}
+
+// hasAvailable reports whether any upstream could take a request
+func (p *pool) hasAvailable() bool {
+	now := time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, u := range p.upstreams {
+		if p.available(u, now) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *pool) available(u *Upstream, now time.Time) bool {
+	if !u.ejectedUntil.IsZero() && !now.Before(u.ejectedUntil) {
+		u.ejectedUntil = time.Time{}
+		u.ejectedGauge.Set(0)
+	}
+	return u.healthy && u.ejectedUntil.IsZero() && u.breaker.ready(now)
+}
+
+// outcome is the state change caused by reporting an attempt's result
+type outcome struct {
+	ejected      bool
+	breakerState breakerState
+}
+
+// release ends an attempt against u, which pick returned
+func (p *pool) release(u *Upstream) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	u.active--
+	u.activeGauge.Dec()
+}
+
+// done records the result of an attempt against u, once its response
+// headers have arrived or it has failed
+func (p *pool) done(u *Upstream, success bool) outcome {
+	now := time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	u.breaker.record(now, success)
+
+	result := outcome{breakerState: u.breaker.state}
+	if success {
+		u.consecutiveFailures = 0
+		return result
+	}
+
+	u.consecutiveFailures++
+	threshold := p.outlier.ConsecutiveFailures
+	if threshold > 0 && u.consecutiveFailures >= threshold && u.ejectedUntil.IsZero() && p.canEject(now) {
+		// Repeat offenders stay out longer, up to ten times the base ejection time
+		u.ejections++
+		u.ejectedUntil = now.Add(p.outlier.BaseEjectionTime * time.Duration(min(u.ejections, 10)))
+		u.consecutiveFailures = 0
+		u.ejectedGauge.Set(1)
+		observability.GatewayUpstreamEjections.WithLabelValues(p.route, u.URL.Host).Inc()
+		result.ejected = true
+	}
+	return result
+}
+
+// abandoned records that an attempt against u ended without a result that
+// says anything about the upstream
+func (p *pool) abandoned(u *Upstream) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	u.breaker.abandon()
+}
+
+// canEject reports whether one more upstream may be ejected without
+// exceeding the configured share of the route
+func (p *pool) canEject(now time.Time) bool {
+	ejected := 0
+	for _, u := range p.upstreams {
+		if !u.ejectedUntil.IsZero() && now.Before(u.ejectedUntil) {
+			ejected++
+		}
+	}
+	return (ejected+1)*100 <= p.outlier.MaxEjectionPercent*len(p.upstreams)
+}
+
+// probed records the result of an active health check
+func (p *pool) probed(u *Upstream, ok bool, hc config.HealthCheckConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if ok {
+		u.probeFailures = 0
+		u.probeSuccesses++
+		if !u.healthy && u.probeSuccesses >= hc.HealthyThreshold {
+			u.healthy = true
+			u.healthyGauge.Set(1)
+		}
+		return
+	}
+	u.probeSuccesses = 0
+	u.probeFailures++
+	if u.healthy && u.probeFailures >= hc.UnhealthyThreshold {
+		u.healthy = false
+		u.healthyGauge.Set(0)
+	}
+}
+
+func remove(upstreams []*Upstream, u *Upstream) []*Upstream {
+	for i, candidate := range upstreams {
+		if candidate == u {
+			return append(upstreams[:i], upstreams[i+1:]...)
+		}
+	}
+	return upstreams
+}
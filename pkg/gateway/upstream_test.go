@@ -0,0 +1,151 @@
+package gateway
+
+import (
+	"apisecurityplatform/pkg/config"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// newTestPool creates a pool of n upstreams for rc, named after the test
+// so their metric series do not collide
+func newTestPool(t *testing.T, rc config.RouteConfig, n int) *pool {
+	t.Helper()
+	rc.Name = t.Name()
+	for i := 0; i < n; i++ {
+		rc.Upstreams = append(rc.Upstreams, config.UpstreamConfig{URL: fmt.Sprintf("http://upstream-%d.test", i), Weight: 1})
+	}
+	p, err := newPool(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// fail reports n consecutive failed attempts against u
+func fail(p *pool, u *Upstream, n int) outcome {
+	var result outcome
+	for i := 0; i < n; i++ {
+		result = p.done(u, false)
+	}
+	return result
+}
+
+func TestOutlierEjection(t *testing.T) {
+	base := time.Minute
+	p := newTestPool(t, config.RouteConfig{
+		OutlierDetection: config.OutlierDetectionConfig{ConsecutiveFailures: 3, BaseEjectionTime: base, MaxEjectionPercent: 100},
+	}, 2)
+	u := p.upstreams[0]
+
+	if fail(p, u, 2).ejected {
+		t.Fatal("ejected below consecutive_failures")
+	}
+	// A success resets the count
+	p.done(u, true)
+	if fail(p, u, 2).ejected {
+		t.Fatal("failures before a success counted towards ejection")
+	}
+
+	start := time.Now()
+	if !fail(p, u, 1).ejected {
+		t.Fatal("not ejected after consecutive_failures")
+	}
+	if got := u.ejectedUntil.Sub(start); got < base || got > base+time.Second {
+		t.Errorf("first ejection lasts %s, want %s", got, base)
+	}
+	if p.available(u, time.Now()) {
+		t.Error("ejected upstream is available")
+	}
+	for i := 0; i < 4; i++ {
+		if picked := p.pick(nil); picked != p.upstreams[1] {
+			t.Fatalf("pick %d = %v, want the remaining upstream", i, picked)
+		}
+	}
+}
+
+func TestOutlierEjectionBackOff(t *testing.T) {
+	base := time.Minute
+	p := newTestPool(t, config.RouteConfig{
+		OutlierDetection: config.OutlierDetectionConfig{ConsecutiveFailures: 1, BaseEjectionTime: base, MaxEjectionPercent: 100},
+	}, 2)
+	u := p.upstreams[0]
+
+	// Repeat offenders stay out longer each time, up to ten times the base
+	for ejection, want := range []time.Duration{1, 2, 3, 4, 10, 10} {
+		if ejection == 4 {
+			u.ejections = 10
+		}
+		start := time.Now()
+		if !fail(p, u, 1).ejected {
+			t.Fatalf("ejection %d did not happen", ejection+1)
+		}
+		if got := u.ejectedUntil.Sub(start); got < want*base || got > want*base+time.Second {
+			t.Errorf("ejection %d lasts %s, want %s", ejection+1, got, want*base)
+		}
+		// Let the ejection expire
+		u.ejectedUntil = time.Now().Add(-time.Second)
+		if !p.available(u, time.Now()) {
+			t.Fatalf("upstream not back after ejection %d expired", ejection+1)
+		}
+	}
+}
+
+func TestOutlierEjectionCap(t *testing.T) {
+	tests := []struct {
+		upstreams  int
+		maxPercent int
+		want       int
+	}{
+		{upstreams: 4, maxPercent: 50, want: 2},
+		{upstreams: 3, maxPercent: 50, want: 1},
+		{upstreams: 2, maxPercent: 10, want: 0},
+		{upstreams: 3, maxPercent: 100, want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d upstreams %d%%", tt.upstreams, tt.maxPercent), func(t *testing.T) {
+			p := newTestPool(t, config.RouteConfig{
+				OutlierDetection: config.OutlierDetectionConfig{ConsecutiveFailures: 1, BaseEjectionTime: time.Minute, MaxEjectionPercent: tt.maxPercent},
+			}, tt.upstreams)
+
+			ejected := 0
+			for _, u := range p.upstreams {
+				if fail(p, u, 1).ejected {
+					ejected++
+				}
+			}
+			if ejected != tt.want {
+				t.Errorf("%d upstreams ejected, want %d", ejected, tt.want)
+			}
+		})
+	}
+}
+
+func TestHealthCheckThresholds(t *testing.T) {
+	hc := config.HealthCheckConfig{HealthyThreshold: 2, UnhealthyThreshold: 3}
+	p := newTestPool(t, config.RouteConfig{}, 1)
+	u := p.upstreams[0]
+
+	for i := 0; i < 2; i++ {
+		p.probed(u, false, hc)
+	}
+	p.probed(u, true, hc)
+	for i := 0; i < 2; i++ {
+		p.probed(u, false, hc)
+	}
+	if !u.healthy {
+		t.Fatal("unhealthy before unhealthy_threshold consecutive failures")
+	}
+	p.probed(u, false, hc)
+	if u.healthy || p.pick(nil) != nil {
+		t.Fatal("upstream still used after unhealthy_threshold failures")
+	}
+	p.probed(u, true, hc)
+	if u.healthy {
+		t.Fatal("healthy before healthy_threshold successes")
+	}
+	p.probed(u, true, hc)
+	if !u.healthy {
+		t.Error("not healthy after healthy_threshold successes")
+	}
+}
@@ -0,0 +1,251 @@
+package gateway
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// WebSocket opcodes and close codes, from RFC 6455
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+
+	closePolicyViolation = 1008
+	closeMessageTooBig   = 1009
+)
+
+var errTerminated = errors.New("websocket connection terminated by the gateway")
+
+// webSocketConn is the upgraded connection to the upstream. The proxy
+// writes the client's frames to it and reads the upstream's frames from it,
+// which lets the gateway limit what the client sends and end the connection
+// with a close frame of its own.
+type webSocketConn struct {
+	io.ReadWriteCloser
+	*stream
+	maxMessageBytes uint64
+	// limiter throttles the client's messages; nil when unlimited
+	limiter *rate.Limiter
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	// fromClient is only used by Write, toClient only by Read
+	fromClient frameScanner
+	toClient   frameScanner
+	message    uint64
+
+	mu         sync.Mutex
+	terminated bool
+	closeCode  uint16
+	closeText  string
+	// closeFrame is what is left to send of the gateway's close frame
+	closeFrame []byte
+	closeBuilt bool
+}
+
+func newWebSocketConn(ctx context.Context, conn io.ReadWriteCloser, route *Route, release func()) *webSocketConn {
+	limits := route.config.Streaming
+	c := &webSocketConn{
+		ReadWriteCloser: conn,
+		maxMessageBytes: uint64(limits.MaxMessageBytes),
+	}
+	if limits.MessagesPerSecond > 0 {
+		c.limiter = rate.NewLimiter(rate.Limit(limits.MessagesPerSecond), limits.MessageBurst)
+	}
+	c.ctx, c.cancel = context.WithCancel(context.WithoutCancel(ctx))
+	c.stream = newStream(ctx, route, protocolWebSocket, release, func() {
+		c.terminate(closePolicyViolation, "credential revoked", "revoked")
+	})
+	return c
+}
+
+// Write forwards the client's frames to the upstream
+func (c *webSocketConn) Write(p []byte) (int, error) {
+	if c.isTerminated() {
+		return len(p), nil
+	}
+	if err := c.fromClient.scan(p, c.checkFrame); err != nil {
+		if errors.Is(err, errTerminated) {
+			return len(p), nil
+		}
+		return 0, err
+	}
+	n, err := c.ReadWriteCloser.Write(p)
+	c.upstreamBytes.Add(float64(n))
+	return n, err
+}
+
+// checkFrame applies the limits as each frame from the client starts
+func (c *webSocketConn) checkFrame(opcode byte, length uint64) error {
+	switch opcode {
+	case opText, opBinary:
+		c.message = length
+		// Waiting holds back the client's frames, and the client with them
+		if c.limiter != nil {
+			if err := c.limiter.Wait(c.ctx); err != nil {
+				return err
+			}
+		}
+	case opContinuation:
+		c.message += length
+	default:
+		return nil
+	}
+	if c.message > c.maxMessageBytes {
+		c.terminate(closeMessageTooBig, "message too large", "message_too_large")
+		return errTerminated
+	}
+	return nil
+}
+
+// Read relays the upstream's frames to the client. Once the gateway ends
+// the connection, it returns the gateway's close frame and then EOF.
+func (c *webSocketConn) Read(p []byte) (int, error) {
+	if c.isTerminated() {
+		return c.readClose(p)
+	}
+	n, err := c.ReadWriteCloser.Read(p)
+	c.toClient.scan(p[:n], nil)
+	c.downstreamBytes.Add(float64(n))
+	if err != nil && c.isTerminated() {
+		if n > 0 {
+			return n, nil
+		}
+		return c.readClose(p)
+	}
+	return n, err
+}
+
+func (c *webSocketConn) readClose(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// A close frame can only be sent between two frames from the upstream
+	if !c.closeBuilt {
+		c.closeBuilt = true
+		if c.toClient.atBoundary() {
+			c.closeFrame = closeFrame(c.closeCode, c.closeText)
+		}
+	}
+	if len(c.closeFrame) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, c.closeFrame)
+	c.closeFrame = c.closeFrame[n:]
+	return n, nil
+}
+
+// terminate ends the connection: the upstream connection is closed and the
+// client receives a close frame with code
+func (c *webSocketConn) terminate(code uint16, text, reason string) {
+	c.mu.Lock()
+	if c.terminated {
+		c.mu.Unlock()
+		return
+	}
+	c.terminated = true
+	c.closeCode, c.closeText = code, text
+	c.mu.Unlock()
+
+	c.end(reason)
+	c.cancel()
+	c.ReadWriteCloser.Close()
+}
+
+func (c *webSocketConn) isTerminated() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.terminated
+}
+
+func (c *webSocketConn) Close() error {
+	err := c.ReadWriteCloser.Close()
+	c.cancel()
+	c.close()
+	return err
+}
+
+// closeFrame builds an unmasked close frame, as sent by a server
+func closeFrame(code uint16, text string) []byte {
+	payload := binary.BigEndian.AppendUint16(nil, code)
+	payload = append(payload, text...)
+	return append([]byte{0x80 | opClose, byte(len(payload))}, payload...)
+}
+
+// frameScanner follows the frame boundaries in one direction of a
+// WebSocket connection, without buffering payloads
+type frameScanner struct {
+	header    []byte
+	remaining uint64
+}
+
+// scan consumes p, calling onFrame as each frame header is completed. It
+// stops at the first error onFrame returns.
+func (s *frameScanner) scan(p []byte, onFrame func(opcode byte, length uint64) error) error {
+	for len(p) > 0 {
+		if s.remaining > 0 {
+			n := min(uint64(len(p)), s.remaining)
+			s.remaining -= n
+			p = p[n:]
+			continue
+		}
+
+		s.header = append(s.header, p[0])
+		p = p[1:]
+		if size, ok := frameHeaderSize(s.header); !ok || len(s.header) < size {
+			continue
+		}
+		opcode, length := parseFrameHeader(s.header)
+		s.header = s.header[:0]
+		s.remaining = length
+		if onFrame != nil {
+			if err := onFrame(opcode, length); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// atBoundary reports whether the scanner is between two frames
+func (s *frameScanner) atBoundary() bool {
+	return s.remaining == 0 && len(s.header) == 0
+}
+
+// frameHeaderSize returns the size of the frame header starting with h,
+// once enough of it is known
+func frameHeaderSize(h []byte) (int, bool) {
+	if len(h) < 2 {
+		return 0, false
+	}
+	size := 2
+	switch h[1] & 0x7f {
+	case 126:
+		size += 2
+	case 127:
+		size += 8
+	}
+	// Frames from clients carry a masking key
+	if h[1]&0x80 != 0 {
+		size += 4
+	}
+	return size, true
+}
+
+func parseFrameHeader(h []byte) (byte, uint64) {
+	opcode := h[0] & 0x0f
+	length := uint64(h[1] & 0x7f)
+	switch length {
+	case 126:
+		length = uint64(binary.BigEndian.Uint16(h[2:4]))
+	case 127:
+		length = binary.BigEndian.Uint64(h[2:10])
+	}
+	return opcode, length
+}
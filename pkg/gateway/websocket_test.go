@@ -0,0 +1,348 @@
+package gateway
+
+import (
+	"apisecurityplatform/pkg/config"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const opPing = 0x9
+
+// frame builds a WebSocket frame. Client frames are masked, as RFC 6455
+// requires.
+func frame(opcode byte, fin, masked bool, payload []byte) []byte {
+	first := opcode
+	if fin {
+		first |= 0x80
+	}
+	maskBit := byte(0)
+	if masked {
+		maskBit = 0x80
+	}
+	f := []byte{first}
+	switch n := len(payload); {
+	case n < 126:
+		f = append(f, maskBit|byte(n))
+	case n <= 0xffff:
+		f = binary.BigEndian.AppendUint16(append(f, maskBit|126), uint16(n))
+	default:
+		f = binary.BigEndian.AppendUint64(append(f, maskBit|127), uint64(n))
+	}
+	if !masked {
+		return append(f, payload...)
+	}
+	key := []byte{0x12, 0x34, 0x56, 0x78}
+	f = append(f, key...)
+	for i, b := range payload {
+		f = append(f, b^key[i%4])
+	}
+	return f
+}
+
+type scannedFrame struct {
+	opcode byte
+	length uint64
+}
+
+func TestFrameScanner(t *testing.T) {
+	large := bytes.Repeat([]byte("x"), 300)
+	huge := bytes.Repeat([]byte("x"), 70000)
+	tests := []struct {
+		name   string
+		stream []byte
+		want   []scannedFrame
+	}{
+		{"unmasked", frame(opText, true, false, []byte("hello")), []scannedFrame{{opText, 5}}},
+		{"masked", frame(opText, true, true, []byte("hello")), []scannedFrame{{opText, 5}}},
+		{"empty", frame(opBinary, true, true, nil), []scannedFrame{{opBinary, 0}}},
+		{"16-bit length", frame(opBinary, true, true, large), []scannedFrame{{opBinary, 300}}},
+		{"64-bit length", frame(opBinary, true, true, huge), []scannedFrame{{opBinary, 70000}}},
+		{
+			"fragmented",
+			bytes.Join([][]byte{
+				frame(opText, false, true, []byte("hel")),
+				frame(opPing, true, true, []byte("p")),
+				frame(opContinuation, false, true, large),
+				frame(opContinuation, true, true, []byte("lo")),
+			}, nil),
+			[]scannedFrame{{opText, 3}, {opPing, 1}, {opContinuation, 300}, {opContinuation, 2}},
+		},
+	}
+	for _, tt := range tests {
+		// Frames are followed whether they arrive whole or byte by byte
+		for _, chunk := range []int{len(tt.stream), 1, 7} {
+			var s frameScanner
+			var got []scannedFrame
+			for p := tt.stream; len(p) > 0; {
+				n := min(chunk, len(p))
+				s.scan(p[:n], func(opcode byte, length uint64) error {
+					got = append(got, scannedFrame{opcode, length})
+					return nil
+				})
+				p = p[n:]
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("%s in chunks of %d: frames %v, want %v", tt.name, chunk, got, tt.want)
+				continue
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("%s in chunks of %d: frame %d = %v, want %v", tt.name, chunk, i, got[i], tt.want[i])
+				}
+			}
+			if !s.atBoundary() {
+				t.Errorf("%s in chunks of %d: scanner not at a frame boundary", tt.name, chunk)
+			}
+		}
+	}
+}
+
+// upstreamConn is the upstream side of a WebSocket connection
+type upstreamConn struct {
+	received bytes.Buffer
+	toClient io.Reader
+	closed   bool
+}
+
+func (u *upstreamConn) Read(p []byte) (int, error) {
+	if u.closed {
+		return 0, io.ErrClosedPipe
+	}
+	return u.toClient.Read(p)
+}
+
+func (u *upstreamConn) Write(p []byte) (int, error) {
+	if u.closed {
+		return 0, io.ErrClosedPipe
+	}
+	return u.received.Write(p)
+}
+
+func (u *upstreamConn) Close() error {
+	u.closed = true
+	return nil
+}
+
+func newTestWebSocketConn(t *testing.T, maxMessageBytes int64, toClient []byte) (*webSocketConn, *upstreamConn) {
+	t.Helper()
+	upstream := &upstreamConn{toClient: bytes.NewReader(toClient)}
+	route := &Route{Name: t.Name(), config: config.RouteConfig{
+		Streaming: config.StreamingConfig{MaxMessageBytes: maxMessageBytes},
+	}}
+	conn := newWebSocketConn(context.Background(), upstream, route, func() {})
+	t.Cleanup(func() { conn.Close() })
+	return conn, upstream
+}
+
+func TestWebSocketMessageSize(t *testing.T) {
+	tests := []struct {
+		name      string
+		frames    [][]byte
+		forwarded int
+		tooBig    bool
+	}{
+		{
+			name:      "within the limit",
+			frames:    [][]byte{frame(opText, true, true, bytes.Repeat([]byte("x"), 10))},
+			forwarded: 1,
+		},
+		{
+			name:   "oversized frame",
+			frames: [][]byte{frame(opBinary, true, true, bytes.Repeat([]byte("x"), 11))},
+			tooBig: true,
+		},
+		{
+			name: "fragments within the limit",
+			frames: [][]byte{
+				frame(opText, false, true, []byte("12345")),
+				frame(opContinuation, true, true, []byte("12345")),
+			},
+			forwarded: 2,
+		},
+		{
+			name: "oversized fragmented message",
+			frames: [][]byte{
+				frame(opText, false, true, []byte("12345")),
+				frame(opContinuation, false, true, []byte("12345")),
+				frame(opContinuation, true, true, []byte("1")),
+			},
+			forwarded: 2,
+			tooBig:    true,
+		},
+		{
+			name: "control frames between fragments",
+			frames: [][]byte{
+				frame(opText, false, true, []byte("12345")),
+				frame(opPing, true, true, []byte("123456789")),
+				frame(opContinuation, true, true, []byte("12345")),
+			},
+			forwarded: 3,
+		},
+		{
+			name: "each message counted alone",
+			frames: [][]byte{
+				frame(opText, true, true, []byte("1234567890")),
+				frame(opText, true, true, []byte("1234567890")),
+			},
+			forwarded: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, upstream := newTestWebSocketConn(t, 10, nil)
+			var want bytes.Buffer
+			for i, f := range tt.frames {
+				n, err := conn.Write(f)
+				if err != nil || n != len(f) {
+					t.Fatalf("Write of frame %d = %d, %v", i, n, err)
+				}
+				if i < tt.forwarded {
+					want.Write(f)
+				}
+			}
+			if !bytes.Equal(upstream.received.Bytes(), want.Bytes()) {
+				t.Errorf("upstream received %d bytes, want the first %d frames (%d bytes)", upstream.received.Len(), tt.forwarded, want.Len())
+			}
+			if upstream.closed != tt.tooBig {
+				t.Fatalf("upstream closed = %v, want %v", upstream.closed, tt.tooBig)
+			}
+			if !tt.tooBig {
+				return
+			}
+
+			// The client is told why before the connection ends
+			got, err := io.ReadAll(conn)
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+			if want := closeFrame(closeMessageTooBig, "message too large"); !bytes.Equal(got, want) {
+				t.Errorf("client received %x, want close frame %x", got, want)
+			}
+			if n, err := conn.Write(frame(opText, true, true, []byte("x"))); n != 7 || err != nil || upstream.received.Len() != want.Len() {
+				t.Error("frames after the close were forwarded")
+			}
+		})
+	}
+}
+
+func TestWebSocketCloseWaitsForFrameBoundary(t *testing.T) {
+	// The upstream is in the middle of a frame when the gateway terminates
+	partial := frame(opText, true, false, []byte("hello"))[:4]
+	conn, _ := newTestWebSocketConn(t, 10, partial)
+
+	buf := make([]byte, 64)
+	if n, _ := conn.Read(buf); n != len(partial) {
+		t.Fatalf("Read = %d bytes, want %d", n, len(partial))
+	}
+	conn.Write(frame(opBinary, true, true, bytes.Repeat([]byte("x"), 11)))
+	if got, err := io.ReadAll(conn); len(got) != 0 || err != nil {
+		t.Errorf("client received %x, %v after a partial frame; want EOF without a close frame", got, err)
+	}
+}
+
+func TestLiftCredentials(t *testing.T) {
+	tests := []struct {
+		name          string
+		target        string
+		header        http.Header
+		wantQuery     string
+		authorization string
+		apiKey        string
+		protocol      string
+	}{
+		{
+			name:          "access token",
+			target:        "/ws?access_token=tok&room=1",
+			header:        http.Header{"Upgrade": {"websocket"}},
+			wantQuery:     "room=1",
+			authorization: "Bearer tok",
+		},
+		{
+			name:      "api key",
+			target:    "/events?api_key=key",
+			header:    http.Header{"Accept": {"text/event-stream"}},
+			wantQuery: "",
+			apiKey:    "key",
+		},
+		{
+			name:          "repeated and empty",
+			target:        "/ws?access_token=a&access_token=b&api_key=&x=%2F",
+			header:        http.Header{"Upgrade": {"websocket"}},
+			wantQuery:     "x=%2F",
+			authorization: "Bearer a",
+		},
+		{
+			name:          "encoded name",
+			target:        "/ws?access%5Ftoken=tok&api%5fkey=key",
+			header:        http.Header{"Upgrade": {"websocket"}},
+			wantQuery:     "",
+			authorization: "Bearer tok",
+			apiKey:        "key",
+		},
+		{
+			name:      "semicolon separated",
+			target:    "/ws?room=1;access_token=tok&x=1;api_key=key;y=2",
+			header:    http.Header{"Upgrade": {"websocket"}},
+			wantQuery: "room=1&x=1;y=2",
+		},
+		{
+			name:          "header kept",
+			target:        "/ws?access_token=query",
+			header:        http.Header{"Upgrade": {"websocket"}, "Authorization": {"Bearer header"}},
+			wantQuery:     "",
+			authorization: "Bearer header",
+		},
+		{
+			name:          "subprotocol",
+			target:        "/ws",
+			header:        http.Header{"Upgrade": {"websocket"}, "Sec-Websocket-Protocol": {"chat, bearer.tok", "api-key.key"}},
+			authorization: "Bearer tok",
+			apiKey:        "key",
+			protocol:      "chat",
+		},
+		{
+			name:      "not a stream",
+			target:    "/items?api_key=key",
+			wantQuery: "api_key=key",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			for name, values := range tt.header {
+				req.Header[name] = values
+			}
+			if req.Header.Get("Upgrade") != "" {
+				req.Header.Set("Connection", "keep-alive, Upgrade")
+			}
+
+			liftCredentials(req)
+
+			if req.URL.RawQuery != tt.wantQuery {
+				t.Errorf("query = %q, want %q", req.URL.RawQuery, tt.wantQuery)
+			}
+			if isWebSocketUpgrade(req) || acceptsEventStream(req) {
+				for _, param := range []string{"access_token", "api_key"} {
+					if strings.Contains(req.URL.String(), param) {
+						t.Errorf("%s left in the upstream URL %s", param, req.URL)
+					}
+				}
+			}
+			if got := req.Header.Get("Authorization"); got != tt.authorization {
+				t.Errorf("Authorization = %q, want %q", got, tt.authorization)
+			}
+			if got := req.Header.Get("X-API-Key"); got != tt.apiKey {
+				t.Errorf("X-API-Key = %q, want %q", got, tt.apiKey)
+			}
+			if got := req.Header.Get("Sec-WebSocket-Protocol"); got != tt.protocol {
+				t.Errorf("Sec-WebSocket-Protocol = %q, want %q", got, tt.protocol)
+			}
+		})
+	}
+}
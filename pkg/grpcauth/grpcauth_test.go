@@ -0,0 +1,187 @@
+package grpcauth
+
+import (
+	"apisecurityplatform/pkg/auth"
+	"apisecurityplatform/pkg/models"
+	"apisecurityplatform/pkg/repository"
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const testSecret = "test-secret-that-is-long-enough-to-sign"
+
+func signToken(t *testing.T, secret string, userID uint) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": userID,
+		"email":   "alice@example.com",
+		"role":    "admin",
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// newAPIKeys stores the API key "valid-key" for user 2 with two scopes
+func newAPIKeys(t *testing.T) *repository.MemoryAPIKeyRepository {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte("valid-key"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := repository.NewMemoryAPIKeyRepository()
+	if err := keys.Create(context.Background(), &models.APIKey{UserID: 2, Name: "ci", Key: string(hash), Scopes: "orders:read orders:write"}); err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+// failingKeys is an APIKeyRepository that cannot be read
+type failingKeys struct {
+	repository.APIKeyRepository
+}
+
+func (failingKeys) List(context.Context) ([]models.APIKey, error) {
+	return nil, errors.New("database unavailable")
+}
+
+func incoming(pairs ...string) context.Context {
+	ctx := context.Background()
+	if pairs == nil {
+		return ctx
+	}
+	return metadata.NewIncomingContext(ctx, metadata.Pairs(pairs...))
+}
+
+func TestAuthenticate(t *testing.T) {
+	keys := auth.NewSigningKeys(testSecret)
+	apiKeys := newAPIKeys(t)
+	token := signToken(t, testSecret, 1)
+
+	tests := []struct {
+		name       string
+		auth       *Authenticator
+		ctx        context.Context
+		wantCode   codes.Code
+		wantMethod string
+	}{
+		{"JWT", New(keys, apiKeys), incoming(MetadataAuthorization, "Bearer "+token), codes.OK, auth.MethodJWT},
+		{"API key", New(keys, apiKeys), incoming(MetadataAPIKey, "valid-key"), codes.OK, auth.MethodAPIKey},
+		{"JWT takes precedence over an API key", New(keys, apiKeys),
+			incoming(MetadataAuthorization, "Bearer "+token, MetadataAPIKey, "valid-key"), codes.OK, auth.MethodJWT},
+		{"an invalid JWT is not retried as an API key", New(keys, apiKeys),
+			incoming(MetadataAuthorization, "Bearer invalid", MetadataAPIKey, "valid-key"), codes.Unauthenticated, ""},
+		{"API key with API keys disabled", New(keys, nil), incoming(MetadataAPIKey, "valid-key"), codes.Unauthenticated, ""},
+		{"JWT with JWTs disabled", New(nil, apiKeys),
+			incoming(MetadataAuthorization, "Bearer "+token, MetadataAPIKey, "valid-key"), codes.OK, auth.MethodAPIKey},
+		{"no metadata", New(keys, apiKeys), incoming(), codes.Unauthenticated, ""},
+		{"no metadata, JWTs only", New(keys, nil), incoming(), codes.Unauthenticated, ""},
+		{"no credentials accepted", New(nil, nil), incoming(MetadataAuthorization, "Bearer "+token), codes.Unauthenticated, ""},
+		{"malformed authorization", New(keys, apiKeys), incoming(MetadataAuthorization, token), codes.Unauthenticated, ""},
+		{"JWT signed with another key", New(keys, apiKeys),
+			incoming(MetadataAuthorization, "Bearer "+signToken(t, "another-secret-that-is-long-enough", 1)), codes.Unauthenticated, ""},
+		{"invalid API key", New(keys, apiKeys), incoming(MetadataAPIKey, "wrong-key"), codes.Unauthenticated, ""},
+		{"API keys unavailable", New(keys, failingKeys{}), incoming(MetadataAPIKey, "valid-key"), codes.Internal, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, err := tt.auth.Authenticate(tt.ctx)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("code = %s, want %s: %v", code, tt.wantCode, err)
+			}
+			if err != nil {
+				return
+			}
+			identity, ok := FromContext(ctx)
+			if !ok || identity.Method != tt.wantMethod {
+				t.Errorf("identity = %+v, want method %s", identity, tt.wantMethod)
+			}
+		})
+	}
+}
+
+func TestAuthenticateIdentity(t *testing.T) {
+	a := New(auth.NewSigningKeys(testSecret), newAPIKeys(t))
+
+	ctx, err := a.Authenticate(incoming(MetadataAuthorization, "Bearer "+signToken(t, testSecret, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity, _ := FromContext(ctx); identity.UserID != 1 || identity.Email != "alice@example.com" || identity.Role != "admin" || identity.APIKeyID != 0 {
+		t.Errorf("JWT identity = %+v", identity)
+	}
+
+	ctx, err = a.Authenticate(incoming(MetadataAPIKey, "valid-key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity, _ := FromContext(ctx); identity.UserID != 2 || identity.APIKeyID == 0 || !slices.Equal(identity.Scopes, []string{"orders:read", "orders:write"}) {
+		t.Errorf("API key identity = %+v", identity)
+	}
+
+	if _, ok := FromContext(context.Background()); ok {
+		t.Error("identity found in an unauthenticated context")
+	}
+}
+
+// testStream is a server stream over a fixed context
+type testStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *testStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestInterceptors(t *testing.T) {
+	a := New(auth.NewSigningKeys(testSecret), nil)
+	authorized := incoming(MetadataAuthorization, "Bearer "+signToken(t, testSecret, 1))
+
+	var unaryIdentity Identity
+	unary := a.UnaryServerInterceptor()
+	_, err := unary(authorized, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, _ any) (any, error) {
+		unaryIdentity, _ = FromContext(ctx)
+		return nil, nil
+	})
+	if err != nil || unaryIdentity.UserID != 1 {
+		t.Errorf("unary call: error %v, identity %+v", err, unaryIdentity)
+	}
+
+	var streamIdentity Identity
+	stream := a.StreamServerInterceptor()
+	err = stream(nil, &testStream{ctx: authorized}, &grpc.StreamServerInfo{}, func(_ any, s grpc.ServerStream) error {
+		streamIdentity, _ = FromContext(s.Context())
+		return nil
+	})
+	if err != nil || streamIdentity.UserID != 1 || streamIdentity.Method != auth.MethodJWT {
+		t.Errorf("streaming call: error %v, identity %+v", err, streamIdentity)
+	}
+
+	called := false
+	err = stream(nil, &testStream{ctx: incoming()}, &grpc.StreamServerInfo{}, func(any, grpc.ServerStream) error {
+		called = true
+		return nil
+	})
+	if status.Code(err) != codes.Unauthenticated || called {
+		t.Errorf("unauthenticated streaming call: error %v, handler called %v", err, called)
+	}
+	if _, err := unary(incoming(), nil, &grpc.UnaryServerInfo{}, func(context.Context, any) (any, error) {
+		t.Error("handler called for an unauthenticated unary call")
+		return nil, nil
+	}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("unauthenticated unary call: error %v", err)
+	}
+}
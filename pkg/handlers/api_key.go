@@ -1,19 +1,34 @@
 package handlers
 
 import (
-	"apisecurityplatform/pkg/database"
 	"apisecurityplatform/pkg/models"
+	"apisecurityplatform/pkg/observability"
+	"apisecurityplatform/pkg/repository"
 	"crypto/rand"
 	"encoding/base64"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type CreateAPIKeyInput struct {
-	Name        string `json:"name" binding:"required"`
-	Description string `json:"description"`
+	Name        string   `json:"name" binding:"required"`
+	Description string   `json:"description"`
+	Scopes      []string `json:"scopes" binding:"dive,required"`
+}
+
+// APIKeyHandler serves the API key management endpoints
+type APIKeyHandler struct {
+	keys repository.APIKeyRepository
+}
+
+// NewAPIKeyHandler creates an APIKeyHandler backed by the given repository
+func NewAPIKeyHandler(keys repository.APIKeyRepository) *APIKeyHandler {
+	return &APIKeyHandler{keys: keys}
 }
 
 // GenerateAPIKey generates a random 32-character API key
@@ -36,13 +51,23 @@ func GenerateAPIKey() (string, error) {
 // @Failure 400 {object} map[string]interface{} "Invalid input"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
 // @Router /users/api-keys [post]
-func CreateAPIKey(c *gin.Context) {
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	defer recordAPIKeyOperation(c, "create")
+
 	var input CreateAPIKeyInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	// Scopes are stored space separated
+	for _, scope := range input.Scopes {
+		if strings.ContainsAny(scope, " \t\r\n") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Scopes must not contain whitespace"})
+			return
+		}
+	}
+
 	// Get user ID from context (set by AuthMiddleware)
 	userID, _ := c.Get("user_id")
 
@@ -66,9 +91,10 @@ func CreateAPIKey(c *gin.Context) {
 		Name:        input.Name,
 		Key:         string(hashedKey),
 		Description: input.Description,
+		Scopes:      strings.Join(input.Scopes, " "),
 	}
 
-	if err := database.DB.Create(&apiKeyRecord).Error; err != nil {
+	if err := h.keys.Create(c.Request.Context(), &apiKeyRecord); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save API key"})
 		return
 	}
@@ -79,6 +105,7 @@ func CreateAPIKey(c *gin.Context) {
 		"api_key": apiKey,
 		"id":      apiKeyRecord.ID,
 		"name":    apiKeyRecord.Name,
+		"scopes":  apiKeyRecord.ScopeList(),
 	})
 }
 
@@ -91,11 +118,11 @@ func CreateAPIKey(c *gin.Context) {
 // @Success 200 {object} map[string]interface{} "List of API keys"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
 // @Router /users/api-keys [get]
-func ListAPIKeys(c *gin.Context) {
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 
-	var apiKeys []models.APIKey
-	if err := database.DB.Where("user_id = ?", userID).Find(&apiKeys).Error; err != nil {
+	apiKeys, err := h.keys.ListByUser(c.Request.Context(), userID.(uint))
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch API keys"})
 		return
 	}
@@ -107,6 +134,7 @@ func ListAPIKeys(c *gin.Context) {
 			"id":          key.ID,
 			"name":        key.Name,
 			"description": key.Description,
+			"scopes":      key.ScopeList(),
 			"created_at":  key.CreatedAt,
 			"last_used":   key.LastUsedAt,
 		})
@@ -126,21 +154,38 @@ func ListAPIKeys(c *gin.Context) {
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
 // @Failure 404 {object} map[string]interface{} "API key not found"
 // @Router /users/api-keys/{id} [delete]
-func DeleteAPIKey(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	keyID := c.Param("id")
+func (h *APIKeyHandler) DeleteAPIKey(c *gin.Context) {
+	defer recordAPIKeyOperation(c, "delete")
 
-	// Verify ownership and delete
-	result := database.DB.Where("id = ? AND user_id = ?", keyID, userID).Delete(&models.APIKey{})
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete API key"})
+	userID, _ := c.Get("user_id")
+	keyID, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key ID"})
 		return
 	}
 
-	if result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found or unauthorized"})
+	// Verify ownership and delete
+	if err := h.keys.DeleteForUser(c.Request.Context(), uint(keyID), userID.(uint)); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API key not found or unauthorized"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete API key"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "API key deleted successfully"})
 }
+
+// recordAPIKeyOperation counts an API key operation by the outcome of its
+// response: "success", "rejected" for client errors, otherwise "error"
+func recordAPIKeyOperation(c *gin.Context, operation string) {
+	outcome := "success"
+	switch status := c.Writer.Status(); {
+	case status >= http.StatusInternalServerError:
+		outcome = "error"
+	case status >= http.StatusBadRequest:
+		outcome = "rejected"
+	}
+	observability.APIKeyOperations.WithLabelValues(operation, outcome).Inc()
+}
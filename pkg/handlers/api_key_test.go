@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"apisecurityplatform/pkg/auth"
+	"apisecurityplatform/pkg/repository"
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func newAPIKeyRouter(keys repository.APIKeyRepository, userID uint) http.Handler {
+	h := NewAPIKeyHandler(keys)
+	r := newTestRouter(userID, "user")
+	r.POST("/users/api-keys", h.CreateAPIKey)
+	r.GET("/users/api-keys", h.ListAPIKeys)
+	r.DELETE("/users/api-keys/:id", h.DeleteAPIKey)
+	return r
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	keys := repository.NewMemoryAPIKeyRepository()
+	r := newAPIKeyRouter(keys, 7)
+
+	status, body := serveJSON(t, r, http.MethodPost, "/users/api-keys",
+		CreateAPIKeyInput{Name: "ci", Scopes: []string{"orders:read", "pii:read"}})
+	if status != http.StatusCreated {
+		t.Fatalf("status %d, body %v", status, body)
+	}
+	key, _ := body["api_key"].(string)
+	if key == "" {
+		t.Fatal("response carries no key")
+	}
+
+	// Only the hash is stored, and the returned key authenticates as the user
+	stored, err := auth.AuthenticateAPIKey(context.Background(), keys, key)
+	if err != nil {
+		t.Fatalf("created key does not authenticate: %v", err)
+	}
+	if stored.Key == key {
+		t.Error("key stored in plain text")
+	}
+	if stored.UserID != 7 || stored.Scopes != "orders:read pii:read" {
+		t.Errorf("stored key = user %d scopes %q, want user 7 %q", stored.UserID, stored.Scopes, "orders:read pii:read")
+	}
+}
+
+func TestCreateAPIKeyRejectsInvalidInput(t *testing.T) {
+	r := newAPIKeyRouter(repository.NewMemoryAPIKeyRepository(), 7)
+	tests := []struct {
+		name  string
+		input CreateAPIKeyInput
+	}{
+		{"missing name", CreateAPIKeyInput{Scopes: []string{"orders:read"}}},
+		{"scope with whitespace", CreateAPIKeyInput{Name: "ci", Scopes: []string{"orders:read pii:read"}}},
+		{"empty scope", CreateAPIKeyInput{Name: "ci", Scopes: []string{""}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status, body := serveJSON(t, r, http.MethodPost, "/users/api-keys", tt.input); status != http.StatusBadRequest {
+				t.Errorf("status %d, body %v; want 400", status, body)
+			}
+		})
+	}
+}
+
+func TestDeleteAPIKey(t *testing.T) {
+	keys := repository.NewMemoryAPIKeyRepository()
+	owner := newAPIKeyRouter(keys, 7)
+	_, body := serveJSON(t, owner, http.MethodPost, "/users/api-keys", CreateAPIKeyInput{Name: "ci"})
+	path := fmt.Sprintf("/users/api-keys/%v", body["id"])
+
+	tests := []struct {
+		name   string
+		router http.Handler
+		path   string
+		want   int
+	}{
+		{"invalid id", owner, "/users/api-keys/abc", http.StatusBadRequest},
+		{"another user's key", newAPIKeyRouter(keys, 8), path, http.StatusNotFound},
+		{"own key", owner, path, http.StatusOK},
+		{"already deleted", owner, path, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status, body := serveJSON(t, tt.router, http.MethodDelete, tt.path, nil); status != tt.want {
+				t.Errorf("status %d, body %v; want %d", status, body, tt.want)
+			}
+		})
+	}
+
+	if remaining, _ := keys.ListByUser(context.Background(), 7); len(remaining) != 0 {
+		t.Errorf("%d keys left after delete", len(remaining))
+	}
+}
@@ -2,11 +2,12 @@ package handlers
 
 import (
 	"apisecurityplatform/pkg/auth"
-	"apisecurityplatform/pkg/database"
+	"apisecurityplatform/pkg/config"
 	"apisecurityplatform/pkg/models"
-	"fmt"
+	"apisecurityplatform/pkg/observability"
+	"apisecurityplatform/pkg/repository"
+	"errors"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
@@ -26,6 +27,23 @@ type LoginInput struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// AuthHandler serves the registration, login and logout endpoints
+type AuthHandler struct {
+	users    repository.UserRepository
+	keys     *auth.SigningKeys
+	tokenTTL time.Duration
+}
+
+// NewAuthHandler creates an AuthHandler backed by the given repository that
+// signs tokens with the current JWT signing key
+func NewAuthHandler(users repository.UserRepository, keys *auth.SigningKeys, jwtConfig config.JWTConfig) *AuthHandler {
+	return &AuthHandler{
+		users:    users,
+		keys:     keys,
+		tokenTTL: time.Duration(jwtConfig.ExpiryHours) * time.Hour,
+	}
+}
+
 // @Summary Register a new user
 // @Description Register a new user with username, email, and password
 // @Tags auth
@@ -35,7 +53,7 @@ type LoginInput struct {
 // @Success 201 {object} map[string]interface{} "User registered successfully"
 // @Failure 400 {object} map[string]interface{} "Invalid input"
 // @Router /auth/register [post]
-func Register(c *gin.Context) {
+func (h *AuthHandler) Register(c *gin.Context) {
 	var input RegisterInput
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -43,15 +61,16 @@ func Register(c *gin.Context) {
 		return
 	}
 
+	ctx := c.Request.Context()
+
 	// Check if email already exists
-	var existingUser models.User
-	if err := database.DB.Where("email = ?", input.Email).First(&existingUser).Error; err == nil {
+	if _, err := h.users.FindByEmail(ctx, input.Email); err == nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Email already registered"})
 		return
 	}
 
 	// Check if username already exists
-	if err := database.DB.Where("username = ?", input.Username).First(&existingUser).Error; err == nil {
+	if _, err := h.users.FindByUsername(ctx, input.Username); err == nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Username already taken"})
 		return
 	}
@@ -69,8 +88,12 @@ func Register(c *gin.Context) {
 		Password: string(hashedPassword),
 	}
 
-	result := database.DB.Create(&user)
-	if result.Error != nil {
+	if err := h.users.Create(ctx, &user); err != nil {
+		// A concurrent registration can take the email or username after the checks above
+		if errors.Is(err, repository.ErrDuplicate) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Email or username already registered"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 		return
 	}
@@ -87,15 +110,20 @@ func Register(c *gin.Context) {
 // @Success 200 {object} map[string]interface{} "Login successful with token"
 // @Failure 401 {object} map[string]interface{} "Invalid credentials"
 // @Router /auth/login [post]
-func Login(c *gin.Context) {
+func (h *AuthHandler) Login(c *gin.Context) {
 	var input LoginInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	var user models.User
-	if err := database.DB.Where("email = ?", input.Email).First(&user).Error; err != nil {
+	user, err := h.users.FindByEmail(c.Request.Context(), input.Email)
+	if err != nil {
+		outcome := auth.OutcomeInvalid
+		if !errors.Is(err, repository.ErrNotFound) {
+			outcome = auth.OutcomeError
+		}
+		observability.AuthAttempts.WithLabelValues(auth.MethodPassword, outcome).Inc()
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error": "Invalid email or password",
 		})
@@ -104,6 +132,7 @@ func Login(c *gin.Context) {
 
 	// Compare password with bcrypt
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)); err != nil {
+		observability.AuthAttempts.WithLabelValues(auth.MethodPassword, auth.OutcomeInvalid).Inc()
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error": "Invalid email or password",
 		})
@@ -111,13 +140,15 @@ func Login(c *gin.Context) {
 	}
 
 	// Generate JWT token
-	token, err := generateToken(user)
+	token, err := h.generateToken(*user)
 	if err != nil {
+		observability.AuthAttempts.WithLabelValues(auth.MethodPassword, auth.OutcomeError).Inc()
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to generate token",
 		})
 		return
 	}
+	observability.AuthAttempts.WithLabelValues(auth.MethodPassword, auth.OutcomeSuccess).Inc()
 
 	c.JSON(http.StatusOK, gin.H{
 		"token": token,
@@ -131,22 +162,16 @@ func Login(c *gin.Context) {
 	})
 }
 
-func generateToken(user models.User) (string, error) {
-	// Get JWT secret from environment variable
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "your_super_secret_key_here" // fallback default
-	}
-
+func (h *AuthHandler) generateToken(user models.User) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"user_id": user.ID,
 		"email":   user.Email,
 		"role":    user.Role,
-		"exp":     time.Now().Add(time.Hour * 72).Unix(), // 3 days expiration
+		"exp":     time.Now().Add(h.tokenTTL).Unix(),
 		"iat":     time.Now().Unix(),
 	})
 
-	tokenString, err := token.SignedString([]byte(jwtSecret))
+	tokenString, err := token.SignedString(h.keys.Current())
 	if err != nil {
 		return "", err
 	}
@@ -164,7 +189,7 @@ func generateToken(user models.User) (string, error) {
 // @Success 200 {object} map[string]string "Logout successful"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
 // @Router /auth/logout [post]
-func Logout(c *gin.Context) {
+func (h *AuthHandler) Logout(c *gin.Context) {
 	authHeader := c.GetHeader("Authorization")
 	if authHeader == "" {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
@@ -173,19 +198,8 @@ func Logout(c *gin.Context) {
 
 	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
-	// Get JWT secret with fallback default
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "your_super_secret_key_here" // fallback default
-	}
-
 	// Parse the token to get the expiration time
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(jwtSecret), nil
-	})
+	token, err := h.keys.ParseToken(tokenString)
 
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"apisecurityplatform/pkg/auth"
+	"apisecurityplatform/pkg/config"
+	"apisecurityplatform/pkg/models"
+	"apisecurityplatform/pkg/repository"
+	"context"
+	"net/http"
+	"testing"
+)
+
+func newAuthRouter(users repository.UserRepository, keys *auth.SigningKeys) http.Handler {
+	h := NewAuthHandler(users, keys, config.JWTConfig{ExpiryHours: 1})
+	r := newTestRouter(0, "")
+	r.POST("/auth/register", h.Register)
+	r.POST("/auth/login", h.Login)
+	return r
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	users := repository.NewMemoryUserRepository()
+	keys := auth.NewSigningKeys("test-secret-that-is-long-enough-to-sign")
+	r := newAuthRouter(users, keys)
+
+	status, body := serveJSON(t, r, http.MethodPost, "/auth/register",
+		RegisterInput{Username: "alice", Email: "alice@example.com", Password: "correct horse"})
+	if status != http.StatusCreated {
+		t.Fatalf("register: status %d, body %v", status, body)
+	}
+	stored, err := users.FindByEmail(context.Background(), "alice@example.com")
+	if err != nil {
+		t.Fatalf("registered user not stored: %v", err)
+	}
+	if stored.Password == "correct horse" {
+		t.Error("password stored in plain text")
+	}
+
+	status, body = serveJSON(t, r, http.MethodPost, "/auth/login",
+		LoginInput{Email: "alice@example.com", Password: "correct horse"})
+	if status != http.StatusOK {
+		t.Fatalf("login: status %d, body %v", status, body)
+	}
+	token, _ := body["token"].(string)
+	claims, err := keys.Authenticate(token)
+	if err != nil {
+		t.Fatalf("login token does not authenticate: %v", err)
+	}
+	if claims.UserID != stored.ID || claims.Email != "alice@example.com" || claims.Role != "user" {
+		t.Errorf("token claims = %+v, want user %d alice@example.com user", claims, stored.ID)
+	}
+}
+
+func TestLoginRejectsInvalidCredentials(t *testing.T) {
+	users := repository.NewMemoryUserRepository()
+	r := newAuthRouter(users, auth.NewSigningKeys("test-secret-that-is-long-enough-to-sign"))
+	serveJSON(t, r, http.MethodPost, "/auth/register",
+		RegisterInput{Username: "alice", Email: "alice@example.com", Password: "correct horse"})
+
+	tests := []struct {
+		name  string
+		input LoginInput
+	}{
+		{"wrong password", LoginInput{Email: "alice@example.com", Password: "battery staple"}},
+		{"unknown email", LoginInput{Email: "bob@example.com", Password: "correct horse"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := serveJSON(t, r, http.MethodPost, "/auth/login", tt.input)
+			if status != http.StatusUnauthorized {
+				t.Fatalf("status %d, want 401", status)
+			}
+			// Both cases answer alike, so emails cannot be enumerated
+			if body["error"] != "Invalid email or password" {
+				t.Errorf("error = %v", body["error"])
+			}
+		})
+	}
+}
+
+func TestRegisterRejectsDuplicates(t *testing.T) {
+	users := repository.NewMemoryUserRepository()
+	r := newAuthRouter(users, auth.NewSigningKeys("test-secret-that-is-long-enough-to-sign"))
+	serveJSON(t, r, http.MethodPost, "/auth/register",
+		RegisterInput{Username: "alice", Email: "alice@example.com", Password: "correct horse"})
+
+	tests := []struct {
+		name    string
+		input   RegisterInput
+		wantErr string
+	}{
+		{"email", RegisterInput{Username: "alice2", Email: "alice@example.com", Password: "correct horse"}, "Email already registered"},
+		{"username", RegisterInput{Username: "alice", Email: "alice2@example.com", Password: "correct horse"}, "Username already taken"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := serveJSON(t, r, http.MethodPost, "/auth/register", tt.input)
+			if status != http.StatusBadRequest || body["error"] != tt.wantErr {
+				t.Errorf("status %d, body %v; want 400 %q", status, body, tt.wantErr)
+			}
+		})
+	}
+}
+
+// racingUserRepository finds no user, as if another registration stored
+// one between the handler's checks and Create
+type racingUserRepository struct {
+	*repository.MemoryUserRepository
+}
+
+func (racingUserRepository) FindByEmail(context.Context, string) (*models.User, error) {
+	return nil, repository.ErrNotFound
+}
+
+func (racingUserRepository) FindByUsername(context.Context, string) (*models.User, error) {
+	return nil, repository.ErrNotFound
+}
+
+func TestRegisterMapsDuplicateFromRepository(t *testing.T) {
+	users := racingUserRepository{repository.NewMemoryUserRepository()}
+	if err := users.Create(context.Background(), &models.User{Username: "alice", Email: "alice@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	r := newAuthRouter(users, auth.NewSigningKeys("test-secret-that-is-long-enough-to-sign"))
+
+	status, body := serveJSON(t, r, http.MethodPost, "/auth/register",
+		RegisterInput{Username: "alice", Email: "alice@example.com", Password: "correct horse"})
+	if status != http.StatusBadRequest {
+		t.Errorf("status %d, body %v; want 400", status, body)
+	}
+}
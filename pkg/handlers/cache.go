@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"apisecurityplatform/pkg/audit"
+	"apisecurityplatform/pkg/cache"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PurgeCacheInput struct {
+	Route string `json:"route"`
+	Tag   string `json:"tag"`
+}
+
+// CacheHandler serves the admin endpoints managing the gateway response cache
+type CacheHandler struct {
+	cache    *cache.Cache
+	auditLog *audit.Logger
+}
+
+// NewCacheHandler creates a CacheHandler recording every purge in the audit log
+func NewCacheHandler(responseCache *cache.Cache, auditLog *audit.Logger) *CacheHandler {
+	return &CacheHandler{cache: responseCache, auditLog: auditLog}
+}
+
+// @Summary Purge cached responses
+// @Description Remove the cached responses of a gateway route, or those carrying a tag. Exactly one of route and tag must be set. Served on the admin listener.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param purge body PurgeCacheInput true "Route or tag to purge"
+// @Success 200 {object} map[string]int "Number of entries purged"
+// @Failure 400 {object} map[string]string "Invalid input"
+// @Failure 403 {object} map[string]string "Access denied"
+// @Router /admin/cache/purge [post]
+func (h *CacheHandler) PurgeCache(c *gin.Context) {
+	var input PurgeCacheInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if (input.Route == "") == (input.Tag == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Exactly one of route and tag is required"})
+		return
+	}
+
+	purged, err := h.cache.Purge(c.Request.Context(), input.Route, input.Tag)
+
+	event := audit.Event{
+		Action:  "gateway.cache.purge",
+		Actor:   fmt.Sprintf("user:%d", c.GetUint("user_id")),
+		Outcome: audit.OutcomeSuccess,
+		Details: map[string]any{"route": input.Route, "tag": input.Tag, "purged": purged},
+	}
+	if err != nil {
+		event.Outcome = audit.OutcomeFailure
+		event.Details["error"] = err.Error()
+	}
+	h.auditLog.Record(event)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to purge cache"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"purged": purged})
+}
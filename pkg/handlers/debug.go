@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"apisecurityplatform/pkg/audit"
+	"apisecurityplatform/pkg/config"
+	"apisecurityplatform/pkg/logging"
+	"apisecurityplatform/pkg/observability"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	runtimepprof "runtime/pprof"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LogLevelInput struct {
+	Level string `json:"level" binding:"required" example:"debug"`
+	// Packages replaces the package levels when set
+	Packages map[string]string `json:"packages,omitempty"`
+}
+
+type LogLevelResponse struct {
+	Level    string            `json:"level" example:"info"`
+	Packages map[string]string `json:"packages"`
+}
+
+type CaptureProfileInput struct {
+	Kind string `json:"kind" binding:"required" example:"cpu"`
+	// Duration of a CPU profile, 30s by default
+	Duration string `json:"duration" example:"30s"`
+}
+
+// DebugHandler serves runtime diagnostics of the running instance: the
+// net/http/pprof endpoints, goroutine and config dumps, the log level and
+// captured profiles
+type DebugHandler struct {
+	config   *config.Manager
+	profiler *observability.Profiler
+	auditLog *audit.Logger
+}
+
+// NewDebugHandler creates a DebugHandler recording log level changes and
+// profile captures in the audit log
+func NewDebugHandler(manager *config.Manager, profiler *observability.Profiler, auditLog *audit.Logger) *DebugHandler {
+	return &DebugHandler{config: manager, profiler: profiler, auditLog: auditLog}
+}
+
+// Pprof serves the net/http/pprof endpoints. It must be mounted at
+// /debug/pprof/*name, the path they expect.
+func (h *DebugHandler) Pprof(c *gin.Context) {
+	switch strings.TrimPrefix(c.Param("name"), "/") {
+	case "cmdline":
+		pprof.Cmdline(c.Writer, c.Request)
+	case "profile":
+		pprof.Profile(c.Writer, c.Request)
+	case "symbol":
+		pprof.Symbol(c.Writer, c.Request)
+	case "trace":
+		pprof.Trace(c.Writer, c.Request)
+	default:
+		pprof.Index(c.Writer, c.Request)
+	}
+}
+
+// @Summary Dump goroutines
+// @Description Stack traces of every goroutine, in the format of an unrecovered panic. Served on the admin listener.
+// @Tags admin
+// @Produce plain
+// @Security BearerAuth
+// @Success 200 {string} string "Goroutine stacks"
+// @Failure 403 {object} map[string]string "Access denied"
+// @Router /debug/goroutines [get]
+func (h *DebugHandler) Goroutines(c *gin.Context) {
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.Header("X-Goroutine-Count", fmt.Sprint(runtime.NumGoroutine()))
+	c.Status(http.StatusOK)
+	runtimepprof.Lookup("goroutine").WriteTo(c.Writer, 2)
+}
+
+// @Summary Dump configuration
+// @Description The configuration in effect, including reloaded changes, with secrets and the header and query values set by gateway routes redacted. Durations are in nanoseconds. Served on the admin listener.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Configuration"
+// @Failure 403 {object} map[string]string "Access denied"
+// @Router /debug/config [get]
+func (h *DebugHandler) GetConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, h.config.Current().Redacted())
+}
+
+// @Summary Get log levels
+// @Description The default log level and the levels of single packages. Served on the admin listener.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} LogLevelResponse "Log levels"
+// @Failure 403 {object} map[string]string "Access denied"
+// @Router /debug/log-level [get]
+func (h *DebugHandler) GetLogLevel(c *gin.Context) {
+	c.JSON(http.StatusOK, logLevels())
+}
+
+// @Summary Set log levels
+// @Description Change the default log level, and the package levels when given, until the next restart or configuration reload. Served on the admin listener.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param levels body LogLevelInput true "Log levels"
+// @Success 200 {object} LogLevelResponse "Log levels"
+// @Failure 400 {object} map[string]string "Invalid level"
+// @Failure 403 {object} map[string]string "Access denied"
+// @Router /debug/log-level [put]
+func (h *DebugHandler) SetLogLevel(c *gin.Context) {
+	var input LogLevelInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	previous := logLevels()
+	var err error
+	if input.Packages != nil {
+		err = logging.SetLevels(input.Level, input.Packages)
+	} else {
+		err = logging.SetLevel(input.Level)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	current := logLevels()
+	h.auditLog.Record(audit.Event{
+		Action:  "debug.log_level.set",
+		Actor:   fmt.Sprintf("user:%d", c.GetUint("user_id")),
+		Outcome: audit.OutcomeSuccess,
+		Details: map[string]any{"previous": previous, "current": current},
+	})
+	c.JSON(http.StatusOK, current)
+}
+
+func logLevels() LogLevelResponse {
+	return LogLevelResponse{
+		Level:    strings.ToLower(logging.Level().String()),
+		Packages: logging.PackageLevels(),
+	}
+}
+
+// @Summary Capture a profile
+// @Description Capture a CPU or heap profile of this instance for download. A heap profile is complete on return; a CPU profile samples for the given duration in the background. Only the latest profiles are kept. Served on the admin listener.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param profile body CaptureProfileInput true "Profile kind (cpu or heap) and duration"
+// @Success 202 {object} observability.Profile "Profile started"
+// @Failure 400 {object} map[string]string "Invalid input"
+// @Failure 403 {object} map[string]string "Access denied"
+// @Failure 409 {object} map[string]string "A CPU profile is already running"
+// @Router /debug/profiles [post]
+func (h *DebugHandler) CaptureProfile(c *gin.Context) {
+	var input CaptureProfileInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var duration time.Duration
+	if input.Duration != "" {
+		var err error
+		if duration, err = time.ParseDuration(input.Duration); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid duration"})
+			return
+		}
+	}
+
+	profile, err := h.profiler.Start(input.Kind, duration)
+	switch {
+	case errors.Is(err, observability.ErrInvalidProfileKind), errors.Is(err, observability.ErrInvalidDuration):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, observability.ErrProfileInProgress):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	event := audit.Event{
+		Action:  "debug.profile.capture",
+		Actor:   fmt.Sprintf("user:%d", c.GetUint("user_id")),
+		Outcome: audit.OutcomeSuccess,
+		Details: map[string]any{"kind": input.Kind, "profile": profile.ID, "duration": profile.Duration},
+	}
+	if err != nil {
+		event.Outcome = audit.OutcomeFailure
+		event.Details["error"] = err.Error()
+	}
+	h.auditLog.Record(event)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to capture profile"})
+		return
+	}
+	c.Header("Location", "/debug/profiles/"+profile.ID)
+	c.JSON(http.StatusAccepted, profile)
+}
+
+// @Summary List profiles
+// @Description Profiles captured on this instance, newest first. Served on the admin listener.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} observability.Profile "Profiles"
+// @Failure 403 {object} map[string]string "Access denied"
+// @Router /debug/profiles [get]
+func (h *DebugHandler) ListProfiles(c *gin.Context) {
+	c.JSON(http.StatusOK, h.profiler.List())
+}
+
+// @Summary Download a profile
+// @Description Download a completed profile in the gzipped protobuf format read by go tool pprof. Served on the admin listener.
+// @Tags admin
+// @Produce octet-stream
+// @Security BearerAuth
+// @Param id path string true "Profile ID"
+// @Success 200 {file} file "Profile"
+// @Failure 403 {object} map[string]string "Access denied"
+// @Failure 404 {object} map[string]string "Profile not found"
+// @Failure 409 {object} map[string]string "Profile is not completed"
+// @Router /debug/profiles/{id} [get]
+func (h *DebugHandler) DownloadProfile(c *gin.Context) {
+	f, profile, err := h.profiler.Open(c.Param("id"))
+	switch {
+	case errors.Is(err, observability.ErrProfileNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, observability.ErrProfileNotReady):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "status": profile.Status})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open profile"})
+		return
+	}
+	defer f.Close()
+
+	name := profile.ID + ".pprof"
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	http.ServeContent(c.Writer, c.Request, name, *profile.FinishedAt, f)
+}
@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"apisecurityplatform/pkg/audit"
+	"apisecurityplatform/pkg/config"
+	"apisecurityplatform/pkg/logging"
+	"apisecurityplatform/pkg/observability"
+	"apisecurityplatform/pkg/server"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// debugTest is a DebugHandler mounted as in cmd/main.go, recording its
+// audit events
+type debugTest struct {
+	handler  *DebugHandler
+	profiler *observability.Profiler
+	dir      string
+	audit    *bytes.Buffer
+	router   http.Handler
+}
+
+func newDebugTest(t *testing.T) *debugTest {
+	t.Helper()
+	manager, err := config.NewManager(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	dir := t.TempDir()
+	profiler, err := observability.NewProfiler(ctx, config.ProfilingConfig{Dir: dir, MaxProfiles: 10, MaxDuration: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := &debugTest{profiler: profiler, dir: dir, audit: &bytes.Buffer{}}
+	d.handler = NewDebugHandler(manager, profiler, audit.NewLogger(d.audit))
+	r := newTestRouter(1, "admin")
+	debug := r.Group("/debug")
+	{
+		debug.GET("/pprof/*name", d.handler.Pprof)
+		debug.GET("/goroutines", d.handler.Goroutines)
+		debug.GET("/config", d.handler.GetConfig)
+		debug.GET("/log-level", d.handler.GetLogLevel)
+		debug.PUT("/log-level", d.handler.SetLogLevel)
+		debug.POST("/profiles", d.handler.CaptureProfile)
+		debug.GET("/profiles", d.handler.ListProfiles)
+		debug.GET("/profiles/:id", d.handler.DownloadProfile)
+	}
+	d.router = r
+	return d
+}
+
+// events returns the audit events recorded so far
+func (d *debugTest) events(t *testing.T) []audit.Event {
+	t.Helper()
+	var events []audit.Event
+	scanner := bufio.NewScanner(bytes.NewReader(d.audit.Bytes()))
+	for scanner.Scan() {
+		var event audit.Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestPprofProfileOnAdminListener(t *testing.T) {
+	d := newDebugTest(t)
+	// A public write timeout shorter than the profile, as the 30s default is
+	// for the default 30s profile
+	public := config.ServerConfig{ReadTimeout: 5 * time.Second, WriteTimeout: 500 * time.Millisecond}
+	admin := server.NewAdmin("", d.router, public)
+	if admin.HTTP.WriteTimeout != 0 {
+		t.Fatalf("admin WriteTimeout = %s, want none", admin.HTTP.WriteTimeout)
+	}
+
+	ts := httptest.NewUnstartedServer(d.router)
+	ts.Config = admin.HTTP
+	ts.Start()
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/debug/pprof/profile?seconds=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read profile: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || len(body) == 0 {
+		t.Errorf("status %d, %d bytes: want a profile", resp.StatusCode, len(body))
+	}
+}
+
+func TestPprofIndex(t *testing.T) {
+	d := newDebugTest(t)
+	w := httptest.NewRecorder()
+	d.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "goroutine") {
+		t.Errorf("index: status %d, body %.100q", w.Code, w.Body)
+	}
+}
+
+func TestGoroutines(t *testing.T) {
+	d := newDebugTest(t)
+	w := httptest.NewRecorder()
+	d.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/goroutines", nil))
+	if w.Code != http.StatusOK || w.Header().Get("X-Goroutine-Count") == "" || !strings.Contains(w.Body.String(), "goroutine ") {
+		t.Errorf("status %d, count %q, body %.100q", w.Code, w.Header().Get("X-Goroutine-Count"), w.Body)
+	}
+}
+
+func TestGetConfigIsRedacted(t *testing.T) {
+	t.Setenv("JWT_SECRET", "debug-test-secret-value-0123456789")
+	d := newDebugTest(t)
+	w := httptest.NewRecorder()
+	d.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/config", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "debug-test-secret-value") {
+		t.Error("JWT secret in the config dump")
+	}
+}
+
+func TestSetLogLevel(t *testing.T) {
+	previous, packages := logging.Level().String(), logging.PackageLevels()
+	t.Cleanup(func() { logging.SetLevels(previous, packages) })
+	d := newDebugTest(t)
+
+	code, body := serveJSON(t, d.router, http.MethodPut, "/debug/log-level", map[string]any{"level": "verbose"})
+	if code != http.StatusBadRequest {
+		t.Errorf("invalid level: status %d, want 400: %v", code, body)
+	}
+
+	code, body = serveJSON(t, d.router, http.MethodPut, "/debug/log-level", map[string]any{
+		"level":    "warn",
+		"packages": map[string]string{"gateway": "debug"},
+	})
+	if code != http.StatusOK || body["level"] != "warn" {
+		t.Fatalf("set: status %d, body %v", code, body)
+	}
+	if code, body := serveJSON(t, d.router, http.MethodGet, "/debug/log-level", nil); code != http.StatusOK ||
+		body["level"] != "warn" || body["packages"].(map[string]any)["gateway"] != "debug" {
+		t.Errorf("get: status %d, body %v", code, body)
+	}
+
+	events := d.events(t)
+	if len(events) != 1 || events[0].Action != "debug.log_level.set" || events[0].Actor != "user:1" || events[0].Outcome != audit.OutcomeSuccess {
+		t.Errorf("audit events = %+v, want one successful log level change", events)
+	}
+}
+
+func TestCaptureProfile(t *testing.T) {
+	d := newDebugTest(t)
+
+	tests := []struct {
+		name string
+		body map[string]any
+		want int
+	}{
+		{"missing kind", map[string]any{}, http.StatusBadRequest},
+		{"invalid kind", map[string]any{"kind": "goroutine"}, http.StatusBadRequest},
+		{"invalid duration", map[string]any{"kind": "cpu", "duration": "soon"}, http.StatusBadRequest},
+		{"duration above max", map[string]any{"kind": "cpu", "duration": "2h"}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if code, body := serveJSON(t, d.router, http.MethodPost, "/debug/profiles", tt.body); code != tt.want {
+			t.Errorf("%s: status %d, want %d: %v", tt.name, code, tt.want, body)
+		}
+	}
+	if events := d.events(t); len(events) != 0 {
+		t.Errorf("rejected captures audited: %+v", events)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/debug/profiles", strings.NewReader(`{"kind":"heap"}`))
+	w := httptest.NewRecorder()
+	d.router.ServeHTTP(w, req)
+	var profile observability.Profile
+	json.Unmarshal(w.Body.Bytes(), &profile)
+	if w.Code != http.StatusAccepted || profile.Status != observability.ProfileCompleted || w.Header().Get("Location") != "/debug/profiles/"+profile.ID {
+		t.Fatalf("heap capture: status %d, Location %q, profile %+v", w.Code, w.Header().Get("Location"), profile)
+	}
+
+	// Only one CPU profile runs at a time
+	if code, body := serveJSON(t, d.router, http.MethodPost, "/debug/profiles", map[string]any{"kind": "cpu", "duration": "1h"}); code != http.StatusAccepted {
+		t.Fatalf("CPU capture: status %d: %v", code, body)
+	}
+	if code, _ := serveJSON(t, d.router, http.MethodPost, "/debug/profiles", map[string]any{"kind": "cpu"}); code != http.StatusConflict {
+		t.Errorf("second CPU capture: status %d, want 409", code)
+	}
+
+	events := d.events(t)
+	if len(events) != 2 {
+		t.Fatalf("%d audit events, want the heap and CPU captures", len(events))
+	}
+	for _, event := range events {
+		if event.Action != "debug.profile.capture" || event.Outcome != audit.OutcomeSuccess {
+			t.Errorf("audit event = %+v, want a successful capture", event)
+		}
+	}
+}
+
+func TestCaptureProfileFailure(t *testing.T) {
+	d := newDebugTest(t)
+	// Profiles can no longer be written
+	if err := os.RemoveAll(d.dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if code, body := serveJSON(t, d.router, http.MethodPost, "/debug/profiles", map[string]any{"kind": "heap"}); code != http.StatusInternalServerError {
+		t.Errorf("status %d, want 500: %v", code, body)
+	}
+	events := d.events(t)
+	if len(events) != 1 || events[0].Outcome != audit.OutcomeFailure || events[0].Details["error"] == nil {
+		t.Errorf("audit events = %+v, want one failed capture", events)
+	}
+}
+
+func TestDownloadProfile(t *testing.T) {
+	d := newDebugTest(t)
+	heap, err := d.profiler.Start(observability.ProfileHeap, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cpu, err := d.profiler.Start(observability.ProfileCPU, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	d.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/profiles", nil))
+	var profiles []observability.Profile
+	json.Unmarshal(w.Body.Bytes(), &profiles)
+	if len(profiles) != 2 || profiles[0].ID != cpu.ID || profiles[1].ID != heap.ID {
+		t.Errorf("profiles = %+v, want the CPU then the heap profile", profiles)
+	}
+
+	w = httptest.NewRecorder()
+	d.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/profiles/"+heap.ID, nil))
+	if w.Code != http.StatusOK || int64(w.Body.Len()) != heap.Size || !strings.Contains(w.Header().Get("Content-Disposition"), heap.ID+".pprof") {
+		t.Errorf("download: status %d, %d bytes, Content-Disposition %q", w.Code, w.Body.Len(), w.Header().Get("Content-Disposition"))
+	}
+
+	tests := []struct {
+		id   string
+		want int
+	}{
+		{cpu.ID, http.StatusConflict},
+		{"heap-20000101T000000.000Z", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if code, body := serveJSON(t, d.router, http.MethodGet, "/debug/profiles/"+tt.id, nil); code != tt.want {
+			t.Errorf("download %s: status %d, want %d: %v", tt.id, code, tt.want, body)
+		}
+	}
+}
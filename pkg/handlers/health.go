@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"apisecurityplatform/pkg/health"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthHandler serves the liveness and readiness probes
+type HealthHandler struct {
+	liveness  *health.Registry
+	readiness *health.Registry
+	// verbose allows the per-check breakdown, which can name dependencies
+	// and their errors; only the admin listener enables it
+	verbose bool
+}
+
+// NewHealthHandler creates a HealthHandler. With verbose set, ?verbose=true adds
+// the result of each check to the response.
+func NewHealthHandler(liveness, readiness *health.Registry, verbose bool) *HealthHandler {
+	return &HealthHandler{liveness: liveness, readiness: readiness, verbose: verbose}
+}
+
+// @Summary Liveness probe
+// @Description Reports whether the process is running and able to serve requests. It does not check dependencies, so a database outage does not get instances restarted. With ?verbose=true on the admin listener, the result of each check is included.
+// @Tags health
+// @Produce json
+// @Param verbose query bool false "Include the result of each check (admin listener only)"
+// @Success 200 {object} health.Report "Live"
+// @Failure 503 {object} health.Report "Not live"
+// @Router /livez [get]
+func (h *HealthHandler) Livez(c *gin.Context) {
+	h.respond(c, h.liveness)
+}
+
+// @Summary Readiness probe
+// @Description Reports whether the instance can take traffic: the database answers and its schema matches this release. The tracing exporter, gateway upstreams and response cache are reported as degraded without failing the probe. Results are cached for a few seconds. With ?verbose=true on the admin listener, the result of each check is included.
+// @Tags health
+// @Produce json
+// @Param verbose query bool false "Include the result of each check (admin listener only)"
+// @Success 200 {object} health.Report "Ready"
+// @Failure 503 {object} health.Report "Not ready"
+// @Router /readyz [get]
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	h.respond(c, h.readiness)
+}
+
+func (h *HealthHandler) respond(c *gin.Context, registry *health.Registry) {
+	report := registry.Run(c.Request.Context())
+
+	status := http.StatusOK
+	if !report.Up() {
+		status = http.StatusServiceUnavailable
+	}
+	// Probes are polled; never let a proxy serve a stale answer
+	c.Header("Cache-Control", "no-store")
+
+	if verbose, _ := strconv.ParseBool(c.Query("verbose")); verbose && h.verbose {
+		c.JSON(status, report)
+		return
+	}
+	c.JSON(status, gin.H{"status": report.Status})
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"apisecurityplatform/pkg/audit"
+	"apisecurityplatform/pkg/config"
+	"apisecurityplatform/pkg/gateway"
+	"apisecurityplatform/pkg/repository"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RollbackRouteInput struct {
+	Version int `json:"version" binding:"required,min=1"`
+}
+
+// RouteHandler serves the admin endpoints managing gateway routes
+type RouteHandler struct {
+	registry *gateway.Registry
+	auditLog *audit.Logger
+}
+
+// NewRouteHandler creates a RouteHandler for the given registry, recording
+// every change in the audit log
+func NewRouteHandler(registry *gateway.Registry, auditLog *audit.Logger) *RouteHandler {
+	return &RouteHandler{registry: registry, auditLog: auditLog}
+}
+
+// @Summary List gateway routes
+// @Description List every route in effect, from the config file and the registry. Served on the admin listener.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Routes"
+// @Failure 403 {object} map[string]string "Access denied"
+// @Router /admin/routes [get]
+func (h *RouteHandler) ListRoutes(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"routes": h.registry.Routes()})
+}
+
+// @Summary Get gateway route
+// @Description Get a route in effect by name. Served on the admin listener.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Route name"
+// @Success 200 {object} gateway.RouteEntry "Route"
+// @Failure 404 {object} map[string]string "Route not found"
+// @Router /admin/routes/{name} [get]
+func (h *RouteHandler) GetRoute(c *gin.Context) {
+	route, ok := h.registry.Route(c.Param("name"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Route not found"})
+		return
+	}
+	c.JSON(http.StatusOK, route)
+}
+
+// @Summary Create gateway route
+// @Description Store a new route and put it into effect. The body uses the keys of a gateway route in the config file. Served on the admin listener.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param route body config.RouteConfig true "Route definition"
+// @Success 201 {object} gateway.RouteEntry "Route created"
+// @Failure 400 {object} map[string]string "Invalid route"
+// @Failure 409 {object} map[string]string "Route already exists"
+// @Router /admin/routes [post]
+func (h *RouteHandler) CreateRoute(c *gin.Context) {
+	var route config.RouteConfig
+	if err := c.ShouldBindJSON(&route); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry, err := h.registry.Create(c.Request.Context(), route, c.GetUint("user_id"))
+	h.record(c, "gateway.route.create", route.Name, err)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, entry)
+}
+
+// @Summary Update gateway route
+// @Description Replace the definition of a stored route, recording a new version. Served on the admin listener.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Route name"
+// @Param route body config.RouteConfig true "Route definition"
+// @Success 200 {object} gateway.RouteEntry "Route updated"
+// @Failure 400 {object} map[string]string "Invalid route"
+// @Failure 404 {object} map[string]string "Route not found"
+// @Router /admin/routes/{name} [put]
+func (h *RouteHandler) UpdateRoute(c *gin.Context) {
+	var route config.RouteConfig
+	if err := c.ShouldBindJSON(&route); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	name := c.Param("name")
+	if route.Name != "" && route.Name != name {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Route name cannot be changed"})
+		return
+	}
+	route.Name = name
+
+	entry, err := h.registry.Update(c.Request.Context(), route, c.GetUint("user_id"))
+	h.record(c, "gateway.route.update", name, err)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+// @Summary Delete gateway route
+// @Description Remove a stored route. Its history is kept for rollback. Served on the admin listener.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Route name"
+// @Success 200 {object} map[string]string "Route deleted"
+// @Failure 404 {object} map[string]string "Route not found"
+// @Router /admin/routes/{name} [delete]
+func (h *RouteHandler) DeleteRoute(c *gin.Context) {
+	name := c.Param("name")
+	err := h.registry.Delete(c.Request.Context(), name, c.GetUint("user_id"))
+	h.record(c, "gateway.route.delete", name, err)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Route deleted successfully"})
+}
+
+// @Summary List gateway route versions
+// @Description List every recorded version of a stored route. Served on the admin listener.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Route name"
+// @Success 200 {object} map[string]interface{} "Versions"
+// @Failure 404 {object} map[string]string "Route not found"
+// @Router /admin/routes/{name}/versions [get]
+func (h *RouteHandler) ListRouteVersions(c *gin.Context) {
+	versions, err := h.registry.Versions(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	result := make([]gin.H, 0, len(versions))
+	for _, v := range versions {
+		result = append(result, gin.H{
+			"version":    v.Version,
+			"action":     v.Action,
+			"definition": json.RawMessage(v.Definition),
+			"changed_by": v.ChangedBy,
+			"created_at": v.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"versions": result})
+}
+
+// @Summary Roll back gateway route
+// @Description Restore the definition a route had at an earlier version, recorded as a new version. Served on the admin listener.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Route name"
+// @Param rollback body RollbackRouteInput true "Version to restore"
+// @Success 200 {object} gateway.RouteEntry "Route rolled back"
+// @Failure 400 {object} map[string]string "Invalid input"
+// @Failure 404 {object} map[string]string "Version not found"
+// @Router /admin/routes/{name}/rollback [post]
+func (h *RouteHandler) RollbackRoute(c *gin.Context) {
+	var input RollbackRouteInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	name := c.Param("name")
+
+	entry, err := h.registry.Rollback(c.Request.Context(), name, input.Version, c.GetUint("user_id"))
+	h.record(c, "gateway.route.rollback", name, err)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+func (h *RouteHandler) respondError(c *gin.Context, err error) {
+	var invalid *gateway.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, gateway.ErrStaticRoute):
+		c.JSON(http.StatusConflict, gin.H{"error": "Route is defined in the config file and cannot be changed here"})
+	case errors.Is(err, repository.ErrDuplicate):
+		c.JSON(http.StatusConflict, gin.H{"error": "Route already exists"})
+	case errors.Is(err, repository.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Route not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update routes"})
+	}
+}
+
+// record writes a route change to the audit log
+func (h *RouteHandler) record(c *gin.Context, action, route string, err error) {
+	event := audit.Event{
+		Action:  action,
+		Actor:   fmt.Sprintf("user:%d", c.GetUint("user_id")),
+		Outcome: audit.OutcomeSuccess,
+		Details: map[string]any{"route": route},
+	}
+	if err != nil {
+		event.Outcome = audit.OutcomeFailure
+		event.Details["error"] = err.Error()
+	}
+	h.auditLog.Record(event)
+}
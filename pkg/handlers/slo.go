@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"apisecurityplatform/pkg/slo"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oasdiff/yaml"
+)
+
+// SLOHandler serves the state of the configured SLOs and the Prometheus rules
+// and Grafana dashboard generated from them
+type SLOHandler struct {
+	tracker *slo.Tracker
+}
+
+// NewSLOHandler creates an SLOHandler for the objectives of tracker
+func NewSLOHandler(tracker *slo.Tracker) *SLOHandler {
+	return &SLOHandler{tracker: tracker}
+}
+
+// @Summary Get SLO status
+// @Description Error budget consumption of each configured SLO, computed from the http_requests_total and http_server_duration_seconds series of this instance since it started. Served on the admin listener.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} slo.Report "Error budgets"
+// @Failure 403 {object} map[string]string "Access denied"
+// @Router /admin/slo [get]
+func (h *SLOHandler) GetStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, h.tracker.Report())
+}
+
+// @Summary Get SLO alerting rules
+// @Description Prometheus rules file recording each SLO's error ratios and alerting on multi-window error budget burn. Served on the admin listener.
+// @Tags admin
+// @Produce application/yaml
+// @Security BearerAuth
+// @Success 200 {string} string "Prometheus rules file"
+// @Failure 403 {object} map[string]string "Access denied"
+// @Router /admin/slo/rules [get]
+func (h *SLOHandler) GetRules(c *gin.Context) {
+	data, err := yaml.Marshal(h.tracker.Rules())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate rules"})
+		return
+	}
+	c.Data(http.StatusOK, "application/yaml", data)
+}
+
+// @Summary Get SLO dashboard
+// @Description Grafana dashboard charting each SLO's error budget, burn rate and SLIs from the series recorded by the generated rules. Served on the admin listener.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Grafana dashboard"
+// @Failure 403 {object} map[string]string "Access denied"
+// @Router /admin/slo/dashboard [get]
+func (h *SLOHandler) GetDashboard(c *gin.Context) {
+	c.JSON(http.StatusOK, h.tracker.Dashboard())
+}
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"apisecurityplatform/pkg/models"
+	"apisecurityplatform/pkg/repository"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultUsageWindow is the period reported when from is not given
+	defaultUsageWindow = 24 * time.Hour
+	// maxUsageWindow bounds the period a single query may cover
+	maxUsageWindow = 366 * 24 * time.Hour
+)
+
+// UsageEntry is the metered usage of one caller and route within a period
+type UsageEntry struct {
+	Period       time.Time `json:"period"`
+	UserID       uint      `json:"user_id"`
+	APIKeyID     uint      `json:"api_key_id,omitempty"`
+	Route        string    `json:"route"`
+	Requests     int64     `json:"requests"`
+	ClientErrors int64     `json:"client_errors"`
+	ServerErrors int64     `json:"server_errors"`
+	BytesIn      int64     `json:"bytes_in"`
+	BytesOut     int64     `json:"bytes_out"`
+}
+
+// UsageTotals sums the entries of a UsageResponse
+type UsageTotals struct {
+	Requests     int64 `json:"requests"`
+	ClientErrors int64 `json:"client_errors"`
+	ServerErrors int64 `json:"server_errors"`
+	BytesIn      int64 `json:"bytes_in"`
+	BytesOut     int64 `json:"bytes_out"`
+}
+
+// UsageResponse is the usage matching a query
+type UsageResponse struct {
+	From        time.Time    `json:"from"`
+	To          time.Time    `json:"to"`
+	Granularity string       `json:"granularity"`
+	Usage       []UsageEntry `json:"usage"`
+	Totals      UsageTotals  `json:"totals"`
+}
+
+// UsageHandler serves the metered usage of users and their API keys
+type UsageHandler struct {
+	usage repository.UsageRepository
+}
+
+// NewUsageHandler creates a UsageHandler backed by the given repository
+func NewUsageHandler(usage repository.UsageRepository) *UsageHandler {
+	return &UsageHandler{usage: usage}
+}
+
+// @Summary Get own usage
+// @Description Requests, errors and bytes metered for the authenticated user, per route and API key, in hourly or daily periods. Usage is written in batches, so the latest requests may take up to the flush interval to appear.
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param from query string false "Start of the period, RFC 3339 (default 24 hours before to)"
+// @Param to query string false "End of the period, RFC 3339 (default now)"
+// @Param granularity query string false "hour or day (default hour)"
+// @Param api_key_id query int false "Only usage made with this API key"
+// @Param route query string false "Only usage of this route"
+// @Success 200 {object} UsageResponse "Usage"
+// @Failure 400 {object} map[string]string "Invalid query"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Router /users/usage [get]
+func (h *UsageHandler) GetMyUsage(c *gin.Context) {
+	filter, ok := usageFilter(c)
+	if !ok {
+		return
+	}
+	filter.UserID = c.GetUint("user_id")
+	h.respond(c, filter)
+}
+
+// @Summary Get usage
+// @Description Requests, errors and bytes metered per user, API key and route, in hourly or daily periods. Usage is written in batches, so the latest requests may take up to the flush interval to appear. Served on the admin listener.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param from query string false "Start of the period, RFC 3339 (default 24 hours before to)"
+// @Param to query string false "End of the period, RFC 3339 (default now)"
+// @Param granularity query string false "hour or day (default hour)"
+// @Param user_id query int false "Only usage of this user"
+// @Param api_key_id query int false "Only usage made with this API key"
+// @Param route query string false "Only usage of this route"
+// @Success 200 {object} UsageResponse "Usage"
+// @Failure 400 {object} map[string]string "Invalid query"
+// @Failure 403 {object} map[string]string "Access denied"
+// @Router /admin/usage [get]
+func (h *UsageHandler) GetUsage(c *gin.Context) {
+	filter, ok := usageFilter(c)
+	if !ok {
+		return
+	}
+	if value := c.Query("user_id"); value != "" {
+		userID, err := strconv.ParseUint(value, 10, 0)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user_id"})
+			return
+		}
+		filter.UserID = uint(userID)
+	}
+	h.respond(c, filter)
+}
+
+func (h *UsageHandler) respond(c *gin.Context, filter repository.UsageFilter) {
+	granularity := c.DefaultQuery("granularity", "hour")
+	var period time.Duration
+	switch granularity {
+	case "hour":
+		period = time.Hour
+	case "day":
+		period = 24 * time.Hour
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "granularity must be hour or day"})
+		return
+	}
+
+	records, err := h.usage.Query(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch usage"})
+		return
+	}
+
+	response := UsageResponse{From: filter.From, To: filter.To, Granularity: granularity, Usage: []UsageEntry{}}
+	// Records are ordered by period, so merging into the last entries of
+	// the same period keeps the response ordered too
+	index := make(map[models.UsageRecord]int)
+	for _, record := range records {
+		key := models.UsageRecord{Period: record.Period.Truncate(period), UserID: record.UserID, APIKeyID: record.APIKeyID, Route: record.Route}
+		i, ok := index[key]
+		if !ok {
+			i = len(response.Usage)
+			index[key] = i
+			response.Usage = append(response.Usage, UsageEntry{Period: key.Period, UserID: key.UserID, APIKeyID: key.APIKeyID, Route: key.Route})
+		}
+		entry := &response.Usage[i]
+		entry.Requests += record.Requests
+		entry.ClientErrors += record.ClientErrors
+		entry.ServerErrors += record.ServerErrors
+		entry.BytesIn += record.BytesIn
+		entry.BytesOut += record.BytesOut
+
+		response.Totals.Requests += record.Requests
+		response.Totals.ClientErrors += record.ClientErrors
+		response.Totals.ServerErrors += record.ServerErrors
+		response.Totals.BytesIn += record.BytesIn
+		response.Totals.BytesOut += record.BytesOut
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// usageFilter reads the filters shared by the usage endpoints, responding
+// with 400 and reporting false when they are invalid
+func usageFilter(c *gin.Context) (repository.UsageFilter, bool) {
+	filter := repository.UsageFilter{Route: c.Query("route"), To: time.Now().UTC()}
+	var err error
+	if value := c.Query("to"); value != "" {
+		if filter.To, err = time.Parse(time.RFC3339, value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be an RFC 3339 time"})
+			return filter, false
+		}
+	}
+	filter.From = filter.To.Add(-defaultUsageWindow)
+	if value := c.Query("from"); value != "" {
+		if filter.From, err = time.Parse(time.RFC3339, value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be an RFC 3339 time"})
+			return filter, false
+		}
+	}
+	if !filter.From.Before(filter.To) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return filter, false
+	}
+	if filter.To.Sub(filter.From) > maxUsageWindow {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The period must not exceed 366 days"})
+		return filter, false
+	}
+	if value := c.Query("api_key_id"); value != "" {
+		keyID, err := strconv.ParseUint(value, 10, 0)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid api_key_id"})
+			return filter, false
+		}
+		filter.APIKeyID = uint(keyID)
+	}
+	return filter, true
+}
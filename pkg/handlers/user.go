@@ -1,13 +1,24 @@
 package handlers
 
 import (
-	"apisecurityplatform/pkg/database"
-	"apisecurityplatform/pkg/models"
+	"apisecurityplatform/pkg/repository"
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
+// UserHandler serves the user profile and administration endpoints
+type UserHandler struct {
+	users repository.UserRepository
+}
+
+// NewUserHandler creates a UserHandler backed by the given repository
+func NewUserHandler(users repository.UserRepository) *UserHandler {
+	return &UserHandler{users: users}
+}
+
 // @Summary Get user profile
 // @Description Get the profile of the authenticated user
 // @Tags users
@@ -17,7 +28,7 @@ import (
 // @Success 200 {object} map[string]interface{} "User profile"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
 // @Router /users/me [get]
-func GetUserProfile(c *gin.Context) {
+func (h *UserHandler) GetUserProfile(c *gin.Context) {
 	// Get user ID from context (set by AuthMiddleware)
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -25,8 +36,8 @@ func GetUserProfile(c *gin.Context) {
 		return
 	}
 
-	var user models.User
-	if err := database.DB.First(&user, userID).Error; err != nil {
+	user, err := h.users.FindByID(c.Request.Context(), userID.(uint))
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
@@ -53,7 +64,7 @@ func GetUserProfile(c *gin.Context) {
 // @Failure 403 {object} map[string]string "Access denied"
 // @Failure 404 {object} map[string]string "User not found"
 // @Router /users/{id} [delete]
-func DeleteUser(c *gin.Context) {
+func (h *UserHandler) DeleteUser(c *gin.Context) {
 	// Get authenticated user's role from context
 	role, exists := c.Get("role")
 	if !exists || role.(string) != "admin" {
@@ -62,18 +73,20 @@ func DeleteUser(c *gin.Context) {
 	}
 
 	// Get user ID from URL parameter
-	userID := c.Param("id")
-
-	// Attempt to delete the user
-	result := database.DB.Delete(&models.User{}, userID)
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
-	// Check if user was found and deleted
-	if result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	// Attempt to delete the user
+	if err := h.users.Delete(c.Request.Context(), uint(userID)); err != nil {
+		// Check if user was found and deleted
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
 		return
 	}
 
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"apisecurityplatform/pkg/models"
+	"apisecurityplatform/pkg/repository"
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestGetUserProfile(t *testing.T) {
+	users := repository.NewMemoryUserRepository()
+	user := models.User{Username: "alice", Email: "alice@example.com", Password: "hash"}
+	if err := users.Create(context.Background(), &user); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		userID uint
+		want   int
+	}{
+		{"authenticated", user.ID, http.StatusOK},
+		{"deleted user", user.ID + 1, http.StatusNotFound},
+		{"anonymous", 0, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRouter(tt.userID, "user")
+			r.GET("/users/me", NewUserHandler(users).GetUserProfile)
+			status, body := serveJSON(t, r, http.MethodGet, "/users/me", nil)
+			if status != tt.want {
+				t.Fatalf("status %d, body %v; want %d", status, body, tt.want)
+			}
+			if status == http.StatusOK {
+				profile, _ := body["user"].(map[string]any)
+				if profile["email"] != "alice@example.com" || profile["password"] != nil {
+					t.Errorf("profile = %v", profile)
+				}
+			}
+		})
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	users := repository.NewMemoryUserRepository()
+	user := models.User{Username: "alice", Email: "alice@example.com"}
+	if err := users.Create(context.Background(), &user); err != nil {
+		t.Fatal(err)
+	}
+	path := fmt.Sprintf("/users/%d", user.ID)
+
+	tests := []struct {
+		name string
+		role string
+		path string
+		want int
+	}{
+		{"not an admin", "user", path, http.StatusForbidden},
+		{"invalid id", "admin", "/users/abc", http.StatusBadRequest},
+		{"admin", "admin", path, http.StatusOK},
+		{"not found", "admin", path, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRouter(1, tt.role)
+			r.DELETE("/users/:id", NewUserHandler(users).DeleteUser)
+			if status, body := serveJSON(t, r, http.MethodDelete, tt.path, nil); status != tt.want {
+				t.Errorf("status %d, body %v; want %d", status, body, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+// Package health runs the dependency checks behind the liveness and readiness
+// probes. Checks run concurrently, each under its own timeout, and their
+// results are cached so frequent probes from several sources do not turn into
+// a stream of database pings.
+package health
+
+import (
+	"apisecurityplatform/pkg/logging"
+	"apisecurityplatform/pkg/observability"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var logger = logging.For("health")
+
+// Statuses of a check and of a report
+const (
+	StatusUp = "up"
+	// StatusDegraded is reported when only non-critical checks fail
+	StatusDegraded = "degraded"
+	StatusDown     = "down"
+)
+
+// Defaults for checkers that leave Timeout or CacheFor unset
+const (
+	DefaultTimeout  = 2 * time.Second
+	DefaultCacheFor = 5 * time.Second
+)
+
+// Checker is a single dependency check
+type Checker struct {
+	Name string
+	// Check returns nil when the dependency is usable. It must honour ctx,
+	// which is cancelled after Timeout.
+	Check func(ctx context.Context) error
+	// Timeout bounds a single run; DefaultTimeout when zero
+	Timeout time.Duration
+	// CacheFor is how long a result is reused; DefaultCacheFor when zero,
+	// and every probe runs the check when negative
+	CacheFor time.Duration
+	// Critical checks take the report down when they fail; others only
+	// degrade it
+	Critical bool
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Name       string    `json:"name"`
+	Status     string    `json:"status"`
+	Critical   bool      `json:"critical"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs float64   `json:"duration_ms"`
+	CheckedAt  time.Time `json:"checked_at"`
+	// Cached is set when the result was reused from an earlier run
+	Cached bool `json:"cached"`
+}
+
+// Report is the combined outcome of a registry's checks
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+// Up reports whether every critical check passed
+func (r Report) Up() bool {
+	return r.Status != StatusDown
+}
+
+// Registry holds the checks of one probe
+type Registry struct {
+	name string
+
+	mu     sync.RWMutex
+	checks []*check
+}
+
+// NewRegistry creates an empty registry; name labels its metrics and logs
+func NewRegistry(name string) *Registry {
+	return &Registry{name: name}
+}
+
+// Register adds a check. Names must be unique within the registry.
+func (r *Registry) Register(checker Checker) error {
+	if checker.Name == "" || checker.Check == nil {
+		return errors.New("health check needs a name and a check function")
+	}
+	if checker.Timeout <= 0 {
+		checker.Timeout = DefaultTimeout
+	}
+	if checker.CacheFor == 0 {
+		checker.CacheFor = DefaultCacheFor
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range r.checks {
+		if c.Name == checker.Name {
+			return fmt.Errorf("health check %q is already registered", checker.Name)
+		}
+	}
+	r.checks = append(r.checks, &check{Checker: checker, probe: r.name})
+	return nil
+}
+
+// Run runs every check, reusing results that are still cached, and reports
+// down if a critical check failed. A registry without checks is up.
+func (r *Registry) Run(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := r.checks
+	r.mu.RUnlock()
+
+	report := Report{Status: StatusUp, Checks: make([]Result, len(checks))}
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c *check) {
+			defer wg.Done()
+			report.Checks[i] = c.result(ctx)
+		}(i, c)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status == StatusUp {
+			continue
+		}
+		if result.Critical {
+			report.Status = StatusDown
+			break
+		}
+		report.Status = StatusDegraded
+	}
+	return report
+}
+
+// check is a registered Checker with its cached result
+type check struct {
+	Checker
+	probe string
+
+	// mu is held while the check runs, so concurrent probes wait for one
+	// run and share its result
+	mu      sync.Mutex
+	last    Result
+	expires time.Time
+	ran     bool
+}
+
+func (c *check) result(ctx context.Context) Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ran && time.Now().Before(c.expires) {
+		cached := c.last
+		cached.Cached = true
+		return cached
+	}
+
+	// Only transitions are logged; a check starts out presumed up
+	previous := StatusUp
+	if c.ran {
+		previous = c.last.Status
+	}
+	result := c.run(ctx)
+	switch {
+	case result.Status == previous:
+	case result.Status == StatusUp:
+		logger.Info("Health check recovered", "probe", c.probe, "check", c.Name)
+	default:
+		logger.Warn("Health check failing", "probe", c.probe, "check", c.Name,
+			"critical", c.Critical, "error", result.Error)
+	}
+
+	c.last, c.ran = result, true
+	c.expires = result.CheckedAt.Add(c.CacheFor)
+	return result
+}
+
+func (c *check) run(ctx context.Context) Result {
+	// The probe request may be cancelled; the result is cached for others
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	errs := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				errs <- fmt.Errorf("check panicked: %v", p)
+			}
+		}()
+		errs <- c.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errs:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %s", c.Timeout)
+	}
+	duration := time.Since(start)
+
+	result := Result{
+		Name:       c.Name,
+		Status:     StatusUp,
+		Critical:   c.Critical,
+		DurationMs: float64(duration.Microseconds()) / 1000,
+		CheckedAt:  start,
+	}
+	up := 1.0
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+		up = 0
+	}
+	observability.HealthCheckUp.WithLabelValues(c.probe, c.Name).Set(up)
+	observability.HealthCheckDuration.WithLabelValues(c.probe, c.Name).Observe(duration.Seconds())
+	return result
+}
@@ -0,0 +1,58 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// requestFields holds the identity of a request once it is authenticated.
+// The auth middlewares fill it in after the request context was created.
+type requestFields struct {
+	userID   atomic.Uint64
+	apiKeyID atomic.Uint64
+}
+
+type fieldsKey struct{}
+
+// NewContext prepares ctx to carry the caller's identity into the records
+// logged with it
+func NewContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, fieldsKey{}, &requestFields{})
+}
+
+// SetIdentity records the authenticated caller of the request with ctx. A
+// zero ID is left out.
+func SetIdentity(ctx context.Context, userID, apiKeyID uint) {
+	fields, ok := ctx.Value(fieldsKey{}).(*requestFields)
+	if !ok {
+		return
+	}
+	fields.userID.Store(uint64(userID))
+	fields.apiKeyID.Store(uint64(apiKeyID))
+}
+
+// contextAttrs returns the trace and identity attributes found in ctx
+func contextAttrs(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	var attrs []slog.Attr
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		attrs = append(attrs,
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
+	if fields, ok := ctx.Value(fieldsKey{}).(*requestFields); ok {
+		if id := fields.userID.Load(); id != 0 {
+			attrs = append(attrs, slog.Uint64("user_id", id))
+		}
+		if id := fields.apiKeyID.Load(); id != 0 {
+			attrs = append(attrs, slog.Uint64("api_key_id", id))
+		}
+	}
+	return attrs
+}
@@ -0,0 +1,146 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// level is shared by every logger without a package level, so the log
+// level can change at runtime
+var level = new(slog.LevelVar)
+
+// packageLevels overrides level for the loggers of single packages
+var packageLevels atomic.Pointer[map[string]slog.Level]
+
+// output formats the records of every logger. It is replaced by Setup.
+var output atomic.Pointer[slog.Handler]
+
+func init() {
+	packageLevels.Store(&map[string]slog.Level{})
+	setOutput(os.Stderr)
+}
+
+func setOutput(w io.Writer) {
+	var h slog.Handler = slog.NewJSONHandler(w, &slog.HandlerOptions{
+		// Each logger filters by its own level
+		Level:       slog.LevelDebug,
+		ReplaceAttr: redact,
+	})
+	output.Store(&h)
+}
+
+// Setup installs JSON logging at the given levels as the default logger.
+// Output from the standard log package is routed through the same handler.
+func Setup(lvl string, packages map[string]string) error {
+	if err := SetLevels(lvl, packages); err != nil {
+		return err
+	}
+	setOutput(os.Stderr)
+	slog.SetDefault(For(""))
+	return nil
+}
+
+// SetLevel changes the minimum level of loggers without a package level
+func SetLevel(lvl string) error {
+	l, err := parseLevel(lvl)
+	if err != nil {
+		return err
+	}
+	level.Set(l)
+	return nil
+}
+
+// SetLevels changes the default level and replaces every package level
+func SetLevels(lvl string, packages map[string]string) error {
+	levels := make(map[string]slog.Level, len(packages))
+	for pkg, pkgLevel := range packages {
+		l, err := parseLevel(pkgLevel)
+		if err != nil {
+			return fmt.Errorf("package %s: %w", pkg, err)
+		}
+		levels[strings.ToLower(pkg)] = l
+	}
+	if err := SetLevel(lvl); err != nil {
+		return err
+	}
+	packageLevels.Store(&levels)
+	return nil
+}
+
+func parseLevel(lvl string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.ToLower(lvl))); err != nil {
+		return l, fmt.Errorf("invalid log level %q: %w", lvl, err)
+	}
+	return l, nil
+}
+
+// Level returns the current minimum level of loggers without a package level
+func Level() slog.Level {
+	return level.Level()
+}
+
+// PackageLevels returns the levels set for single packages
+func PackageLevels() map[string]string {
+	levels := make(map[string]string)
+	for pkg, l := range *packageLevels.Load() {
+		levels[pkg] = strings.ToLower(l.String())
+	}
+	return levels
+}
+
+// For returns the logger of a package. Its records carry the package name,
+// and its level can be set apart from the default one. Loggers can be
+// created before Setup, as package variables.
+func For(pkg string) *slog.Logger {
+	return slog.New(&handler{pkg: pkg})
+}
+
+// handler applies the level of its package and writes through the current
+// output, adding the request attributes found in the context
+type handler struct {
+	pkg string
+	// ops replays WithAttrs and WithGroup calls on the output
+	ops []func(slog.Handler) slog.Handler
+}
+
+func (h *handler) Enabled(_ context.Context, l slog.Level) bool {
+	if pkgLevel, ok := (*packageLevels.Load())[h.pkg]; ok {
+		return l >= pkgLevel
+	}
+	return l >= level.Level()
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	attrs := contextAttrs(ctx)
+	if h.pkg != "" {
+		attrs = append([]slog.Attr{slog.String("package", h.pkg)}, attrs...)
+	}
+	out := *output.Load()
+	if len(attrs) > 0 {
+		out = out.WithAttrs(attrs)
+	}
+	for _, op := range h.ops {
+		out = op(out)
+	}
+	return out.Handle(ctx, r)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(out slog.Handler) slog.Handler { return out.WithAttrs(attrs) })
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return h.with(func(out slog.Handler) slog.Handler { return out.WithGroup(name) })
+}
+
+func (h *handler) with(op func(slog.Handler) slog.Handler) *handler {
+	ops := make([]func(slog.Handler) slog.Handler, len(h.ops), len(h.ops)+1)
+	copy(ops, h.ops)
+	return &handler{pkg: h.pkg, ops: append(ops, op)}
+}
@@ -0,0 +1,88 @@
+package logging
+
+import (
+	"log/slog"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute keys whose values are never logged, compared
+// in lower case without separators
+var sensitiveKeys = map[string]bool{
+	"authorization":      true,
+	"proxyauthorization": true,
+	"cookie":             true,
+	"setcookie":          true,
+	"apikey":             true,
+	"xapikey":            true,
+	"email":              true,
+}
+
+// sensitivePatterns find credentials and emails inside logged strings, such
+// as error messages and URLs
+var sensitivePatterns = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]+=*`), "Bearer " + redacted},
+	{regexp.MustCompile(`\beyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`), redacted},
+	{regexp.MustCompile(`(?i)\b(access_token|api_key|apikey|token|password)=[^&\s"]+`), "${1}=" + redacted},
+	{regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`), redacted},
+}
+
+// redact is the ReplaceAttr function of the output. It drops the values of
+// sensitive keys and masks credentials and emails in strings and errors.
+func redact(_ []string, a slog.Attr) slog.Attr {
+	if sensitiveKey(a.Key) {
+		return slog.String(a.Key, redacted)
+	}
+	switch a.Value.Kind() {
+	case slog.KindString:
+		a.Value = slog.StringValue(scrub(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			a.Value = slog.StringValue(scrub(err.Error()))
+		}
+	}
+	return a
+}
+
+func sensitiveKey(key string) bool {
+	key = strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(key))
+	return sensitiveKeys[key] ||
+		strings.HasSuffix(key, "password") ||
+		strings.HasSuffix(key, "secret") ||
+		strings.HasSuffix(key, "token")
+}
+
+// RedactQuery masks the values of sensitive parameters in a raw query string,
+// such as access_token or api_key, keeping the names and order of all of them
+func RedactQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	params := strings.Split(rawQuery, "&")
+	for i, param := range params {
+		rawName, _, _ := strings.Cut(param, "=")
+		name, err := url.QueryUnescape(rawName)
+		if err != nil {
+			name = rawName
+		}
+		if sensitiveKey(name) {
+			params[i] = rawName + "=" + redacted
+			continue
+		}
+		params[i] = scrub(param)
+	}
+	return strings.Join(params, "&")
+}
+
+func scrub(s string) string {
+	for _, p := range sensitivePatterns {
+		s = p.pattern.ReplaceAllString(s, p.replacement)
+	}
+	return s
+}
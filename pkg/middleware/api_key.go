@@ -1,61 +1,34 @@
 package middleware
 
 import (
-	"apisecurityplatform/pkg/database"
-	"apisecurityplatform/pkg/models"
-	"apisecurityplatform/pkg/observability"
-	"fmt"
+	"apisecurityplatform/pkg/auth"
+	"apisecurityplatform/pkg/logging"
+	"apisecurityplatform/pkg/repository"
+	"errors"
 	"net/http"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
 )
 
-func APIKeyAuth() gin.HandlerFunc {
+func APIKeyAuth(keys repository.APIKeyRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		apiKey := c.GetHeader("X-API-Key")
-		if apiKey == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "API key is required"})
-			c.Abort()
-			return
-		}
-
-		var storedKey models.APIKey
-		// Get all API keys and compare with bcrypt
-		var apiKeys []models.APIKey
-		if err := database.DB.Find(&apiKeys).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate API key"})
-			c.Abort()
-			return
-		}
-
-		// Find matching API key
-		keyFound := false
-		for _, key := range apiKeys {
-			if err := bcrypt.CompareHashAndPassword([]byte(key.Key), []byte(apiKey)); err == nil {
-				storedKey = key
-				keyFound = true
-				break
+		storedKey, err := auth.AuthenticateAPIKey(c.Request.Context(), keys, c.GetHeader("X-API-Key"))
+		auth.RecordAttempt(c.Request.Context(), auth.MethodAPIKey, err)
+		if err != nil {
+			if errors.Is(err, auth.ErrMissingAPIKey) || errors.Is(err, auth.ErrInvalidAPIKey) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate API key"})
 			}
-		}
-
-		if !keyFound {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
 			c.Abort()
 			return
 		}
 
-		// Update last used timestamp
-		now := time.Now().Unix()
-		storedKey.LastUsedAt = &now
-		database.DB.Save(&storedKey)
-
-		// Increment API key usage metric
-		observability.APIKeyUsage.WithLabelValues(fmt.Sprintf("%d", storedKey.ID)).Inc()
-
+		c.Set("auth_method", auth.MethodAPIKey)
 		c.Set("api_key_id", storedKey.ID)
 		c.Set("user_id", storedKey.UserID)
+		c.Set("scopes", storedKey.ScopeList())
+		logging.SetIdentity(c.Request.Context(), storedKey.UserID, storedKey.ID)
 		c.Next()
 	}
 }
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"apisecurityplatform/pkg/auth"
+	"apisecurityplatform/pkg/certs"
+	"apisecurityplatform/pkg/logging"
+	"apisecurityplatform/pkg/observability"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClientCertAuth authenticates requests carrying a verified client certificate
+// that maps to a known identity. Requests without a client certificate are
+// handed to fallback, so certificates can sit alongside APIKeyAuth.
+func ClientCertAuth(identities *certs.IdentityMapper, fallback gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		state := c.Request.TLS
+		if state == nil || len(state.PeerCertificates) == 0 {
+			fallback(c)
+			return
+		}
+
+		// The TLS layer only fills VerifiedChains when the chain checks out
+		// against the client CA bundle
+		if len(state.VerifiedChains) == 0 {
+			observability.AuthAttempts.WithLabelValues(auth.MethodClientCert, auth.OutcomeInvalid).Inc()
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Client certificate is not trusted"})
+			c.Abort()
+			return
+		}
+
+		identity, ok := identities.Lookup(state.VerifiedChains[0][0])
+		if !ok {
+			observability.AuthAttempts.WithLabelValues(auth.MethodClientCert, auth.OutcomeInvalid).Inc()
+			c.JSON(http.StatusForbidden, gin.H{"error": "Client certificate is not mapped to an identity"})
+			c.Abort()
+			return
+		}
+
+		observability.AuthAttempts.WithLabelValues(auth.MethodClientCert, auth.OutcomeSuccess).Inc()
+		c.Set("auth_method", auth.MethodClientCert)
+		c.Set("client_identity", identity.Name)
+		if identity.UserID != 0 {
+			c.Set("user_id", identity.UserID)
+		}
+		logging.SetIdentity(c.Request.Context(), identity.UserID, 0)
+		c.Next()
+	}
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"apisecurityplatform/pkg/certs"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestClientCertAuth(t *testing.T) {
+	identities := certs.NewIdentityMapper([]certs.IdentityRule{
+		{Match: "billing.internal", Identity: certs.Identity{Name: "billing", UserID: 7}},
+		{Match: "reports", Identity: certs.Identity{Name: "reports"}},
+	})
+	mapped := &x509.Certificate{DNSNames: []string{"billing.internal"}}
+	byCommonName := &x509.Certificate{Subject: pkix.Name{CommonName: "reports"}}
+	unmapped := &x509.Certificate{DNSNames: []string{"unknown.internal"}}
+
+	tests := []struct {
+		name         string
+		state        *tls.ConnectionState
+		wantStatus   int
+		wantIdentity string
+		wantUserID   any
+	}{
+		{"plain HTTP falls back", nil, http.StatusTeapot, "", nil},
+		{"no client certificate falls back", &tls.ConnectionState{}, http.StatusTeapot, "", nil},
+		{"untrusted certificate", &tls.ConnectionState{PeerCertificates: []*x509.Certificate{mapped}}, http.StatusUnauthorized, "", nil},
+		{"unmapped certificate", &tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{unmapped},
+			VerifiedChains:   [][]*x509.Certificate{{unmapped}},
+		}, http.StatusForbidden, "", nil},
+		{"identity from the SAN", &tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{mapped},
+			VerifiedChains:   [][]*x509.Certificate{{mapped}},
+		}, http.StatusOK, "billing", uint(7)},
+		{"identity from the common name", &tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{byCommonName},
+			VerifiedChains:   [][]*x509.Certificate{{byCommonName}},
+		}, http.StatusOK, "reports", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var identity string
+			var userID any
+			r := gin.New()
+			fallback := func(c *gin.Context) { c.AbortWithStatus(http.StatusTeapot) }
+			r.GET("/", ClientCertAuth(identities, fallback), func(c *gin.Context) {
+				identity = c.GetString("client_identity")
+				userID, _ = c.Get("user_id")
+				if c.GetString("auth_method") == "" {
+					t.Error("auth_method not set")
+				}
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.TLS = tt.state
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if identity != tt.wantIdentity || userID != tt.wantUserID {
+				t.Errorf("identity %q, user %v; want %q, %v", identity, userID, tt.wantIdentity, tt.wantUserID)
+			}
+		})
+	}
+}
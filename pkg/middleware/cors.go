@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+type corsOrigins struct {
+	any     bool
+	allowed map[string]struct{}
+}
+
+// CORS answers cross-origin requests from a set of allowed origins that can
+// be replaced while the server is running
+type CORS struct {
+	origins atomic.Pointer[corsOrigins]
+}
+
+func NewCORS(origins []string) *CORS {
+	c := &CORS{}
+	c.Update(origins)
+	return c
+}
+
+// Update replaces the allowed origins. "*" allows every origin.
+func (cors *CORS) Update(origins []string) {
+	next := &corsOrigins{allowed: make(map[string]struct{}, len(origins))}
+	for _, origin := range origins {
+		if origin == "*" {
+			next.any = true
+		}
+		next.allowed[strings.TrimSuffix(origin, "/")] = struct{}{}
+	}
+	cors.origins.Store(next)
+}
+
+func (cors *CORS) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		c.Writer.Header().Add("Vary", "Origin")
+		origins := cors.origins.Load()
+		if _, ok := origins.allowed[origin]; !ok && !origins.any {
+			if c.Request.Method == http.MethodOptions {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		c.Header("Access-Control-Allow-Origin", origin)
+		c.Header("Access-Control-Allow-Credentials", "true")
+
+		// Answer preflight requests directly
+		if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" {
+			c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			c.Header("Access-Control-Allow-Headers", "Authorization, Content-Type, X-API-Key")
+			c.Header("Access-Control-Max-Age", "600")
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Next()
+	}
+}
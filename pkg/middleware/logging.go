@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"apisecurityplatform/pkg/logging"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var accessLogger = logging.For("http")
+
+// AccessLog logs every request once it completes. It also prepares the
+// request context to carry the caller's identity into every record logged
+// while serving the request, so it must run before the auth middlewares.
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Request = c.Request.WithContext(logging.NewContext(c.Request.Context()))
+		path := c.Request.URL.Path
+		query := c.Request.URL.RawQuery
+
+		c.Next()
+
+		status := c.Writer.Status()
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", path),
+			slog.Int("status", status),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes", c.Writer.Size()),
+			slog.String("client_ip", c.ClientIP()),
+			slog.String("user_agent", c.Request.UserAgent()),
+		}
+		// Credentials in the query string are masked by the output
+		if query != "" {
+			attrs = append(attrs, slog.String("query", query))
+		}
+		if route := c.GetString("gateway_route"); route != "" {
+			attrs = append(attrs, slog.String("route", route))
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("error", c.Errors.String()))
+		}
+
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		accessLogger.LogAttrs(c.Request.Context(), level, "Request served", attrs...)
+	}
+}
+
+// Recovery answers 500 to requests whose handler panicked and logs the panic
+// with its stack
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered any) {
+		accessLogger.ErrorContext(c.Request.Context(), "Panic while serving request",
+			"panic", fmt.Sprint(recovered), "stack", string(debug.Stack()))
+		c.AbortWithStatus(http.StatusInternalServerError)
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func MetricsMiddleware() gin.HandlerFunc {
@@ -15,7 +16,8 @@ func MetricsMiddleware() gin.HandlerFunc {
 		if path == "" {
 			path = "not_found"
 		}
-		method := c.Request.Method
+		// Unknown methods share one label, so client input cannot add series
+		method, _ := observability.HTTPMethod(c.Request.Method)
 
 		// Track request size (new)
 		if c.Request.ContentLength > 0 {
@@ -40,13 +42,20 @@ func MetricsMiddleware() gin.HandlerFunc {
 			path,
 		).Dec()
 
+		// Proxied requests have no gin route, so label them by gateway route
+		if route := c.GetString("gateway_route"); route != "" {
+			path = "gateway:" + route
+		}
+
 		// Record metrics after request is processed
 		duration := time.Since(start).Seconds()
 		status := strconv.Itoa(c.Writer.Status())
 
 		// Existing metrics
 		observability.RequestCounter.WithLabelValues(method, path, status).Inc()
-		observability.ResponseTime.WithLabelValues(method, path).Observe(duration)
+		// Sampled requests link their latency to the trace through an exemplar
+		span := trace.SpanContextFromContext(c.Request.Context())
+		observability.ObserveWithTrace(observability.ResponseTime.WithLabelValues(method, path), duration, span)
 
 		if c.Writer.Status() >= 400 {
 			errorType := "client_error"
@@ -57,11 +66,11 @@ func MetricsMiddleware() gin.HandlerFunc {
 		}
 
 		// New OpenTelemetry-aligned metrics
-		observability.HTTPServerDuration.WithLabelValues(
+		observability.ObserveWithTrace(observability.HTTPServerDuration.WithLabelValues(
 			method,
 			path,
 			status,
-		).Observe(duration)
+		), duration, span)
 
 		// Track response size (new)
 		responseSize := float64(c.Writer.Size())
@@ -2,29 +2,62 @@ package middleware
 
 import (
 	"sync"
+	"sync/atomic"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/time/rate"
 )
 
+type rateSettings struct {
+	rate  rate.Limit
+	burst int
+}
+
 type RateLimiter struct {
 	limiters sync.Map
-	rate     rate.Limit
-	burst    int
+	settings atomic.Pointer[rateSettings]
 }
 
 func NewRateLimiter(r rate.Limit, b int) *RateLimiter {
-	return &RateLimiter{
-		rate:  r,
-		burst: b,
-	}
+	rl := &RateLimiter{}
+	rl.settings.Store(&rateSettings{rate: r, burst: b})
+	return rl
+}
+
+// Update changes the limit for new and existing clients without resetting
+// the tokens they have already used. A zero rate disables limiting.
+func (rl *RateLimiter) Update(r rate.Limit, b int) {
+	rl.settings.Store(&rateSettings{rate: r, burst: b})
+	rl.limiters.Range(func(_, value interface{}) bool {
+		limiter := value.(*rate.Limiter)
+		limiter.SetLimit(r)
+		limiter.SetBurst(b)
+		return true
+	})
 }
 
 func (rl *RateLimiter) Middleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		settings := rl.settings.Load()
+		if settings.rate == 0 {
+			c.Next()
+			return
+		}
+
 		key := c.ClientIP()
-		limiter, _ := rl.limiters.LoadOrStore(key, rate.NewLimiter(rl.rate, rl.burst))
-		if !limiter.(*rate.Limiter).Allow() {
+		value, _ := rl.limiters.LoadOrStore(key, rate.NewLimiter(settings.rate, settings.burst))
+		limiter := value.(*rate.Limiter)
+		// An Update between loading the settings and storing a new limiter
+		// would have ranged over the limiters without it
+		if current := rl.settings.Load(); current != settings {
+			limiter.SetLimit(current.rate)
+			limiter.SetBurst(current.burst)
+			if current.rate == 0 {
+				c.Next()
+				return
+			}
+		}
+		if !limiter.Allow() {
 			c.JSON(429, gin.H{"error": "Too many requests"})
 			c.Abort()
 			return
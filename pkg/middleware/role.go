@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"apisecurityplatform/pkg/observability"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequireRole rejects requests whose JWT does not carry the given role. It
+// must run after AuthMiddleware.
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("role") != role {
+			observability.AuthorizationDenials.WithLabelValues(role, c.GetString("auth_method")).Inc()
+			trace.SpanFromContext(c.Request.Context()).AddEvent("auth.authorize", trace.WithAttributes(
+				attribute.String("auth.required_role", role),
+				attribute.String("auth.outcome", "denied"),
+			))
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
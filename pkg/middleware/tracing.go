@@ -1,52 +1,109 @@
 package middleware
 
 import (
+	"apisecurityplatform/pkg/logging"
 	"apisecurityplatform/pkg/observability"
-	"fmt"
+	"net"
+	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// TracingMiddleware records a server span per request following the
+// OpenTelemetry HTTP semantic conventions. Requests carrying W3C traceparent
+// and baggage headers continue the caller's trace, which the gateway passes
+// on to upstreams. Sensitive query parameters are redacted.
 func TracingMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		tracer := observability.GetTracer()
-		spanName := fmt.Sprintf("%s %s", c.Request.Method, c.FullPath())
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
 
-		ctx, span := tracer.Start(c.Request.Context(), spanName)
-		defer span.End()
-
-		// Add common attributes
-		span.SetAttributes(
-			attribute.String("http.method", c.Request.Method),
-			attribute.String("http.url", c.Request.URL.String()),
-			attribute.String("http.user_agent", c.Request.UserAgent()),
-			attribute.String("http.remote_addr", c.ClientIP()),
+		method, attrs := observability.HTTPMethod(c.Request.Method)
+		ctx, span := observability.GetTracer().Start(ctx, method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(append(attrs, requestAttributes(c)...)...),
 		)
+		defer span.End()
 
-		// Log trace ID for debugging
-		traceID := span.SpanContext().TraceID().String()
-		fmt.Printf("Trace ID: %s\n", traceID)
-
-		// Store span in context
+		// Store span in context; records logged with it carry the trace and span IDs
 		c.Request = c.Request.WithContext(ctx)
 
 		// Process request
 		c.Next()
 
-		// Add response attributes
-		span.SetAttributes(
-			attribute.Int("http.status_code", c.Writer.Status()),
-			attribute.Int("http.response_size", c.Writer.Size()),
-		)
+		// Proxied requests have no gin route; the gateway sets their template
+		route := c.FullPath()
+		if route == "" {
+			route = c.GetString("http_route")
+		}
+		if route != "" {
+			span.SetName(method + " " + route)
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+		if name := c.GetString("gateway_route"); name != "" {
+			span.SetAttributes(attribute.String("gateway.route", name))
+		}
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if size := c.Writer.Size(); size > 0 {
+			span.SetAttributes(semconv.HTTPResponseBodySize(size))
+		}
 
-		// Record error if any
+		// A 4xx is the client's error, not the server span's
+		if status >= http.StatusInternalServerError {
+			span.SetAttributes(semconv.ErrorTypeKey.String(strconv.Itoa(status)))
+			span.SetStatus(codes.Error, "")
+		}
 		if len(c.Errors) > 0 {
 			span.RecordError(c.Errors.Last().Err)
-			span.SetStatus(codes.Error, c.Errors.Last().Error())
-		} else {
-			span.SetStatus(codes.Ok, "")
 		}
 	}
 }
+
+func requestAttributes(c *gin.Context) []attribute.KeyValue {
+	r := c.Request
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	attrs := []attribute.KeyValue{
+		semconv.URLScheme(scheme),
+		semconv.URLPath(r.URL.Path),
+		semconv.ClientAddress(c.ClientIP()),
+		semconv.NetworkProtocolVersion(protocolVersion(r)),
+	}
+	if r.URL.RawQuery != "" {
+		attrs = append(attrs, semconv.URLQuery(logging.RedactQuery(r.URL.RawQuery)))
+	}
+	if host, port, err := net.SplitHostPort(r.Host); err == nil {
+		attrs = append(attrs, semconv.ServerAddress(host))
+		if p, err := strconv.Atoi(port); err == nil {
+			attrs = append(attrs, semconv.ServerPort(p))
+		}
+	} else if r.Host != "" {
+		attrs = append(attrs, semconv.ServerAddress(r.Host))
+	}
+	if ua := r.UserAgent(); ua != "" {
+		attrs = append(attrs, semconv.UserAgentOriginal(ua))
+	}
+	if r.ContentLength > 0 {
+		attrs = append(attrs, semconv.HTTPRequestBodySize(int(r.ContentLength)))
+	}
+	return attrs
+}
+
+// protocolVersion formats the HTTP version as semantic conventions expect,
+// "1.1" or "2"
+func protocolVersion(r *http.Request) string {
+	if r.ProtoMajor >= 2 {
+		return strconv.Itoa(r.ProtoMajor)
+	}
+	return strconv.Itoa(r.ProtoMajor) + "." + strconv.Itoa(r.ProtoMinor)
+}
@@ -1,6 +1,9 @@
 package models
 
 import (
+	"slices"
+	"strings"
+
 	"gorm.io/gorm"
 )
 
@@ -11,5 +14,17 @@ type APIKey struct {
 	Key         string `gorm:"unique;not null"`
 	LastUsedAt  *int64
 	Description string
-	User        User `gorm:"foreignKey:UserID"`
+	// Scopes is a space separated list of permissions, e.g. "orders:read pii:read"
+	Scopes string
+	User   User `gorm:"foreignKey:UserID"`
+}
+
+// ScopeList returns the key's scopes as a slice
+func (k APIKey) ScopeList() []string {
+	return strings.Fields(k.Scopes)
+}
+
+// HasScope reports whether the key was granted scope
+func (k APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.ScopeList(), scope)
 }
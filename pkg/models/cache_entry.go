@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// CacheEntry is a gateway response stored by the shared database cache backend
+type CacheEntry struct {
+	Key   string `gorm:"column:cache_key;primaryKey;size:64"`
+	Route string `gorm:"index;not null"`
+	// URI is the request target, for invalidation by unsafe requests
+	URI string `gorm:"index"`
+	// Tags is space separated with a leading and trailing space, so a tag can
+	// be matched with LIKE '% tag %'
+	Tags string
+	// Data is the JSON encoded response
+	Data      []byte    `gorm:"not null"`
+	ExpiresAt time.Time `gorm:"index;not null"`
+}
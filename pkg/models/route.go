@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Route is a gateway route managed through the admin API. Definition holds
+// the route's settings as JSON, in the same shape as the config file.
+type Route struct {
+	gorm.Model
+	Name       string `gorm:"uniqueIndex;not null"`
+	Version    int    `gorm:"not null"`
+	Definition string `gorm:"not null"`
+}
+
+// RouteVersion is an immutable snapshot recorded on every change to a route
+type RouteVersion struct {
+	ID         uint   `gorm:"primarykey"`
+	RouteName  string `gorm:"uniqueIndex:idx_route_version;not null"`
+	Version    int    `gorm:"uniqueIndex:idx_route_version;not null"`
+	Action     string `gorm:"not null"`
+	Definition string `gorm:"not null"`
+	// ChangedBy is the ID of the admin who made the change
+	ChangedBy uint
+	CreatedAt time.Time
+}
+
+// Route change actions recorded in RouteVersion
+const (
+	RouteActionCreate   = "create"
+	RouteActionUpdate   = "update"
+	RouteActionRollback = "rollback"
+	RouteActionDelete   = "delete"
+)
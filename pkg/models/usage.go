@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// UsageRecord counts one caller's requests to one route within an hour. The
+// counters only ever grow as metered traffic is added to them.
+type UsageRecord struct {
+	ID     uint      `gorm:"primarykey"`
+	Period time.Time `gorm:"uniqueIndex:idx_usage_key;index;not null"`
+	UserID uint      `gorm:"uniqueIndex:idx_usage_key;index;not null"`
+	// APIKeyID is 0 for requests not made with an API key
+	APIKeyID uint `gorm:"uniqueIndex:idx_usage_key;not null"`
+	// Route is the gateway route name, or the platform endpoint's path pattern
+	Route        string `gorm:"uniqueIndex:idx_usage_key;not null"`
+	Requests     int64  `gorm:"not null"`
+	ClientErrors int64  `gorm:"not null"`
+	ServerErrors int64  `gorm:"not null"`
+	BytesIn      int64  `gorm:"not null"`
+	BytesOut     int64  `gorm:"not null"`
+}
+
+// Add increments the counters of r by those of other
+func (r *UsageRecord) Add(other UsageRecord) {
+	r.Requests += other.Requests
+	r.ClientErrors += other.ClientErrors
+	r.ServerErrors += other.ServerErrors
+	r.BytesIn += other.BytesIn
+	r.BytesOut += other.BytesOut
+}
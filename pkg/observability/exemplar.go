@@ -0,0 +1,28 @@
+package observability
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ObserveWithTrace records value on observer, attaching the trace ID of span
+// as an exemplar when the span is sampled, so that a latency bucket in
+// Grafana links to a trace that was actually exported
+func ObserveWithTrace(observer prometheus.Observer, value float64, span trace.SpanContext) {
+	if exemplars, ok := observer.(prometheus.ExemplarObserver); ok && span.IsSampled() {
+		exemplars.ObserveWithExemplar(value, prometheus.Labels{"trace_id": span.TraceID().String()})
+		return
+	}
+	observer.Observe(value)
+}
+
+// MetricsHandler serves the default registry, in the OpenMetrics format when
+// the scraper asks for it. Exemplars are only exposed in that format.
+func MetricsHandler() http.Handler {
+	return promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true}),
+	)
+}
@@ -0,0 +1,192 @@
+package observability
+
+import (
+	"apisecurityplatform/pkg/config"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Exports to an unreachable collector are retried with backoff for up to
+// exportRetryElapsed, then dropped; the exporters connect lazily, so startup
+// never waits for the collector
+const (
+	exportRetryInitial = time.Second
+	exportRetryMax     = 30 * time.Second
+	exportRetryElapsed = time.Minute
+)
+
+// OTLP/HTTP paths of each signal, appended to endpoints given as a base URL
+const (
+	otlpTracesPath  = "/v1/traces"
+	otlpMetricsPath = "/v1/metrics"
+)
+
+// newSpanExporter creates the configured span exporter, or nil when spans
+// are not exported
+func newSpanExporter(ctx context.Context, cfg config.TracingConfig) (sdktrace.SpanExporter, error) {
+	switch cfg.Exporter {
+	case config.TracingExporterNone:
+		return nil, nil
+	case config.TracingExporterStdout:
+		// Standard output carries the audit log
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stderr))
+	}
+
+	if cfg.Protocol == config.TracingProtocolHTTP {
+		opts := []otlptracehttp.Option{otlptracehttp.WithRetry(otlptracehttp.RetryConfig{
+			Enabled:         true,
+			InitialInterval: exportRetryInitial,
+			MaxInterval:     exportRetryMax,
+			MaxElapsedTime:  exportRetryElapsed,
+		})}
+		switch {
+		case isURL(cfg.Endpoint):
+			opts = append(opts, otlptracehttp.WithEndpointURL(signalURL(cfg.Endpoint, otlpTracesPath)))
+		case cfg.Endpoint != "":
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure && !isURL(cfg.Endpoint) {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(ctx, opts...)
+	}
+
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithRetry(otlptracegrpc.RetryConfig{
+		Enabled:         true,
+		InitialInterval: exportRetryInitial,
+		MaxInterval:     exportRetryMax,
+		MaxElapsedTime:  exportRetryElapsed,
+	})}
+	switch {
+	case isURL(cfg.Endpoint):
+		opts = append(opts, otlptracegrpc.WithEndpointURL(cfg.Endpoint))
+	case cfg.Endpoint != "":
+		opts = append(opts, otlptracegrpc.WithEndpoint(cfg.Endpoint))
+	}
+	if cfg.Insecure && !isURL(cfg.Endpoint) {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	return otlptracegrpc.New(ctx, opts...)
+}
+
+// newMetricReader creates a periodic reader for the configured exporter, or
+// nil when OTLP metrics are not exported. Prometheus scrapes /metrics either way.
+func newMetricReader(ctx context.Context, cfg config.TracingConfig) (sdkmetric.Reader, error) {
+	var exporter sdkmetric.Exporter
+	var err error
+	switch {
+	case cfg.Exporter == config.TracingExporterNone:
+		return nil, nil
+	case cfg.Exporter == config.TracingExporterStdout:
+		exporter, err = stdoutmetric.New(stdoutmetric.WithWriter(os.Stderr))
+	case cfg.Protocol == config.TracingProtocolHTTP:
+		opts := []otlpmetrichttp.Option{otlpmetrichttp.WithRetry(otlpmetrichttp.RetryConfig{
+			Enabled:         true,
+			InitialInterval: exportRetryInitial,
+			MaxInterval:     exportRetryMax,
+			MaxElapsedTime:  exportRetryElapsed,
+		})}
+		switch {
+		case isURL(cfg.Endpoint):
+			opts = append(opts, otlpmetrichttp.WithEndpointURL(signalURL(cfg.Endpoint, otlpMetricsPath)))
+		case cfg.Endpoint != "":
+			opts = append(opts, otlpmetrichttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure && !isURL(cfg.Endpoint) {
+			opts = append(opts, otlpmetrichttp.WithInsecure())
+		}
+		exporter, err = otlpmetrichttp.New(ctx, opts...)
+	default:
+		opts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithRetry(otlpmetricgrpc.RetryConfig{
+			Enabled:         true,
+			InitialInterval: exportRetryInitial,
+			MaxInterval:     exportRetryMax,
+			MaxElapsedTime:  exportRetryElapsed,
+		})}
+		switch {
+		case isURL(cfg.Endpoint):
+			opts = append(opts, otlpmetricgrpc.WithEndpointURL(cfg.Endpoint))
+		case cfg.Endpoint != "":
+			opts = append(opts, otlpmetricgrpc.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure && !isURL(cfg.Endpoint) {
+			opts = append(opts, otlpmetricgrpc.WithInsecure())
+		}
+		exporter, err = otlpmetricgrpc.New(ctx, opts...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sdkmetric.NewPeriodicReader(exporter), nil
+}
+
+func isURL(endpoint string) bool {
+	return strings.Contains(endpoint, "://")
+}
+
+// signalURL returns the OTLP/HTTP URL of a signal. A base URL, as
+// OTEL_EXPORTER_OTLP_ENDPOINT holds, gets the signal's path appended; a
+// URL already naming the traces path is switched to the signal's.
+func signalURL(endpoint, signalPath string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		// Reported by the exporter, which parses it again
+		return endpoint
+	}
+	path := strings.TrimSuffix(u.Path, "/")
+	if path == "" || strings.HasSuffix(path, otlpTracesPath) {
+		u.Path = strings.TrimSuffix(path, otlpTracesPath) + signalPath
+	}
+	return u.String()
+}
+
+// statusExporter records the outcome of the last span export, so readiness
+// can report a collector that stopped accepting spans
+type statusExporter struct {
+	sdktrace.SpanExporter
+
+	mu      sync.Mutex
+	lastErr error
+	failed  time.Time
+}
+
+var spanExporter *statusExporter
+
+func (e *statusExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	err := e.SpanExporter.ExportSpans(ctx, spans)
+	e.mu.Lock()
+	e.lastErr = err
+	if err != nil {
+		e.failed = time.Now()
+	}
+	e.mu.Unlock()
+	return err
+}
+
+// CheckExporter returns the error of the last span export, if it failed. It
+// returns nil when spans are not exported or nothing was exported yet.
+func CheckExporter(context.Context) error {
+	if spanExporter == nil {
+		return nil
+	}
+	spanExporter.mu.Lock()
+	defer spanExporter.mu.Unlock()
+	if spanExporter.lastErr != nil {
+		return fmt.Errorf("span export failed at %s: %w", spanExporter.failed.Format(time.RFC3339), spanExporter.lastErr)
+	}
+	return nil
+}
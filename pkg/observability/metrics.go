@@ -1,16 +1,14 @@
 package observability
 
 import (
-	"context"
-	"fmt"
-	"runtime"
-	"time"
-
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
-	"go.opentelemetry.io/otel/metric"
 )
 
+// HTTPServerDurationBuckets are the bucket bounds of http_server_duration_seconds,
+// in seconds. SLO latency thresholds must be one of them.
+var HTTPServerDurationBuckets = []float64{.005, .01, .025, .05, .075, .1, .25, .5, .75, 1, 2.5, 5, 7.5, 10}
+
 var (
 	// RequestCounter tracks total HTTP requests
 	RequestCounter = promauto.NewCounterVec(
@@ -40,31 +38,43 @@ var (
 		[]string{"method", "endpoint", "error_type"},
 	)
 
-	// APIKeyUsage tracks API key usage
-	APIKeyUsage = promauto.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "api_key_usage_total",
-			Help: "Total number of API key usages",
-		},
-		[]string{"key_id"},
-	)
-
-	// Track API key creation/deletion
+	// APIKeyOperations counts API key management by operation (create, delete)
+	// and outcome. Per-key traffic lives in the usage store, not here.
 	APIKeyOperations = promauto.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "api_key_operations_total",
 			Help: "Total number of API key operations",
 		},
-		[]string{"operation", "user_id"},
+		[]string{"operation", "outcome"},
 	)
 
-	// Track authentication attempts
+	// AuthAttempts counts authentication by method (password, jwt, api_key,
+	// client_cert) and outcome (success, missing, invalid, revoked, error)
 	AuthAttempts = promauto.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "auth_attempts_total",
 			Help: "Total number of authentication attempts",
 		},
-		[]string{"success", "method"},
+		[]string{"method", "outcome"},
+	)
+
+	// AuthorizationDenials counts authenticated requests refused for lacking
+	// the required role
+	AuthorizationDenials = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "authorization_denials_total",
+			Help: "Total number of requests denied for lacking the required role",
+		},
+		[]string{"required_role", "method"},
+	)
+
+	// UsageFlushes counts writes of metered usage to the usage store by result
+	UsageFlushes = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "usage_flushes_total",
+			Help: "Total number of usage flushes to the usage store",
+		},
+		[]string{"result"},
 	)
 
 	// New OpenTelemetry-aligned metrics
@@ -72,7 +82,7 @@ var (
 		prometheus.HistogramOpts{
 			Name:    "http_server_duration_seconds",
 			Help:    "Duration of HTTP requests",
-			Buckets: []float64{.005, .01, .025, .05, .075, .1, .25, .5, .75, 1, 2.5, 5, 7.5, 10},
+			Buckets: HTTPServerDurationBuckets,
 		},
 		[]string{"method", "route", "status_code"},
 	)
@@ -100,141 +110,246 @@ var (
 		},
 		[]string{"method", "path"},
 	)
-)
 
-// RuntimeMetrics holds runtime metric instruments
-type RuntimeMetrics struct {
-	// Memory metrics
-	heapAlloc   metric.Int64UpDownCounter
-	heapIdle    metric.Int64UpDownCounter
-	heapInuse   metric.Int64UpDownCounter
-	heapObjects metric.Int64UpDownCounter
-
-	// Goroutine metrics
-	goroutines metric.Int64UpDownCounter
-
-	// GC metrics
-	gcCount      metric.Int64Counter
-	gcPauseTotal metric.Int64Counter
-	gcPauseNs    metric.Float64Histogram
-}
+	// ConfigReloads tracks configuration reload attempts by trigger and result
+	ConfigReloads = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "config_reloads_total",
+			Help: "Total number of configuration reload attempts",
+		},
+		[]string{"trigger", "result"},
+	)
 
-// InitRuntimeMetrics initializes and starts runtime metrics collection
-func InitRuntimeMetrics(ctx context.Context) error {
-	meter := GetMeter()
-
-	rm := &RuntimeMetrics{}
-	var err error
-
-	// Initialize memory metrics
-	if rm.heapAlloc, err = meter.Int64UpDownCounter(
-		"process.runtime.go.mem.heap_alloc",
-		metric.WithDescription("Bytes of allocated heap objects"),
-		metric.WithUnit("bytes"),
-	); err != nil {
-		return fmt.Errorf("failed to create heap_alloc metric: %w", err)
-	}
-
-	if rm.heapIdle, err = meter.Int64UpDownCounter(
-		"process.runtime.go.mem.heap_idle",
-		metric.WithDescription("Bytes in idle (unused) spans"),
-		metric.WithUnit("bytes"),
-	); err != nil {
-		return fmt.Errorf("failed to create heap_idle metric: %w", err)
-	}
-
-	if rm.heapInuse, err = meter.Int64UpDownCounter(
-		"process.runtime.go.mem.heap_inuse",
-		metric.WithDescription("Bytes in in-use spans"),
-		metric.WithUnit("bytes"),
-	); err != nil {
-		return fmt.Errorf("failed to create heap_inuse metric: %w", err)
-	}
-
-	if rm.heapObjects, err = meter.Int64UpDownCounter(
-		"process.runtime.go.mem.heap_objects",
-		metric.WithDescription("Number of allocated heap objects"),
-	); err != nil {
-		return fmt.Errorf("failed to create heap_objects metric: %w", err)
-	}
-
-	// Initialize goroutine metrics
-	if rm.goroutines, err = meter.Int64UpDownCounter(
-		"process.runtime.go.goroutines",
-		metric.WithDescription("Number of goroutines that currently exist"),
-	); err != nil {
-		return fmt.Errorf("failed to create goroutines metric: %w", err)
-	}
-
-	// Initialize GC metrics
-	if rm.gcCount, err = meter.Int64Counter(
-		"process.runtime.go.gc.count",
-		metric.WithDescription("Number of completed GC cycles"),
-	); err != nil {
-		return fmt.Errorf("failed to create gc_count metric: %w", err)
-	}
-
-	if rm.gcPauseTotal, err = meter.Int64Counter(
-		"process.runtime.go.gc.pause_total",
-		metric.WithDescription("Cumulative nanoseconds in GC stop-the-world pauses"),
-		metric.WithUnit("ns"),
-	); err != nil {
-		return fmt.Errorf("failed to create gc_pause_total metric: %w", err)
-	}
-
-	if rm.gcPauseNs, err = meter.Float64Histogram(
-		"process.runtime.go.gc.pause",
-		metric.WithDescription("Amount of time in GC stop-the-world pauses"),
-		metric.WithUnit("ns"),
-	); err != nil {
-		return fmt.Errorf("failed to create gc_pause metric: %w", err)
-	}
-
-	// Start collection loop
-	go rm.collect(ctx)
-
-	return nil
-}
+	// TLSCertificateExpiry records when the served TLS certificate expires
+	TLSCertificateExpiry = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "tls_certificate_expiry_timestamp_seconds",
+			Help: "Unix timestamp at which the served TLS certificate expires",
+		},
+	)
+
+	// TLSCertificateReloads tracks TLS certificate reload attempts by result
+	TLSCertificateReloads = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "tls_certificate_reloads_total",
+			Help: "Total number of TLS certificate reload attempts",
+		},
+		[]string{"result"},
+	)
+
+	// GatewayUpstreamRequests tracks proxied attempts by upstream and result
+	GatewayUpstreamRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_upstream_requests_total",
+			Help: "Total number of requests sent to gateway upstreams",
+		},
+		[]string{"route", "upstream", "result"},
+	)
+
+	// GatewayUpstreamDuration tracks the time until an upstream's response headers arrive
+	GatewayUpstreamDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "gateway_upstream_duration_seconds",
+			Help:    "Time until response headers from gateway upstreams",
+			Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		},
+		[]string{"route", "upstream"},
+	)
+
+	// GatewayUpstreamActiveRequests tracks in-flight requests per upstream
+	GatewayUpstreamActiveRequests = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "gateway_upstream_active_requests",
+			Help: "Number of in-flight requests per gateway upstream",
+		},
+		[]string{"route", "upstream"},
+	)
+
+	// GatewayUpstreamHealthy reports the active health check state (1 healthy, 0 unhealthy)
+	GatewayUpstreamHealthy = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "gateway_upstream_healthy",
+			Help: "Whether a gateway upstream passes its active health checks",
+		},
+		[]string{"route", "upstream"},
+	)
+
+	// GatewayUpstreamEjected reports whether an upstream is ejected by outlier detection
+	GatewayUpstreamEjected = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "gateway_upstream_ejected",
+			Help: "Whether a gateway upstream is ejected by outlier detection",
+		},
+		[]string{"route", "upstream"},
+	)
+
+	// GatewayUpstreamEjections tracks outlier ejections per upstream
+	GatewayUpstreamEjections = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_upstream_ejections_total",
+			Help: "Total number of outlier ejections of gateway upstreams",
+		},
+		[]string{"route", "upstream"},
+	)
+
+	// GatewayCircuitBreakerState reports each upstream's breaker (0 closed, 1 half-open, 2 open)
+	GatewayCircuitBreakerState = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "gateway_circuit_breaker_state",
+			Help: "Circuit breaker state per gateway upstream: 0 closed, 1 half-open, 2 open",
+		},
+		[]string{"route", "upstream"},
+	)
+
+	// GatewayRetries tracks retries by result: retried or budget_exhausted
+	GatewayRetries = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_retries_total",
+			Help: "Total number of gateway retry decisions",
+		},
+		[]string{"route", "result"},
+	)
+
+	// GatewayCacheRequests tracks gateway cache lookups by route and result
+	GatewayCacheRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_cache_requests_total",
+			Help: "Total number of gateway cache lookups by result: hit, miss, revalidated or bypass",
+		},
+		[]string{"route", "result"},
+	)
+
+	// GatewayCachePurgedEntries tracks entries removed through the purge endpoint
+	// or invalidated by unsafe requests
+	GatewayCachePurgedEntries = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_cache_purged_entries_total",
+			Help: "Total number of gateway cache entries purged, by route, tag or invalidation",
+		},
+		[]string{"by"},
+	)
+
+	// GatewayStreamConnections tracks open WebSocket connections and event streams
+	GatewayStreamConnections = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "gateway_stream_connections",
+			Help: "Number of open gateway streams by protocol: websocket or sse",
+		},
+		[]string{"route", "protocol"},
+	)
+
+	// GatewayStreamBytes tracks bytes relayed over streams
+	GatewayStreamBytes = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_stream_bytes_total",
+			Help: "Total number of bytes relayed over gateway streams, by direction: upstream or downstream",
+		},
+		[]string{"route", "protocol", "direction"},
+	)
+
+	// GatewayStreamsClosed tracks why streams ended
+	GatewayStreamsClosed = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_streams_closed_total",
+			Help: "Total number of gateway streams closed, by reason: closed, revoked or message_too_large",
+		},
+		[]string{"route", "protocol", "reason"},
+	)
+
+	// OpenAPIRequestViolations tracks requests rejected by OpenAPI validation
+	OpenAPIRequestViolations = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "openapi_request_violations_total",
+			Help: "Total number of requests rejected for not matching the OpenAPI document",
+		},
+		[]string{"spec", "operation"},
+	)
+
+	// OpenAPIResponseDrift tracks responses that do not match the OpenAPI document
+	OpenAPIResponseDrift = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "openapi_response_drift_total",
+			Help: "Total number of responses that did not match the OpenAPI document",
+		},
+		[]string{"spec", "operation"},
+	)
+
+	// DatabaseQueryDuration tracks the latency of database queries
+	DatabaseQueryDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "database_query_duration_seconds",
+			Help:    "Database query duration in seconds",
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		},
+		[]string{"operation", "table", "result"},
+	)
+
+	// DatabasePoolConnections tracks the connections of the database pool by state
+	DatabasePoolConnections = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "database_pool_connections",
+			Help: "Connections of the database pool, by state (open, in_use or idle)",
+		},
+		[]string{"state"},
+	)
+
+	// DatabasePoolMaxOpenConnections is the limit on open connections
+	DatabasePoolMaxOpenConnections = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "database_pool_max_open_connections",
+			Help: "Maximum number of open connections to the database, 0 for unlimited",
+		},
+	)
+
+	// DatabasePoolWaits tracks queries that waited for a free connection
+	DatabasePoolWaits = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "database_pool_waits_total",
+			Help: "Total number of times a query waited for a free database connection",
+		},
+	)
+
+	// DatabasePoolWaitDuration tracks the time spent waiting for a free connection
+	DatabasePoolWaitDuration = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "database_pool_wait_seconds_total",
+			Help: "Total time spent waiting for a free database connection, in seconds",
+		},
+	)
+
+	// ConfigLastReloadSuccess records when the configuration was last reloaded successfully
+	ConfigLastReloadSuccess = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "config_last_reload_success_timestamp_seconds",
+			Help: "Unix timestamp of the last successful configuration reload",
+		},
+	)
+
+	// HealthCheckUp reports the last result of a liveness or readiness check (1 up, 0 down)
+	HealthCheckUp = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "health_check_up",
+			Help: "Whether a health check passed when it last ran",
+		},
+		[]string{"probe", "check"},
+	)
+
+	// HealthCheckDuration measures health check runs; cached results are not recorded
+	HealthCheckDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "health_check_duration_seconds",
+			Help:    "Duration of health check runs",
+			Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		},
+		[]string{"probe", "check"},
+	)
+)
 
-func (rm *RuntimeMetrics) collect(ctx context.Context) {
-	ticker := time.NewTicker(15 * time.Second)
-	defer ticker.Stop()
-
-	var stats runtime.MemStats
-	var lastPauseNs uint64
-	var lastNumGC uint32
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			runtime.ReadMemStats(&stats)
-
-			// Update memory metrics
-			rm.heapAlloc.Add(ctx, int64(stats.HeapAlloc))
-			rm.heapIdle.Add(ctx, int64(stats.HeapIdle))
-			rm.heapInuse.Add(ctx, int64(stats.HeapInuse))
-			rm.heapObjects.Add(ctx, int64(stats.HeapObjects))
-
-			// Update goroutine count
-			rm.goroutines.Add(ctx, int64(runtime.NumGoroutine()))
-
-			// Update GC metrics
-			if stats.NumGC > lastNumGC {
-				rm.gcCount.Add(ctx, int64(stats.NumGC-lastNumGC))
-
-				// Record pause times
-				for i := lastNumGC; i < stats.NumGC; i++ {
-					pauseNs := stats.PauseNs[i%256]
-					if pauseNs > lastPauseNs {
-						rm.gcPauseTotal.Add(ctx, int64(pauseNs-lastPauseNs))
-						rm.gcPauseNs.Record(ctx, float64(pauseNs-lastPauseNs))
-						lastPauseNs = pauseNs
-					}
-				}
-				lastNumGC = stats.NumGC
-			}
-		}
-	}
+// DeleteGatewayUpstreamGauges drops the state gauges of an upstream removed
+// from the gateway, so it does not linger as stale series
+func DeleteGatewayUpstreamGauges(route, upstream string) {
+	GatewayUpstreamActiveRequests.DeleteLabelValues(route, upstream)
+	GatewayUpstreamHealthy.DeleteLabelValues(route, upstream)
+	GatewayUpstreamEjected.DeleteLabelValues(route, upstream)
+	GatewayCircuitBreakerState.DeleteLabelValues(route, upstream)
 }
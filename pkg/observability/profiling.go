@@ -0,0 +1,305 @@
+package observability
+
+import (
+	"apisecurityplatform/pkg/config"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/pprof"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Profile kinds that can be captured
+const (
+	ProfileCPU  = "cpu"
+	ProfileHeap = "heap"
+)
+
+// Profile states
+const (
+	ProfileRunning   = "running"
+	ProfileCompleted = "completed"
+	ProfileFailed    = "failed"
+)
+
+// DefaultProfileDuration is how long a CPU profile runs when no duration is given
+const DefaultProfileDuration = 30 * time.Second
+
+// Profiling errors. Their messages are safe to return to the caller.
+var (
+	ErrProfileNotFound    = errors.New("Profile not found")
+	ErrProfileNotReady    = errors.New("Profile is not completed")
+	ErrProfileInProgress  = errors.New("A CPU profile is already being captured")
+	ErrInvalidProfileKind = errors.New("Profile kind must be cpu or heap")
+	ErrInvalidDuration    = errors.New("Invalid profile duration")
+)
+
+// profileIDFormat timestamps profile IDs, which also name their files
+const profileIDFormat = "20060102T150405.000Z"
+
+var profileFileName = regexp.MustCompile(`^(cpu|heap)-(\d{8}T\d{6}\.\d{3}Z)\.pprof$`)
+
+// writeHeapProfile writes a heap profile to w
+var writeHeapProfile = func(w io.Writer) error {
+	// Collect garbage first, so the profile reflects live objects
+	runtime.GC()
+	return pprof.Lookup("heap").WriteTo(w, 0)
+}
+
+// Profile describes a captured profile
+type Profile struct {
+	ID     string `json:"id"`
+	Kind   string `json:"kind"`
+	Status string `json:"status"`
+	// Duration is how long a CPU profile samples for
+	Duration   string     `json:"duration,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Size       int64      `json:"size,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// Profiler captures CPU and heap profiles on demand into a directory, so
+// they can be downloaded once complete. Only the latest profiles are kept.
+type Profiler struct {
+	// ctx bounds the running CPU profile
+	ctx         context.Context
+	dir         string
+	maxProfiles int
+	maxDuration time.Duration
+
+	mu sync.Mutex
+	// profiles are ordered by start, oldest first
+	profiles []*Profile
+}
+
+// NewProfiler creates a Profiler storing profiles in the configured
+// directory. Profiles captured before a restart are listed again. A CPU
+// profile running when ctx is cancelled stops early and is kept.
+func NewProfiler(ctx context.Context, cfg config.ProfilingConfig) (*Profiler, error) {
+	dir := cfg.Dir
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), serviceName+"-profiles")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create profile directory: %w", err)
+	}
+	p := &Profiler{ctx: ctx, dir: dir, maxProfiles: cfg.MaxProfiles, maxDuration: cfg.MaxDuration}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read profile directory: %w", err)
+	}
+	for _, entry := range entries {
+		match := profileFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		started, err := time.Parse(profileIDFormat, match[2])
+		info, infoErr := entry.Info()
+		if err != nil || infoErr != nil {
+			continue
+		}
+		finished := info.ModTime()
+		p.profiles = append(p.profiles, &Profile{
+			ID:         match[1] + "-" + match[2],
+			Kind:       match[1],
+			Status:     ProfileCompleted,
+			StartedAt:  started,
+			FinishedAt: &finished,
+			Size:       info.Size(),
+		})
+	}
+	slices.SortFunc(p.profiles, func(a, b *Profile) int { return a.StartedAt.Compare(b.StartedAt) })
+	p.prune()
+	return p, nil
+}
+
+// Start captures a profile of kind. A heap profile is written before Start
+// returns, and a failure to write it is returned along with the failed
+// profile; a CPU profile samples for duration, or DefaultProfileDuration,
+// in the background. Only one CPU profile can run at a time, including
+// those served by net/http/pprof.
+func (p *Profiler) Start(kind string, duration time.Duration) (Profile, error) {
+	switch kind {
+	case ProfileCPU:
+		if duration == 0 {
+			duration = DefaultProfileDuration
+		}
+		if duration < 0 || duration > p.maxDuration {
+			return Profile{}, fmt.Errorf("%w: it must be positive and at most %s", ErrInvalidDuration, p.maxDuration)
+		}
+	case ProfileHeap:
+		duration = 0
+	default:
+		return Profile{}, ErrInvalidProfileKind
+	}
+
+	profile := p.add(kind, duration)
+	f, err := os.Create(p.path(profile.ID))
+	if err != nil {
+		p.remove(profile.ID)
+		return Profile{}, fmt.Errorf("create profile file: %w", err)
+	}
+
+	if kind == ProfileHeap {
+		err := writeHeapProfile(f)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		p.finish(profile, err)
+		finished, _ := p.get(profile.ID)
+		if err != nil {
+			return finished, fmt.Errorf("write heap profile: %w", err)
+		}
+		return finished, nil
+	}
+
+	if err := pprof.StartCPUProfile(f); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		p.remove(profile.ID)
+		return Profile{}, ErrProfileInProgress
+	}
+	go func() {
+		timer := time.NewTimer(duration)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-p.ctx.Done():
+		}
+		pprof.StopCPUProfile()
+		p.finish(profile, f.Close())
+	}()
+	return p.get(profile.ID)
+}
+
+// List returns every profile, newest first
+func (p *Profiler) List() []Profile {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	profiles := make([]Profile, 0, len(p.profiles))
+	for i := len(p.profiles) - 1; i >= 0; i-- {
+		profiles = append(profiles, *p.profiles[i])
+	}
+	return profiles
+}
+
+// Open opens the file of a completed profile. The caller closes it.
+func (p *Profiler) Open(id string) (*os.File, Profile, error) {
+	profile, err := p.get(id)
+	if err != nil {
+		return nil, Profile{}, err
+	}
+	if profile.Status != ProfileCompleted {
+		return nil, profile, ErrProfileNotReady
+	}
+	f, err := os.Open(p.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		// Pruned since
+		return nil, Profile{}, ErrProfileNotFound
+	}
+	return f, profile, err
+}
+
+func (p *Profiler) path(id string) string {
+	return filepath.Join(p.dir, id+".pprof")
+}
+
+// add records a running profile under a new ID
+func (p *Profiler) add(kind string, duration time.Duration) *Profile {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	started := time.Now().UTC()
+	id := kind + "-" + started.Format(profileIDFormat)
+	// IDs have millisecond resolution
+	for p.find(id) != nil {
+		started = started.Add(time.Millisecond)
+		id = kind + "-" + started.Format(profileIDFormat)
+	}
+	profile := &Profile{ID: id, Kind: kind, Status: ProfileRunning, StartedAt: started}
+	if duration > 0 {
+		profile.Duration = duration.String()
+	}
+	p.profiles = append(p.profiles, profile)
+	return profile
+}
+
+// finish completes profile, or fails it with err, and deletes the oldest
+// profiles beyond the limit
+func (p *Profiler) finish(profile *Profile, err error) {
+	var size int64
+	if err == nil {
+		var info os.FileInfo
+		if info, err = os.Stat(p.path(profile.ID)); err == nil {
+			size = info.Size()
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	finished := time.Now().UTC()
+	profile.FinishedAt = &finished
+	if err != nil {
+		logger.Error("Profile capture failed", "profile", profile.ID, "error", err)
+		profile.Status = ProfileFailed
+		profile.Error = err.Error()
+		os.Remove(p.path(profile.ID))
+	} else {
+		logger.Info("Profile captured", "profile", profile.ID, "bytes", size)
+		profile.Status = ProfileCompleted
+		profile.Size = size
+	}
+	p.prune()
+}
+
+// prune deletes the oldest finished profiles beyond maxProfiles. The caller
+// holds mu, unless p is not shared yet.
+func (p *Profiler) prune() {
+	excess := len(p.profiles) - p.maxProfiles
+	kept := p.profiles[:0]
+	for _, profile := range p.profiles {
+		if excess > 0 && profile.Status != ProfileRunning {
+			excess--
+			if err := os.Remove(p.path(profile.ID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				logger.Warn("Failed to delete old profile", "profile", profile.ID, "error", err)
+			}
+			continue
+		}
+		kept = append(kept, profile)
+	}
+	p.profiles = kept
+}
+
+func (p *Profiler) remove(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.profiles = slices.DeleteFunc(p.profiles, func(profile *Profile) bool { return profile.ID == id })
+}
+
+func (p *Profiler) get(id string) (Profile, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	profile := p.find(id)
+	if profile == nil {
+		return Profile{}, ErrProfileNotFound
+	}
+	return *profile, nil
+}
+
+// find returns the profile with id, or nil. The caller holds mu.
+func (p *Profiler) find(id string) *Profile {
+	for _, profile := range p.profiles {
+		if profile.ID == id {
+			return profile
+		}
+	}
+	return nil
+}
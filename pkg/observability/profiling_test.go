@@ -0,0 +1,209 @@
+package observability
+
+import (
+	"apisecurityplatform/pkg/config"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestProfiler(t *testing.T, ctx context.Context, dir string, maxProfiles int) *Profiler {
+	t.Helper()
+	p, err := NewProfiler(ctx, config.ProfilingConfig{Dir: dir, MaxProfiles: maxProfiles, MaxDuration: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// waitFor polls the profile with id until it is no longer running
+func waitFor(t *testing.T, p *Profiler, id string) Profile {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if profile, err := p.get(id); err != nil || profile.Status != ProfileRunning {
+			return profile
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("profile %s still running", id)
+	return Profile{}
+}
+
+func TestHeapProfile(t *testing.T) {
+	p := newTestProfiler(t, context.Background(), t.TempDir(), 10)
+	profile, err := p.Start(ProfileHeap, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile.Status != ProfileCompleted || profile.Size == 0 || profile.FinishedAt == nil || profile.Duration != "" {
+		t.Errorf("heap profile = %+v, want completed at once without a duration", profile)
+	}
+
+	f, opened, err := p.Open(profile.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	data, _ := io.ReadAll(f)
+	if int64(len(data)) != opened.Size {
+		t.Errorf("read %d bytes, want %d", len(data), opened.Size)
+	}
+}
+
+func TestHeapProfileFailure(t *testing.T) {
+	writeErr := errors.New("disk full")
+	defer func(write func(io.Writer) error) { writeHeapProfile = write }(writeHeapProfile)
+	writeHeapProfile = func(io.Writer) error { return writeErr }
+
+	dir := t.TempDir()
+	p := newTestProfiler(t, context.Background(), dir, 10)
+	profile, err := p.Start(ProfileHeap, 0)
+	if !errors.Is(err, writeErr) {
+		t.Fatalf("Start error = %v, want the write error", err)
+	}
+	if profile.ID == "" || profile.Status != ProfileFailed || profile.Error == "" {
+		t.Errorf("profile = %+v, want the failed profile", profile)
+	}
+	if _, err := os.Stat(p.path(profile.ID)); !os.IsNotExist(err) {
+		t.Error("file of the failed profile kept")
+	}
+	if _, _, err := p.Open(profile.ID); !errors.Is(err, ErrProfileNotReady) {
+		t.Errorf("Open of a failed profile: error %v, want ErrProfileNotReady", err)
+	}
+}
+
+func TestStartValidation(t *testing.T) {
+	p := newTestProfiler(t, context.Background(), t.TempDir(), 10)
+	tests := []struct {
+		kind     string
+		duration time.Duration
+		want     error
+	}{
+		{"goroutine", 0, ErrInvalidProfileKind},
+		{ProfileCPU, -time.Second, ErrInvalidDuration},
+		{ProfileCPU, 2 * time.Hour, ErrInvalidDuration},
+	}
+	for _, tt := range tests {
+		if _, err := p.Start(tt.kind, tt.duration); !errors.Is(err, tt.want) {
+			t.Errorf("Start(%s, %s): error %v, want %v", tt.kind, tt.duration, err, tt.want)
+		}
+	}
+	if profiles := p.List(); len(profiles) != 0 {
+		t.Errorf("rejected captures listed: %+v", profiles)
+	}
+}
+
+func TestOneCPUProfileAtATime(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := newTestProfiler(t, ctx, t.TempDir(), 10)
+
+	first, err := p.Start(ProfileCPU, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Status != ProfileRunning || first.Duration != "1h0m0s" {
+		t.Errorf("CPU profile = %+v, want running for 1h", first)
+	}
+	if _, _, err := p.Open(first.ID); !errors.Is(err, ErrProfileNotReady) {
+		t.Errorf("Open of a running profile: error %v, want ErrProfileNotReady", err)
+	}
+
+	if _, err := p.Start(ProfileCPU, time.Second); !errors.Is(err, ErrProfileInProgress) {
+		t.Errorf("second CPU profile: error %v, want ErrProfileInProgress", err)
+	}
+	if profiles := p.List(); len(profiles) != 1 {
+		t.Errorf("%d profiles listed, want the running one only", len(profiles))
+	}
+	// A heap profile does not wait for the CPU profile
+	if _, err := p.Start(ProfileHeap, 0); err != nil {
+		t.Errorf("heap profile during a CPU profile: %v", err)
+	}
+
+	// Cancelling the context stops the CPU profile early and keeps it
+	cancel()
+	if done := waitFor(t, p, first.ID); done.Status != ProfileCompleted || done.Size == 0 {
+		t.Errorf("stopped CPU profile = %+v, want completed", done)
+	}
+}
+
+func TestProfileIDCollision(t *testing.T) {
+	p := newTestProfiler(t, context.Background(), t.TempDir(), 10)
+	ids := make(map[string]bool)
+	for i := 0; i < 5; i++ {
+		profile := p.add(ProfileHeap, 0)
+		if ids[profile.ID] {
+			t.Fatalf("ID %s reused", profile.ID)
+		}
+		ids[profile.ID] = true
+		if !profileFileName.MatchString(profile.ID + ".pprof") {
+			t.Errorf("ID %s does not name a profile file", profile.ID)
+		}
+	}
+}
+
+func TestProfilePruning(t *testing.T) {
+	dir := t.TempDir()
+	p := newTestProfiler(t, context.Background(), dir, 2)
+	var ids []string
+	for i := 0; i < 3; i++ {
+		profile, err := p.Start(ProfileHeap, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, profile.ID)
+	}
+
+	profiles := p.List()
+	if len(profiles) != 2 || profiles[0].ID != ids[2] || profiles[1].ID != ids[1] {
+		t.Errorf("profiles = %+v, want the latest two, newest first", profiles)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ids[0]+".pprof")); !os.IsNotExist(err) {
+		t.Error("file of the pruned profile kept")
+	}
+	if _, _, err := p.Open(ids[0]); !errors.Is(err, ErrProfileNotFound) {
+		t.Errorf("Open of a pruned profile: error %v, want ErrProfileNotFound", err)
+	}
+}
+
+func TestProfilesReloadedFromDisk(t *testing.T) {
+	dir := t.TempDir()
+	p := newTestProfiler(t, context.Background(), dir, 10)
+	var ids []string
+	for i := 0; i < 3; i++ {
+		profile, err := p.Start(ProfileHeap, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, profile.ID)
+	}
+	// Other files in the directory are left alone
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x"), 0o600)
+
+	restarted := newTestProfiler(t, context.Background(), dir, 2)
+	profiles := restarted.List()
+	if len(profiles) != 2 || profiles[0].ID != ids[2] || profiles[1].ID != ids[1] {
+		t.Fatalf("profiles after a restart = %+v, want the latest two", profiles)
+	}
+	for _, profile := range profiles {
+		if profile.Status != ProfileCompleted || profile.Kind != ProfileHeap || profile.Size == 0 || profile.FinishedAt == nil {
+			t.Errorf("reloaded profile = %+v, want a completed heap profile", profile)
+		}
+	}
+	if f, _, err := restarted.Open(ids[2]); err != nil {
+		t.Errorf("Open after a restart: %v", err)
+	} else {
+		f.Close()
+	}
+	if _, err := os.Stat(filepath.Join(dir, ids[0]+".pprof")); !os.IsNotExist(err) {
+		t.Error("profile beyond max_profiles kept after a restart")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes.txt")); err != nil {
+		t.Error("unrelated file deleted")
+	}
+}
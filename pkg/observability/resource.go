@@ -0,0 +1,80 @@
+package observability
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+// serviceAccountNamespace holds the pod's namespace when a service account is mounted
+const serviceAccountNamespace = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// newResource describes this instance: the service and environment, the
+// host, container and Kubernetes pod it runs in, and the Go runtime.
+// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override anything detected.
+func newResource(ctx context.Context, environment string) (*resource.Resource, error) {
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			semconv.ServiceName(serviceName),
+			semconv.DeploymentEnvironment(environment),
+		),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithOSType(),
+		resource.WithProcessPID(),
+		resource.WithProcessRuntimeName(),
+		resource.WithProcessRuntimeVersion(),
+		resource.WithContainer(),
+		resource.WithDetectors(kubernetesDetector{}),
+		resource.WithFromEnv(),
+	)
+	// A detector that fails, such as the container one outside a container,
+	// still leaves the attributes of the others
+	if errors.Is(err, resource.ErrPartialResource) {
+		logger.Debug("Some resource attributes could not be detected", "error", err)
+		return res, nil
+	}
+	return res, err
+}
+
+// kubernetesDetector reads the pod's identity from the variables the Helm
+// chart sets through the downward API, falling back to the hostname and the
+// service account namespace when only running in a cluster is known
+type kubernetesDetector struct{}
+
+func (kubernetesDetector) Detect(context.Context) (*resource.Resource, error) {
+	_, inCluster := os.LookupEnv("KUBERNETES_SERVICE_HOST")
+
+	podName := os.Getenv("K8S_POD_NAME")
+	namespace := os.Getenv("K8S_NAMESPACE_NAME")
+	if inCluster && podName == "" {
+		podName, _ = os.Hostname()
+	}
+	if inCluster && namespace == "" {
+		if data, err := os.ReadFile(serviceAccountNamespace); err == nil {
+			namespace = strings.TrimSpace(string(data))
+		}
+	}
+
+	var attrs []attribute.KeyValue
+	for _, attr := range []attribute.KeyValue{
+		semconv.K8SPodName(podName),
+		semconv.K8SNamespaceName(namespace),
+		semconv.K8SPodUID(os.Getenv("K8S_POD_UID")),
+		semconv.K8SNodeName(os.Getenv("K8S_NODE_NAME")),
+	} {
+		if attr.Value.AsString() != "" {
+			attrs = append(attrs, attr)
+		}
+	}
+	if len(attrs) == 0 {
+		return resource.Empty(), nil
+	}
+	// Schemaless, so it merges with detectors using other semconv versions
+	return resource.NewSchemaless(attrs...), nil
+}
@@ -0,0 +1,356 @@
+package observability
+
+import (
+	"context"
+	"math"
+	"runtime/metrics"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Kinds of exported runtime metrics
+const (
+	runtimeGauge = iota
+	runtimeCounter
+	// runtimeQuantiles turns a runtime histogram into quantile gauges over
+	// the last runtimeQuantileWindow
+	runtimeQuantiles
+)
+
+// runtimeMetric maps a runtime/metrics sample onto an exported metric. Both
+// the OTLP instruments and the Prometheus collector are built from this
+// table and read the same snapshot, so the two report the same values under
+// the same names: go.memory.used in OTLP is go_memory_used_bytes in Prometheus,
+// as an OpenTelemetry collector would translate it.
+type runtimeMetric struct {
+	// keys are runtime/metrics names; the first one the runtime supports is read
+	keys        []string
+	name        string
+	description string
+	unit        string
+	kind        int
+	// class labels metrics split by memory class, such as heap/objects
+	class string
+}
+
+var runtimeMetricDefinitions = []runtimeMetric{
+	{keys: []string{"/sched/goroutines:goroutines"}, name: "go.goroutine.count", unit: "{goroutine}",
+		description: "Goroutines that currently exist", kind: runtimeGauge},
+	{keys: []string{"/sched/gomaxprocs:threads"}, name: "go.processor.limit", unit: "{thread}",
+		description: "Operating system threads that can execute Go code at once (GOMAXPROCS)", kind: runtimeGauge},
+	{keys: []string{"/sched/latencies:seconds"}, name: "go.schedule.latency", unit: "s",
+		description: "Time goroutines spent runnable before running, over the last one to two minutes", kind: runtimeQuantiles},
+	{keys: []string{"/gc/cycles/total:gc-cycles"}, name: "go.gc.cycles", unit: "{cycle}",
+		description: "Completed GC cycles", kind: runtimeCounter},
+	{keys: []string{"/sched/pauses/total/gc:seconds", "/gc/pauses:seconds"}, name: "go.gc.pause", unit: "s",
+		description: "Stop-the-world pauses caused by the GC, over the last one to two minutes", kind: runtimeQuantiles},
+	{keys: []string{"/cpu/classes/gc/total:cpu-seconds"}, name: "go.gc.cpu", unit: "s",
+		description: "Estimated CPU time spent on GC", kind: runtimeCounter},
+	{keys: []string{"/gc/heap/goal:bytes"}, name: "go.memory.gc.goal", unit: "By",
+		description: "Heap size the GC aims to stay under this cycle", kind: runtimeGauge},
+	{keys: []string{"/gc/heap/live:bytes"}, name: "go.memory.live", unit: "By",
+		description: "Heap memory marked live by the previous GC", kind: runtimeGauge},
+	{keys: []string{"/gc/heap/allocs:bytes"}, name: "go.memory.allocated", unit: "By",
+		description: "Bytes allocated on the heap", kind: runtimeCounter},
+	{keys: []string{"/gc/heap/allocs:objects"}, name: "go.memory.allocations", unit: "{allocation}",
+		description: "Objects allocated on the heap", kind: runtimeCounter},
+	{keys: []string{"/gc/gomemlimit:bytes"}, name: "go.memory.limit", unit: "By",
+		description: "Go runtime memory limit (GOMEMLIMIT)", kind: runtimeGauge},
+	{keys: []string{"/gc/gogc:percent"}, name: "go.config.gogc", unit: "%",
+		description: "Heap growth that triggers a GC (GOGC)", kind: runtimeGauge},
+}
+
+// memoryClassPrefix selects the memory classes, exported together as go.memory.used
+const memoryClassPrefix = "/memory/classes/"
+
+// runtimeQuantileValues are the quantiles reported for runtime histograms;
+// 1 is the maximum
+var runtimeQuantileValues = []float64{0.5, 0.9, 0.99, 1}
+
+const (
+	// runtimeQuantileWindow is the period runtime histogram quantiles cover, at least
+	runtimeQuantileWindow = time.Minute
+	// runtimeSnapshotMaxAge lets an OTLP collection and a Prometheus scrape at
+	// the same moment share one read of the runtime
+	runtimeSnapshotMaxAge = time.Second
+)
+
+// runtimeStats is the snapshot source shared by both exports
+var runtimeStats = newRuntimeReader()
+
+func init() {
+	// The default collector reads runtime.MemStats instead, at other times
+	prometheus.Unregister(collectors.NewGoCollector())
+	prometheus.MustRegister(runtimeCollector{reader: runtimeStats})
+}
+
+// runtimeReader reads the supported runtime metrics, caching the result
+type runtimeReader struct {
+	// metrics are the supported ones, each with the single key it reads
+	metrics []runtimeMetric
+
+	mu       sync.Mutex
+	snapshot runtimeSnapshot
+	readAt   time.Time
+	// baseline holds the histogram counts at the start of the quantile
+	// window, and pending those of the next one
+	baseline  map[string][]uint64
+	pending   map[string][]uint64
+	pendingAt time.Time
+}
+
+// runtimeSnapshot is one read of the runtime, indexed like runtimeReader.metrics
+type runtimeSnapshot struct {
+	values []float64
+	// quantiles are nil for histograms without events in the window
+	quantiles [][]float64
+}
+
+func newRuntimeReader() *runtimeReader {
+	supported := make(map[string]bool)
+	var classes []runtimeMetric
+	for _, desc := range metrics.All() {
+		supported[desc.Name] = true
+		if strings.HasPrefix(desc.Name, memoryClassPrefix) && desc.Name != memoryClassPrefix+"total:bytes" {
+			class := strings.TrimSuffix(strings.TrimPrefix(desc.Name, memoryClassPrefix), ":bytes")
+			classes = append(classes, runtimeMetric{keys: []string{desc.Name}, name: "go.memory.used", unit: "By",
+				description: "Memory mapped by the Go runtime, by class", kind: runtimeGauge, class: class})
+		}
+	}
+
+	r := &runtimeReader{}
+	for _, m := range append(append([]runtimeMetric{}, runtimeMetricDefinitions...), classes...) {
+		for _, key := range m.keys {
+			if supported[key] {
+				m.keys = []string{key}
+				r.metrics = append(r.metrics, m)
+				break
+			}
+		}
+	}
+	return r
+}
+
+// read returns a snapshot no older than runtimeSnapshotMaxAge
+func (r *runtimeReader) read() runtimeSnapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(r.readAt) < runtimeSnapshotMaxAge {
+		return r.snapshot
+	}
+
+	samples := make([]metrics.Sample, len(r.metrics))
+	for i, m := range r.metrics {
+		samples[i].Name = m.keys[0]
+	}
+	metrics.Read(samples)
+
+	next := runtimeSnapshot{
+		values:    make([]float64, len(samples)),
+		quantiles: make([][]float64, len(samples)),
+	}
+	current := make(map[string][]uint64)
+	for i, sample := range samples {
+		switch sample.Value.Kind() {
+		case metrics.KindUint64:
+			next.values[i] = float64(sample.Value.Uint64())
+		case metrics.KindFloat64:
+			next.values[i] = sample.Value.Float64()
+		case metrics.KindFloat64Histogram:
+			hist := sample.Value.Float64Histogram()
+			counts := append([]uint64(nil), hist.Counts...)
+			current[sample.Name] = counts
+			next.quantiles[i] = histogramQuantiles(counts, r.baseline[sample.Name], hist.Buckets)
+		}
+	}
+
+	// Each full window moves the baseline forward by one, so quantiles cover
+	// between one and two windows of events, and everything until the first
+	if r.pending == nil || now.Sub(r.pendingAt) >= runtimeQuantileWindow {
+		r.baseline = r.pending
+		r.pending, r.pendingAt = current, now
+	}
+	r.snapshot, r.readAt = next, now
+	return next
+}
+
+// histogramQuantiles estimates runtimeQuantileValues from the events counted
+// since baseline, as the upper bound of the bucket holding each quantile
+func histogramQuantiles(counts, baseline []uint64, buckets []float64) []float64 {
+	delta := make([]uint64, len(counts))
+	var total uint64
+	for i, count := range counts {
+		if i < len(baseline) {
+			count -= baseline[i]
+		}
+		delta[i] = count
+		total += count
+	}
+	if total == 0 {
+		return nil
+	}
+
+	quantiles := make([]float64, len(runtimeQuantileValues))
+	for qi, q := range runtimeQuantileValues {
+		rank := uint64(math.Ceil(q * float64(total)))
+		var seen uint64
+		for i, count := range delta {
+			seen += count
+			if count > 0 && seen >= rank {
+				quantiles[qi] = bucketBound(buckets, i)
+				break
+			}
+		}
+	}
+	return quantiles
+}
+
+// bucketBound is the upper bound of bucket i, or its lower bound when the
+// bucket is unbounded
+func bucketBound(buckets []float64, i int) float64 {
+	if upper := buckets[i+1]; !math.IsInf(upper, 1) {
+		return upper
+	}
+	return buckets[i]
+}
+
+// registerRuntimeMetrics creates an observable instrument per runtime metric,
+// all observed in one callback from a single snapshot
+func registerRuntimeMetrics(meter metric.Meter) error {
+	reader := runtimeStats
+
+	floatGauges := make(map[string]metric.Float64ObservableGauge)
+	floatCounters := make(map[string]metric.Float64ObservableCounter)
+	var instruments []metric.Observable
+	for _, m := range reader.metrics {
+		if _, ok := floatGauges[m.name]; ok {
+			continue
+		}
+		if _, ok := floatCounters[m.name]; ok {
+			continue
+		}
+		if m.kind == runtimeCounter {
+			counter, err := meter.Float64ObservableCounter(m.name,
+				metric.WithDescription(m.description), metric.WithUnit(m.unit))
+			if err != nil {
+				return err
+			}
+			floatCounters[m.name] = counter
+			instruments = append(instruments, counter)
+			continue
+		}
+		gauge, err := meter.Float64ObservableGauge(m.name,
+			metric.WithDescription(m.description), metric.WithUnit(m.unit))
+		if err != nil {
+			return err
+		}
+		floatGauges[m.name] = gauge
+		instruments = append(instruments, gauge)
+	}
+
+	quantileAttrs := make([]metric.ObserveOption, len(runtimeQuantileValues))
+	for i, q := range runtimeQuantileValues {
+		quantileAttrs[i] = metric.WithAttributes(attribute.Float64("quantile", q))
+	}
+
+	_, err := meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		snapshot := reader.read()
+		for i, m := range reader.metrics {
+			switch m.kind {
+			case runtimeCounter:
+				o.ObserveFloat64(floatCounters[m.name], snapshot.values[i])
+			case runtimeQuantiles:
+				for qi, value := range snapshot.quantiles[i] {
+					o.ObserveFloat64(floatGauges[m.name], value, quantileAttrs[qi])
+				}
+			default:
+				if m.class != "" {
+					o.ObserveFloat64(floatGauges[m.name], snapshot.values[i],
+						metric.WithAttributes(attribute.String("class", m.class)))
+					continue
+				}
+				o.ObserveFloat64(floatGauges[m.name], snapshot.values[i])
+			}
+		}
+		return nil
+	}, instruments...)
+	return err
+}
+
+// runtimeCollector exports the runtime metrics on /metrics
+type runtimeCollector struct {
+	reader *runtimeReader
+}
+
+func (c runtimeCollector) descs() map[string]*prometheus.Desc {
+	descs := make(map[string]*prometheus.Desc)
+	for _, m := range c.reader.metrics {
+		if _, ok := descs[m.name]; ok {
+			continue
+		}
+		var labels []string
+		switch {
+		case m.class != "":
+			labels = []string{"class"}
+		case m.kind == runtimeQuantiles:
+			labels = []string{"quantile"}
+		}
+		descs[m.name] = prometheus.NewDesc(prometheusName(m), m.description, labels, nil)
+	}
+	return descs
+}
+
+func (c runtimeCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range c.descs() {
+		ch <- desc
+	}
+}
+
+func (c runtimeCollector) Collect(ch chan<- prometheus.Metric) {
+	descs := c.descs()
+	snapshot := c.reader.read()
+	for i, m := range c.reader.metrics {
+		desc := descs[m.name]
+		switch {
+		case m.kind == runtimeCounter:
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, snapshot.values[i])
+		case m.kind == runtimeQuantiles:
+			for qi, value := range snapshot.quantiles[i] {
+				ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value,
+					strconv.FormatFloat(runtimeQuantileValues[qi], 'g', -1, 64))
+			}
+		case m.class != "":
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, snapshot.values[i], m.class)
+		default:
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, snapshot.values[i])
+		}
+	}
+}
+
+// prometheusName translates an OpenTelemetry name the way the collector's
+// Prometheus exporter does: dots become underscores, the unit is appended
+// and counters end in _total
+func prometheusName(m runtimeMetric) string {
+	name := strings.ReplaceAll(m.name, ".", "_")
+	switch m.unit {
+	case "By":
+		name += "_bytes"
+	case "s":
+		name += "_seconds"
+	case "%":
+		name += "_percent"
+	}
+	if m.kind == runtimeCounter {
+		name += "_total"
+	}
+	return name
+}
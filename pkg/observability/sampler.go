@@ -0,0 +1,40 @@
+package observability
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// ratioSampler is a TraceIDRatioBased sampler whose ratio can be changed at
+// runtime. It decides for root spans; InitTracer wraps it in ParentBased.
+type ratioSampler struct {
+	current atomic.Pointer[sdktrace.Sampler]
+}
+
+var sampler = newRatioSampler(1)
+
+func newRatioSampler(ratio float64) *ratioSampler {
+	s := &ratioSampler{}
+	s.set(ratio)
+	return s
+}
+
+func (s *ratioSampler) set(ratio float64) {
+	next := sdktrace.TraceIDRatioBased(ratio)
+	s.current.Store(&next)
+}
+
+func (s *ratioSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	return (*s.current.Load()).ShouldSample(p)
+}
+
+func (s *ratioSampler) Description() string {
+	return fmt.Sprintf("Dynamic{%s}", (*s.current.Load()).Description())
+}
+
+// SetSampleRatio changes the fraction of traces sampled without restarting the tracer provider
+func SetSampleRatio(ratio float64) {
+	sampler.set(ratio)
+}
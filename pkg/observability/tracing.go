@@ -1,91 +1,96 @@
 package observability
 
 import (
+	"apisecurityplatform/pkg/config"
+	"apisecurityplatform/pkg/logging"
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"net/http"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
 	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
 	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
-	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
-	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 	"go.opentelemetry.io/otel/trace"
-	"google.golang.org/grpc"
 )
 
+var logger = logging.For("observability")
+
 const (
 	serviceName = "api-security-platform"
 )
 
-func InitTracer() (func(), error) {
+// InitTracer sets up the global tracer and meter providers exporting as
+// configured. Exporters connect lazily and retry in the background, so a
+// missing collector never delays startup. The returned function flushes and
+// stops both providers.
+func InitTracer(cfg config.TracingConfig, environment string) (func(context.Context) error, error) {
 	ctx := context.Background()
 
-	exporter, err := otlptracegrpc.New(
-		ctx,
-		otlptracegrpc.WithInsecure(),
-		otlptracegrpc.WithEndpoint("jaeger:4317"),
-		otlptracegrpc.WithDialOption(grpc.WithBlock()),
-	)
+	// W3C trace context and baggage, extracted from requests and injected
+	// into upstream calls
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	res, err := newResource(ctx, environment)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+		return nil, fmt.Errorf("failed to create resource: %w", err)
 	}
 
-	res, err := resource.New(ctx,
-		resource.WithAttributes(
-			semconv.ServiceName(serviceName),
-			attribute.String("environment", "development"),
-		),
-	)
+	exporter, err := newSpanExporter(ctx, cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create resource: %w", err)
+		return nil, fmt.Errorf("failed to create span exporter: %w", err)
 	}
 
-	tp := sdktrace.NewTracerProvider(
-		sdktrace.WithBatcher(exporter),
+	// Traces started upstream keep their sampling decision, so a trace is
+	// either complete or absent; only root spans are sampled by ratio
+	SetSampleRatio(cfg.SampleRatio)
+	tpOptions := []sdktrace.TracerProviderOption{
 		sdktrace.WithResource(res),
-		sdktrace.WithSampler(sdktrace.AlwaysSample()),
-	)
-
+		sdktrace.WithSampler(sdktrace.ParentBased(sampler)),
+	}
+	// Without an exporter spans are still created, so trace IDs keep
+	// correlating logs and propagating to upstreams
+	if exporter != nil {
+		spanExporter = &statusExporter{SpanExporter: exporter}
+		tpOptions = append(tpOptions, sdktrace.WithBatcher(spanExporter))
+	}
+	tp := sdktrace.NewTracerProvider(tpOptions...)
 	otel.SetTracerProvider(tp)
 
-	log.Printf("OTLP gRPC tracer initialized with service name: %s", serviceName)
-
-	metricExporter, err := otlpmetricgrpc.New(
-		ctx,
-		otlpmetricgrpc.WithInsecure(),
-		otlpmetricgrpc.WithEndpoint("jaeger:4317"),
-		otlpmetricgrpc.WithDialOption(grpc.WithBlock()),
-	)
+	reader, err := newMetricReader(ctx, cfg)
 	if err != nil {
+		tp.Shutdown(ctx)
 		return nil, fmt.Errorf("failed to create metrics exporter: %w", err)
 	}
-
-	mp := sdkmetric.NewMeterProvider(
-		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter)),
-		sdkmetric.WithResource(res),
-	)
-
+	mpOptions := []sdkmetric.Option{sdkmetric.WithResource(res)}
+	if reader != nil {
+		mpOptions = append(mpOptions, sdkmetric.WithReader(reader))
+	}
+	mp := sdkmetric.NewMeterProvider(mpOptions...)
 	otel.SetMeterProvider(mp)
 
-	log.Printf("OTLP gRPC metrics initialized with service name: %s", serviceName)
+	logger.Info("Telemetry initialized", "service", serviceName, "exporter", cfg.Exporter,
+		"protocol", cfg.Protocol, "endpoint", cfg.Endpoint, "sample_ratio", cfg.SampleRatio)
 
-	// Initialize runtime metrics
-	if err := InitRuntimeMetrics(ctx); err != nil {
+	// Observed on each export, from the same reads as /metrics
+	if err := registerRuntimeMetrics(GetMeter()); err != nil {
 		return nil, fmt.Errorf("failed to initialize runtime metrics: %w", err)
 	}
 
-	return func() {
+	return func(ctx context.Context) error {
+		// Spans first, so spans recorded while draining are still exported
+		var errs []error
 		if err := tp.Shutdown(ctx); err != nil {
-			log.Printf("Error shutting down tracer provider: %v", err)
+			errs = append(errs, fmt.Errorf("tracer provider: %w", err))
 		}
 		if err := mp.Shutdown(ctx); err != nil {
-			log.Printf("Error shutting down meter provider: %v", err)
+			errs = append(errs, fmt.Errorf("meter provider: %w", err))
 		}
+		return errors.Join(errs...)
 	}, nil
 }
 
@@ -96,3 +101,20 @@ func GetTracer() trace.Tracer {
 func GetMeter() metric.Meter {
 	return otel.Meter(serviceName)
 }
+
+// knownMethods are the methods semantic conventions allow as span names
+var knownMethods = map[string]bool{
+	http.MethodConnect: true, http.MethodDelete: true, http.MethodGet: true,
+	http.MethodHead: true, http.MethodOptions: true, http.MethodPatch: true,
+	http.MethodPost: true, http.MethodPut: true, http.MethodTrace: true,
+}
+
+// HTTPMethod returns the name of an HTTP span for method and its method
+// attributes. Unknown methods are reported as _OTHER, so arbitrary client
+// input does not become span names.
+func HTTPMethod(method string) (string, []attribute.KeyValue) {
+	if knownMethods[method] {
+		return method, []attribute.KeyValue{semconv.HTTPRequestMethodKey.String(method)}
+	}
+	return "HTTP", []attribute.KeyValue{semconv.HTTPRequestMethodOther, semconv.HTTPRequestMethodOriginal(method)}
+}
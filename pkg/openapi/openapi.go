@@ -0,0 +1,243 @@
+// Package openapi validates requests, and optionally responses, against an
+// OpenAPI document
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"apisecurityplatform/pkg/logging"
+	"apisecurityplatform/pkg/observability"
+
+	"github.com/getkin/kin-openapi/openapi2"
+	"github.com/getkin/kin-openapi/openapi2conv"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gin-gonic/gin"
+	"github.com/oasdiff/yaml"
+)
+
+var logger = logging.For("openapi")
+
+func init() {
+	// Keep schema errors to one line, without echoing the schema and the
+	// offending value back to the client
+	openapi3.SchemaErrorDetailsDisabled = true
+}
+
+// Load reads an OpenAPI 3 or Swagger 2 document from a JSON or YAML file
+func Load(path string) (*openapi3.T, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return doc, nil
+}
+
+// Parse decodes an OpenAPI 3 or Swagger 2 document in JSON or YAML. Swagger
+// 2 documents, such as the ones swag generates, are converted to OpenAPI 3.
+func Parse(data []byte) (*openapi3.T, error) {
+	var version struct {
+		Swagger string `json:"swagger"`
+	}
+	if err := yaml.Unmarshal(data, &version); err != nil {
+		return nil, err
+	}
+	if version.Swagger == "" {
+		return openapi3.NewLoader().LoadFromData(data)
+	}
+
+	var doc2 openapi2.T
+	if err := yaml.Unmarshal(data, &doc2); err != nil {
+		return nil, err
+	}
+	return openapi2conv.ToV3(&doc2)
+}
+
+// Validator checks traffic against one document
+type Validator struct {
+	// name identifies the document in logs and metrics
+	name              string
+	router            routers.Router
+	validateResponses bool
+	maxBodyBytes      int64
+}
+
+// New creates a validator for doc. Its paths are matched below basePath,
+// whatever servers the document declares.
+func New(name string, doc *openapi3.T, basePath string, validateResponses bool, maxBodyBytes int64) (*Validator, error) {
+	// Examples in generated documents are often not valid against their
+	// schemas, and do not affect validation
+	if err := doc.Validate(context.Background(), openapi3.DisableExamplesValidation()); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	doc.Servers = openapi3.Servers{{URL: basePath}}
+	for _, item := range doc.Paths.Map() {
+		item.Servers = nil
+	}
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &Validator{
+		name:              name,
+		router:            router,
+		validateResponses: validateResponses,
+		maxBodyBytes:      maxBodyBytes,
+	}, nil
+}
+
+// Middleware rejects requests that do not match the document with a 400
+// listing the violations. Requests for paths or methods the document does
+// not describe are let through, since it may cover only part of the API.
+// Bodies above maxBodyBytes are not buffered, and go unchecked.
+func (v *Validator) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route, params, err := v.router.FindRoute(c.Request)
+		if err != nil {
+			c.Next()
+			return
+		}
+		operation := c.Request.Method + " " + route.Path
+
+		options := &openapi3filter.Options{
+			MultiError: true,
+			// Credentials are checked by the auth middlewares
+			AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+		}
+		if c.Request.Body != nil && c.Request.Body != http.NoBody {
+			body, err := io.ReadAll(io.LimitReader(c.Request.Body, v.maxBodyBytes+1))
+			if err != nil {
+				c.Request.Body.Close()
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+				return
+			}
+			if int64(len(body)) > v.maxBodyBytes {
+				// Only the parameters of a body too large to buffer are checked.
+				// How large a body may be is up to the handler or route.
+				options.ExcludeRequestBody = true
+				c.Request.Body = struct {
+					io.Reader
+					io.Closer
+				}{io.MultiReader(bytes.NewReader(body), c.Request.Body), c.Request.Body}
+			} else {
+				c.Request.Body.Close()
+				c.Request.Body = io.NopCloser(bytes.NewReader(body))
+				c.Request.GetBody = func() (io.ReadCloser, error) {
+					return io.NopCloser(bytes.NewReader(body)), nil
+				}
+			}
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    c.Request,
+			PathParams: params,
+			Route:      route,
+			Options:    options,
+		}
+		if err := openapi3filter.ValidateRequest(c.Request.Context(), input); err != nil {
+			observability.OpenAPIRequestViolations.WithLabelValues(v.name, operation).Inc()
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":      "Request does not match the API specification",
+				"violations": violations(err),
+			})
+			return
+		}
+
+		if !v.validateResponses {
+			c.Next()
+			return
+		}
+		w := &responseRecorder{ResponseWriter: c.Writer, limit: v.maxBodyBytes}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+		v.checkResponse(c.Request.Context(), input, operation, w)
+	}
+}
+
+// checkResponse logs a response that drifted from the document. The
+// response has already been sent; drift is only reported.
+func (v *Validator) checkResponse(ctx context.Context, input *openapi3filter.RequestValidationInput, operation string, w *responseRecorder) {
+	if w.hijacked {
+		return
+	}
+	output := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: input,
+		Status:                 w.Status(),
+		Header:                 w.Header(),
+		Options: &openapi3filter.Options{
+			MultiError:            true,
+			IncludeResponseStatus: true,
+			ExcludeResponseBody:   w.overflow,
+		},
+	}
+	output.SetBodyBytes(w.body.Bytes())
+	if err := openapi3filter.ValidateResponse(ctx, output); err != nil {
+		observability.OpenAPIResponseDrift.WithLabelValues(v.name, operation).Inc()
+		logger.WarnContext(ctx, "OpenAPI response drift",
+			"spec", v.name, "operation", operation, "status", w.Status(), "error", err)
+	}
+}
+
+// Violation is one way a request failed validation
+type Violation struct {
+	// In is where the violation is: path, query, header, cookie or body
+	In string `json:"in"`
+	// Name is the parameter name, or a JSON pointer into the body
+	Name   string `json:"name,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// violations flattens a validation error into the violations it reports
+func violations(err error) []Violation {
+	if multi, ok := err.(openapi3.MultiError); ok {
+		var all []Violation
+		for _, e := range multi {
+			all = append(all, violations(e)...)
+		}
+		return all
+	}
+	requestErr, ok := err.(*openapi3filter.RequestError)
+	if !ok {
+		return []Violation{{In: "request", Reason: err.Error()}}
+	}
+
+	in, name := "body", ""
+	if requestErr.Parameter != nil {
+		in, name = requestErr.Parameter.In, requestErr.Parameter.Name
+	}
+	if requestErr.Err == nil {
+		return []Violation{{In: in, Name: name, Reason: requestErr.Reason}}
+	}
+	causes := []error{requestErr.Err}
+	if multi, ok := requestErr.Err.(openapi3.MultiError); ok {
+		causes = multi
+	}
+
+	var all []Violation
+	for _, cause := range causes {
+		violation := Violation{In: in, Name: name, Reason: cause.Error()}
+		var schemaErr *openapi3.SchemaError
+		if errors.As(cause, &schemaErr) {
+			violation.Reason = schemaErr.Reason
+			if pointer := schemaErr.JSONPointer(); in == "body" && len(pointer) > 0 {
+				violation.Name = "/" + strings.Join(pointer, "/")
+			}
+		}
+		all = append(all, violation)
+	}
+	return all
+}
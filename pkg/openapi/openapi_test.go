@@ -0,0 +1,185 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"apisecurityplatform/pkg/observability"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+const testSpec = `
+openapi: 3.0.3
+info: {title: Orders, version: "1"}
+paths:
+  /orders:
+    post:
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [quantity]
+              properties:
+                quantity: {type: integer, minimum: 1}
+      parameters:
+        - {name: expand, in: query, schema: {type: string, enum: [items, customer]}}
+      responses:
+        "201":
+          description: Created
+          content:
+            application/json:
+              schema:
+                type: object
+                required: [id]
+                properties:
+                  id: {type: string}
+`
+
+// newTestValidator serves the handler behind a validator of testSpec. The
+// handler answers 201 with response and echoes the request body it read in
+// the X-Body header.
+func newTestValidator(t *testing.T, name string, validateResponses bool, maxBodyBytes int64, response string) *gin.Engine {
+	t.Helper()
+	doc, err := Parse([]byte(testSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := New(name, doc, "/api", validateResponses, maxBodyBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.Use(v.Middleware())
+	handler := func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.Header("X-Body", string(body))
+		c.Data(http.StatusCreated, "application/json", []byte(response))
+	}
+	r.POST("/api/orders", handler)
+	r.GET("/api/orders", handler)
+	r.POST("/api/users", handler)
+	return r
+}
+
+func counterValue(c prometheus.Counter) float64 {
+	var m dto.Metric
+	c.Write(&m)
+	return m.GetCounter().GetValue()
+}
+
+func serve(r http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestMiddlewarePassesUndocumentedRequests(t *testing.T) {
+	r := newTestValidator(t, t.Name(), false, 1<<20, `{"id":"1"}`)
+	tests := []struct {
+		name, method, target string
+	}{
+		{"undocumented path", http.MethodPost, "/api/users?expand=anything"},
+		{"undocumented method", http.MethodGet, "/api/orders?expand=anything"},
+	}
+	for _, tt := range tests {
+		w := serve(r, tt.method, tt.target, `{"quantity":0}`)
+		if w.Code != http.StatusCreated || w.Header().Get("X-Body") != `{"quantity":0}` {
+			t.Errorf("%s: status %d, body seen %q", tt.name, w.Code, w.Header().Get("X-Body"))
+		}
+	}
+}
+
+func TestMiddlewareViolations(t *testing.T) {
+	r := newTestValidator(t, t.Name(), false, 1<<20, `{"id":"1"}`)
+
+	w := serve(r, http.MethodPost, "/api/orders?expand=items", `{"quantity":2}`)
+	if w.Code != http.StatusCreated || w.Header().Get("X-Body") != `{"quantity":2}` {
+		t.Fatalf("valid request: status %d, body seen %q", w.Code, w.Header().Get("X-Body"))
+	}
+
+	w = serve(r, http.MethodPost, "/api/orders?expand=invoices", `{"quantity":0}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid request: status %d, want 400", w.Code)
+	}
+	var body struct {
+		Error      string      `json:"error"`
+		Violations []Violation `json:"violations"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Violations) != 2 {
+		t.Fatalf("violations = %+v, want the query parameter and the body field", body.Violations)
+	}
+	if v := body.Violations[0]; v.In != "query" || v.Name != "expand" || v.Reason == "" {
+		t.Errorf("first violation = %+v, want the expand query parameter", v)
+	}
+	if v := body.Violations[1]; v.In != "body" || v.Name != "/quantity" || v.Reason == "" {
+		t.Errorf("second violation = %+v, want /quantity in the body", v)
+	}
+	if got := counterValue(observability.OpenAPIRequestViolations.WithLabelValues(t.Name(), "POST /orders")); got != 1 {
+		t.Errorf("violations counted = %g, want 1", got)
+	}
+
+	if w := serve(r, http.MethodPost, "/api/orders", `{"quantity":`); w.Code != http.StatusBadRequest {
+		t.Errorf("malformed body: status %d, want 400", w.Code)
+	}
+}
+
+func TestMiddlewareLargeBodies(t *testing.T) {
+	r := newTestValidator(t, t.Name(), false, 16, `{"id":"1"}`)
+	large := `{"quantity":0,"note":"` + strings.Repeat("x", 64) + `"}`
+
+	// A body above the limit is not validated, and reaches the handler whole
+	w := serve(r, http.MethodPost, "/api/orders", large)
+	if w.Code != http.StatusCreated || w.Header().Get("X-Body") != large {
+		t.Errorf("large body: status %d, body seen %q", w.Code, w.Header().Get("X-Body"))
+	}
+	// Its parameters still are
+	if w := serve(r, http.MethodPost, "/api/orders?expand=invoices", large); w.Code != http.StatusBadRequest {
+		t.Errorf("large body with an invalid parameter: status %d, want 400", w.Code)
+	}
+	if w := serve(r, http.MethodPost, "/api/orders", `{"quantity":0}`); w.Code != http.StatusBadRequest {
+		t.Errorf("small invalid body: status %d, want 400", w.Code)
+	}
+}
+
+func TestMiddlewareResponseDrift(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		drift    float64
+	}{
+		{"matching response", `{"id":"1"}`, 0},
+		{"drifted response", `{"id":1}`, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestValidator(t, t.Name(), true, 1<<20, tt.response)
+			w := serve(r, http.MethodPost, "/api/orders", `{"quantity":1}`)
+			// Drift is only reported; the response is sent as is
+			if w.Code != http.StatusCreated || !bytes.Equal(w.Body.Bytes(), []byte(tt.response)) {
+				t.Errorf("status %d, body %q", w.Code, w.Body)
+			}
+			if got := counterValue(observability.OpenAPIResponseDrift.WithLabelValues(t.Name(), "POST /orders")); got != tt.drift {
+				t.Errorf("drift counted = %g, want %g", got, tt.drift)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package openapi
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+
+	"github.com/gin-gonic/gin"
+)
+
+// responseRecorder keeps a copy of the response body, up to a limit, while
+// passing it through to the client
+type responseRecorder struct {
+	gin.ResponseWriter
+	body  bytes.Buffer
+	limit int64
+	// overflow is set once the body exceeds limit and the copy is dropped
+	overflow bool
+	hijacked bool
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	if !w.overflow {
+		if int64(w.body.Len()+len(b)) > w.limit {
+			w.overflow = true
+			w.body = bytes.Buffer{}
+		} else {
+			w.body.Write(b)
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.hijacked = true
+	return w.ResponseWriter.Hijack()
+}
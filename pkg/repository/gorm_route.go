@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"apisecurityplatform/pkg/models"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// GormRouteRepository is a RouteRepository backed by GORM
+type GormRouteRepository struct {
+	db *gorm.DB
+}
+
+// NewGormRouteRepository creates a RouteRepository using the given connection
+func NewGormRouteRepository(db *gorm.DB) *GormRouteRepository {
+	return &GormRouteRepository{db: db}
+}
+
+func (r *GormRouteRepository) List(ctx context.Context) ([]models.Route, error) {
+	var routes []models.Route
+	if err := r.db.WithContext(ctx).Order("name").Find(&routes).Error; err != nil {
+		return nil, err
+	}
+	return routes, nil
+}
+
+func (r *GormRouteRepository) FindByName(ctx context.Context, name string) (*models.Route, error) {
+	return findRoute(r.db.WithContext(ctx), name)
+}
+
+func (r *GormRouteRepository) Create(ctx context.Context, route *models.Route, changedBy uint) error {
+	return translateError(r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Continue the numbering of a deleted route with the same name
+		var latest int
+		if err := tx.Model(&models.RouteVersion{}).
+			Where("route_name = ?", route.Name).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&latest).Error; err != nil {
+			return err
+		}
+
+		route.Version = latest + 1
+		if err := tx.Create(route).Error; err != nil {
+			return err
+		}
+		return recordVersion(tx, route, models.RouteActionCreate, changedBy)
+	}))
+}
+
+func (r *GormRouteRepository) Update(ctx context.Context, route *models.Route, action string, changedBy uint) error {
+	return translateError(r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		existing, err := findRoute(tx, route.Name)
+		if err != nil {
+			return err
+		}
+
+		route.ID = existing.ID
+		route.CreatedAt = existing.CreatedAt
+		route.Version = existing.Version + 1
+		if err := tx.Save(route).Error; err != nil {
+			return err
+		}
+		return recordVersion(tx, route, action, changedBy)
+	}))
+}
+
+func (r *GormRouteRepository) Delete(ctx context.Context, name string, changedBy uint) error {
+	return translateError(r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		existing, err := findRoute(tx, name)
+		if err != nil {
+			return err
+		}
+
+		// Deleted for good, so the name can be reused; the history keeps the definition
+		if err := tx.Unscoped().Delete(existing).Error; err != nil {
+			return err
+		}
+		existing.Version++
+		return recordVersion(tx, existing, models.RouteActionDelete, changedBy)
+	}))
+}
+
+func (r *GormRouteRepository) Versions(ctx context.Context, name string) ([]models.RouteVersion, error) {
+	var versions []models.RouteVersion
+	if err := r.db.WithContext(ctx).
+		Where("route_name = ?", name).
+		Order("version").
+		Find(&versions).Error; err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, ErrNotFound
+	}
+	return versions, nil
+}
+
+func (r *GormRouteRepository) FindVersion(ctx context.Context, name string, version int) (*models.RouteVersion, error) {
+	var v models.RouteVersion
+	if err := r.db.WithContext(ctx).
+		Where("route_name = ? AND version = ?", name, version).
+		First(&v).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &v, nil
+}
+
+func findRoute(db *gorm.DB, name string) (*models.Route, error) {
+	var route models.Route
+	if err := db.Where("name = ?", name).First(&route).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &route, nil
+}
+
+func recordVersion(tx *gorm.DB, route *models.Route, action string, changedBy uint) error {
+	return tx.Create(&models.RouteVersion{
+		RouteName:  route.Name,
+		Version:    route.Version,
+		Action:     action,
+		Definition: route.Definition,
+		ChangedBy:  changedBy,
+	}).Error
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"apisecurityplatform/pkg/models"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormUsageRepository is a UsageRepository backed by GORM
+type GormUsageRepository struct {
+	db *gorm.DB
+}
+
+// NewGormUsageRepository creates a UsageRepository using the given connection
+func NewGormUsageRepository(db *gorm.DB) *GormUsageRepository {
+	return &GormUsageRepository{db: db}
+}
+
+func (r *GormUsageRepository) Add(ctx context.Context, records []models.UsageRecord) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, record := range records {
+			record.ID = 0
+			// Increment in the database, so instances adding to the same
+			// record concurrently do not overwrite each other
+			upsert := clause.OnConflict{
+				Columns: []clause.Column{{Name: "period"}, {Name: "user_id"}, {Name: "api_key_id"}, {Name: "route"}},
+				DoUpdates: clause.Assignments(map[string]any{
+					"requests":      gorm.Expr("usage_records.requests + ?", record.Requests),
+					"client_errors": gorm.Expr("usage_records.client_errors + ?", record.ClientErrors),
+					"server_errors": gorm.Expr("usage_records.server_errors + ?", record.ServerErrors),
+					"bytes_in":      gorm.Expr("usage_records.bytes_in + ?", record.BytesIn),
+					"bytes_out":     gorm.Expr("usage_records.bytes_out + ?", record.BytesOut),
+				}),
+			}
+			if err := tx.Clauses(upsert).Create(&record).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *GormUsageRepository) Query(ctx context.Context, filter UsageFilter) ([]models.UsageRecord, error) {
+	query := r.db.WithContext(ctx).Model(&models.UsageRecord{})
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.APIKeyID != 0 {
+		query = query.Where("api_key_id = ?", filter.APIKeyID)
+	}
+	if filter.Route != "" {
+		query = query.Where("route = ?", filter.Route)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("period >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("period < ?", filter.To)
+	}
+
+	var records []models.UsageRecord
+	if err := query.Order("period, user_id, api_key_id, route").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (r *GormUsageRepository) DeleteBefore(ctx context.Context, t time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("period < ?", t).Delete(&models.UsageRecord{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"apisecurityplatform/pkg/models"
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryUserRepository is an in-memory UserRepository for tests and local runs
+type MemoryUserRepository struct {
+	mu     sync.RWMutex
+	nextID uint
+	users  map[uint]models.User
+}
+
+// NewMemoryUserRepository creates an empty in-memory UserRepository
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{users: make(map[uint]models.User)}
+}
+
+func (r *MemoryUserRepository) Create(_ context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.users {
+		if existing.Email == user.Email || existing.Username == user.Username {
+			return ErrDuplicate
+		}
+	}
+
+	r.nextID++
+	now := time.Now()
+	user.ID = r.nextID
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	if user.Role == "" {
+		user.Role = "user"
+	}
+	r.users[user.ID] = *user
+	return nil
+}
+
+func (r *MemoryUserRepository) FindByID(_ context.Context, id uint) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r *MemoryUserRepository) FindByEmail(_ context.Context, email string) (*models.User, error) {
+	return r.findBy(func(u models.User) bool { return u.Email == email })
+}
+
+func (r *MemoryUserRepository) FindByUsername(_ context.Context, username string) (*models.User, error) {
+	return r.findBy(func(u models.User) bool { return u.Username == username })
+}
+
+func (r *MemoryUserRepository) Delete(_ context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.users, id)
+	return nil
+}
+
+func (r *MemoryUserRepository) findBy(match func(models.User) bool) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if match(user) {
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// MemoryAPIKeyRepository is an in-memory APIKeyRepository for tests and local runs
+type MemoryAPIKeyRepository struct {
+	mu     sync.RWMutex
+	nextID uint
+	keys   map[uint]models.APIKey
+}
+
+// NewMemoryAPIKeyRepository creates an empty in-memory APIKeyRepository
+func NewMemoryAPIKeyRepository() *MemoryAPIKeyRepository {
+	return &MemoryAPIKeyRepository{keys: make(map[uint]models.APIKey)}
+}
+
+func (r *MemoryAPIKeyRepository) Create(_ context.Context, key *models.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.keys {
+		if existing.Key == key.Key {
+			return ErrDuplicate
+		}
+	}
+
+	r.nextID++
+	now := time.Now()
+	key.ID = r.nextID
+	key.CreatedAt = now
+	key.UpdatedAt = now
+	r.keys[key.ID] = *key
+	return nil
+}
+
+func (r *MemoryAPIKeyRepository) List(_ context.Context) ([]models.APIKey, error) {
+	return r.filter(func(models.APIKey) bool { return true }), nil
+}
+
+func (r *MemoryAPIKeyRepository) FindByID(_ context.Context, id uint) (*models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key, ok := r.keys[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &key, nil
+}
+
+func (r *MemoryAPIKeyRepository) ListByUser(_ context.Context, userID uint) ([]models.APIKey, error) {
+	return r.filter(func(k models.APIKey) bool { return k.UserID == userID }), nil
+}
+
+func (r *MemoryAPIKeyRepository) UpdateLastUsed(_ context.Context, id uint, lastUsedAt int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[id]
+	if !ok {
+		return ErrNotFound
+	}
+	key.LastUsedAt = &lastUsedAt
+	key.UpdatedAt = time.Now()
+	r.keys[id] = key
+	return nil
+}
+
+func (r *MemoryAPIKeyRepository) DeleteForUser(_ context.Context, id, userID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[id]
+	if !ok || key.UserID != userID {
+		return ErrNotFound
+	}
+	delete(r.keys, id)
+	return nil
+}
+
+func (r *MemoryAPIKeyRepository) filter(match func(models.APIKey) bool) []models.APIKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var keys []models.APIKey
+	for _, key := range r.keys {
+		if match(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"apisecurityplatform/pkg/models"
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryRouteRepository is an in-memory RouteRepository for tests and local runs
+type MemoryRouteRepository struct {
+	mu       sync.RWMutex
+	nextID   uint
+	routes   map[string]models.Route
+	versions map[string][]models.RouteVersion
+}
+
+// NewMemoryRouteRepository creates an empty in-memory RouteRepository
+func NewMemoryRouteRepository() *MemoryRouteRepository {
+	return &MemoryRouteRepository{
+		routes:   make(map[string]models.Route),
+		versions: make(map[string][]models.RouteVersion),
+	}
+}
+
+func (r *MemoryRouteRepository) List(_ context.Context) ([]models.Route, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	routes := make([]models.Route, 0, len(r.routes))
+	for _, route := range r.routes {
+		routes = append(routes, route)
+	}
+	sort.Slice(routes, func(i, j int) bool { return routes[i].Name < routes[j].Name })
+	return routes, nil
+}
+
+func (r *MemoryRouteRepository) FindByName(_ context.Context, name string) (*models.Route, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	route, ok := r.routes[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &route, nil
+}
+
+func (r *MemoryRouteRepository) Create(_ context.Context, route *models.Route, changedBy uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.routes[route.Name]; ok {
+		return ErrDuplicate
+	}
+
+	r.nextID++
+	now := time.Now()
+	route.ID = r.nextID
+	route.CreatedAt = now
+	route.UpdatedAt = now
+	route.Version = len(r.versions[route.Name]) + 1
+	r.routes[route.Name] = *route
+	r.record(route, models.RouteActionCreate, changedBy)
+	return nil
+}
+
+func (r *MemoryRouteRepository) Update(_ context.Context, route *models.Route, action string, changedBy uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.routes[route.Name]
+	if !ok {
+		return ErrNotFound
+	}
+
+	route.ID = existing.ID
+	route.CreatedAt = existing.CreatedAt
+	route.UpdatedAt = time.Now()
+	route.Version = existing.Version + 1
+	r.routes[route.Name] = *route
+	r.record(route, action, changedBy)
+	return nil
+}
+
+func (r *MemoryRouteRepository) Delete(_ context.Context, name string, changedBy uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.routes[name]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(r.routes, name)
+	existing.Version++
+	r.record(&existing, models.RouteActionDelete, changedBy)
+	return nil
+}
+
+func (r *MemoryRouteRepository) Versions(_ context.Context, name string) ([]models.RouteVersion, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	versions, ok := r.versions[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]models.RouteVersion(nil), versions...), nil
+}
+
+func (r *MemoryRouteRepository) FindVersion(_ context.Context, name string, version int) (*models.RouteVersion, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, v := range r.versions[name] {
+		if v.Version == version {
+			return &v, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// record appends a version; the caller holds the write lock
+func (r *MemoryRouteRepository) record(route *models.Route, action string, changedBy uint) {
+	versions := r.versions[route.Name]
+	r.versions[route.Name] = append(versions, models.RouteVersion{
+		ID:         uint(len(versions) + 1),
+		RouteName:  route.Name,
+		Version:    route.Version,
+		Action:     action,
+		Definition: route.Definition,
+		ChangedBy:  changedBy,
+		CreatedAt:  time.Now(),
+	})
+}
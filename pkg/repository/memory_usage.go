@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"apisecurityplatform/pkg/models"
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// usageKey identifies a usage record
+type usageKey struct {
+	period   int64
+	userID   uint
+	apiKeyID uint
+	route    string
+}
+
+// MemoryUsageRepository is an in-memory UsageRepository for tests and local runs
+type MemoryUsageRepository struct {
+	mu      sync.RWMutex
+	nextID  uint
+	records map[usageKey]models.UsageRecord
+}
+
+// NewMemoryUsageRepository creates an empty in-memory UsageRepository
+func NewMemoryUsageRepository() *MemoryUsageRepository {
+	return &MemoryUsageRepository{records: make(map[usageKey]models.UsageRecord)}
+}
+
+func (r *MemoryUsageRepository) Add(_ context.Context, records []models.UsageRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, record := range records {
+		key := usageKey{period: record.Period.Unix(), userID: record.UserID, apiKeyID: record.APIKeyID, route: record.Route}
+		stored, ok := r.records[key]
+		if !ok {
+			r.nextID++
+			stored = models.UsageRecord{
+				ID:       r.nextID,
+				Period:   record.Period,
+				UserID:   record.UserID,
+				APIKeyID: record.APIKeyID,
+				Route:    record.Route,
+			}
+		}
+		stored.Add(record)
+		r.records[key] = stored
+	}
+	return nil
+}
+
+func (r *MemoryUsageRepository) Query(_ context.Context, filter UsageFilter) ([]models.UsageRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var records []models.UsageRecord
+	for _, record := range r.records {
+		switch {
+		case filter.UserID != 0 && record.UserID != filter.UserID,
+			filter.APIKeyID != 0 && record.APIKeyID != filter.APIKeyID,
+			filter.Route != "" && record.Route != filter.Route,
+			!filter.From.IsZero() && record.Period.Before(filter.From),
+			!filter.To.IsZero() && !record.Period.Before(filter.To):
+			continue
+		}
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		switch {
+		case !a.Period.Equal(b.Period):
+			return a.Period.Before(b.Period)
+		case a.UserID != b.UserID:
+			return a.UserID < b.UserID
+		case a.APIKeyID != b.APIKeyID:
+			return a.APIKeyID < b.APIKeyID
+		}
+		return a.Route < b.Route
+	})
+	return records, nil
+}
+
+func (r *MemoryUsageRepository) DeleteBefore(_ context.Context, t time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for key, record := range r.records {
+		if record.Period.Before(t) {
+			delete(r.records, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"apisecurityplatform/pkg/models"
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when a lookup or delete matches no record
+	ErrNotFound = errors.New("record not found")
+	// ErrDuplicate is returned when a unique field such as an email is reused
+	ErrDuplicate = errors.New("duplicate record")
+)
+
+// UserRepository provides access to stored users
+type UserRepository interface {
+	Create(ctx context.Context, user *models.User) error
+	FindByID(ctx context.Context, id uint) (*models.User, error)
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	FindByUsername(ctx context.Context, username string) (*models.User, error)
+	Delete(ctx context.Context, id uint) error
+}
+
+// APIKeyRepository provides access to stored API keys
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *models.APIKey) error
+	List(ctx context.Context) ([]models.APIKey, error)
+	FindByID(ctx context.Context, id uint) (*models.APIKey, error)
+	ListByUser(ctx context.Context, userID uint) ([]models.APIKey, error)
+	UpdateLastUsed(ctx context.Context, id uint, lastUsedAt int64) error
+	DeleteForUser(ctx context.Context, id, userID uint) error
+}
+
+// RouteRepository persists gateway routes along with the history of every
+// change, so that a route can be rolled back to an earlier definition
+type RouteRepository interface {
+	List(ctx context.Context) ([]models.Route, error)
+	FindByName(ctx context.Context, name string) (*models.Route, error)
+	// Create stores a new route, numbering it after any earlier route of the same name
+	Create(ctx context.Context, route *models.Route, changedBy uint) error
+	// Update replaces the definition of an existing route and bumps its version
+	Update(ctx context.Context, route *models.Route, action string, changedBy uint) error
+	Delete(ctx context.Context, name string, changedBy uint) error
+	Versions(ctx context.Context, name string) ([]models.RouteVersion, error)
+	FindVersion(ctx context.Context, name string, version int) (*models.RouteVersion, error)
+}
+
+// UsageFilter selects usage records. Zero fields match every record; From is
+// inclusive and To exclusive.
+type UsageFilter struct {
+	UserID   uint
+	APIKeyID uint
+	Route    string
+	From     time.Time
+	To       time.Time
+}
+
+// UsageRepository stores metered usage in hourly records
+type UsageRepository interface {
+	// Add increments the counters of the stored records matching each of
+	// records by period, user, API key and route, creating missing ones
+	Add(ctx context.Context, records []models.UsageRecord) error
+	// Query returns the matching records ordered by period
+	Query(ctx context.Context, filter UsageFilter) ([]models.UsageRecord, error)
+	// DeleteBefore removes the records of periods before t
+	DeleteBefore(ctx context.Context, t time.Time) (int64, error)
+}
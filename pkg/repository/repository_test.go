@@ -0,0 +1,429 @@
+package repository
+
+import (
+	"apisecurityplatform/pkg/cache"
+	"apisecurityplatform/pkg/config"
+	"apisecurityplatform/pkg/database"
+	"apisecurityplatform/pkg/models"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The behavior suite runs every repository against the in-memory fakes and
+// against the database named by DB_DRIVER, configured through the service's
+// own DB_* variables. CI runs it once with SQLite and once with Postgres.
+// Without DB_DRIVER, a fresh SQLite file is used.
+
+// repositories is one implementation of every repository, and of the
+// response cache backend, which shares the database
+type repositories struct {
+	users  UserRepository
+	keys   APIKeyRepository
+	usage  UsageRepository
+	routes RouteRepository
+	cache  cache.Backend
+}
+
+// forEachBackend runs test against the in-memory repositories and the
+// GORM ones on the configured database
+func forEachBackend(t *testing.T, test func(t *testing.T, repos repositories)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, repositories{
+			users:  NewMemoryUserRepository(),
+			keys:   NewMemoryAPIKeyRepository(),
+			usage:  NewMemoryUsageRepository(),
+			routes: NewMemoryRouteRepository(),
+			cache:  cache.NewMemoryBackend(1 << 20),
+		})
+	})
+	settings := testDatabaseSettings(t)
+	t.Run(settings.Driver, func(t *testing.T) {
+		db := openTestDB(t, settings)
+		test(t, repositories{
+			users:  NewGormUserRepository(db),
+			keys:   NewGormAPIKeyRepository(db),
+			usage:  NewGormUsageRepository(db),
+			routes: NewGormRouteRepository(db),
+			cache:  cache.NewGormBackend(db),
+		})
+	})
+}
+
+func testDatabaseSettings(t *testing.T) config.DatabaseConfig {
+	t.Helper()
+	if os.Getenv("DB_DRIVER") == "" {
+		return config.DatabaseConfig{Driver: database.DriverSQLite, Path: filepath.Join(t.TempDir(), "test.db")}
+	}
+	cfg, err := config.LoadConfig(nil)
+	if err != nil {
+		t.Fatalf("load database settings: %v", err)
+	}
+	return cfg.Database
+}
+
+// openTestDB connects and migrates, then empties the tables so that tests
+// sharing a database start alike
+func openTestDB(t *testing.T, settings config.DatabaseConfig) *gorm.DB {
+	t.Helper()
+	db, err := database.Open(settings, nil)
+	if err != nil {
+		t.Fatalf("open %s database: %v", settings.Driver, err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	// Children first, for the foreign keys
+	tables := []any{&models.APIKey{}, &models.RouteVersion{}, &models.Route{}, &models.CacheEntry{}, &models.UsageRecord{}, &models.User{}}
+	for _, table := range tables {
+		if err := db.Unscoped().Where("1 = 1").Delete(table).Error; err != nil {
+			t.Fatalf("empty %T: %v", table, err)
+		}
+	}
+	return db
+}
+
+func createUser(t *testing.T, users UserRepository, name string) *models.User {
+	t.Helper()
+	user := &models.User{Username: name, Email: name + "@example.com", Password: "hash"}
+	if err := users.Create(context.Background(), user); err != nil {
+		t.Fatalf("create user %s: %v", name, err)
+	}
+	return user
+}
+
+func TestUserRepository(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos repositories) {
+		ctx := context.Background()
+		alice := createUser(t, repos.users, "alice")
+		if alice.ID == 0 {
+			t.Fatal("Create did not assign an ID")
+		}
+		if alice.Role != "user" {
+			t.Errorf("role = %q, want the default user", alice.Role)
+		}
+
+		lookups := map[string]func() (*models.User, error){
+			"FindByID":       func() (*models.User, error) { return repos.users.FindByID(ctx, alice.ID) },
+			"FindByEmail":    func() (*models.User, error) { return repos.users.FindByEmail(ctx, "alice@example.com") },
+			"FindByUsername": func() (*models.User, error) { return repos.users.FindByUsername(ctx, "alice") },
+		}
+		for name, lookup := range lookups {
+			if user, err := lookup(); err != nil || user.ID != alice.ID {
+				t.Errorf("%s = %v, %v; want user %d", name, user, err, alice.ID)
+			}
+		}
+
+		missing := map[string]func() (*models.User, error){
+			"FindByID":       func() (*models.User, error) { return repos.users.FindByID(ctx, alice.ID+100) },
+			"FindByEmail":    func() (*models.User, error) { return repos.users.FindByEmail(ctx, "bob@example.com") },
+			"FindByUsername": func() (*models.User, error) { return repos.users.FindByUsername(ctx, "bob") },
+		}
+		for name, lookup := range missing {
+			if _, err := lookup(); !errors.Is(err, ErrNotFound) {
+				t.Errorf("%s of a missing user: error %v, want ErrNotFound", name, err)
+			}
+		}
+
+		duplicates := []models.User{
+			{Username: "alice2", Email: "alice@example.com", Password: "hash"},
+			{Username: "alice", Email: "alice2@example.com", Password: "hash"},
+		}
+		for _, user := range duplicates {
+			if err := repos.users.Create(ctx, &user); !errors.Is(err, ErrDuplicate) {
+				t.Errorf("Create %s <%s>: error %v, want ErrDuplicate", user.Username, user.Email, err)
+			}
+		}
+
+		if err := repos.users.Delete(ctx, alice.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := repos.users.FindByID(ctx, alice.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("FindByID after Delete: error %v, want ErrNotFound", err)
+		}
+		if err := repos.users.Delete(ctx, alice.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("second Delete: error %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestAPIKeyRepository(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos repositories) {
+		ctx := context.Background()
+		alice := createUser(t, repos.users, "alice")
+		bob := createUser(t, repos.users, "bob")
+
+		key := &models.APIKey{UserID: alice.ID, Name: "ci", Key: "hash-1", Scopes: "orders:read"}
+		if err := repos.keys.Create(ctx, key); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if err := repos.keys.Create(ctx, &models.APIKey{UserID: bob.ID, Name: "ci", Key: "hash-2"}); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if err := repos.keys.Create(ctx, &models.APIKey{UserID: bob.ID, Name: "copy", Key: "hash-1"}); !errors.Is(err, ErrDuplicate) {
+			t.Errorf("Create with a reused key: error %v, want ErrDuplicate", err)
+		}
+
+		found, err := repos.keys.FindByID(ctx, key.ID)
+		if err != nil || found.Name != "ci" || found.Scopes != "orders:read" {
+			t.Errorf("FindByID = %+v, %v", found, err)
+		}
+		if _, err := repos.keys.FindByID(ctx, key.ID+100); !errors.Is(err, ErrNotFound) {
+			t.Errorf("FindByID of a missing key: error %v, want ErrNotFound", err)
+		}
+		if all, err := repos.keys.List(ctx); err != nil || len(all) != 2 {
+			t.Errorf("List = %d keys, %v; want 2", len(all), err)
+		}
+		if owned, err := repos.keys.ListByUser(ctx, alice.ID); err != nil || len(owned) != 1 || owned[0].ID != key.ID {
+			t.Errorf("ListByUser = %+v, %v; want key %d", owned, err, key.ID)
+		}
+
+		if err := repos.keys.UpdateLastUsed(ctx, key.ID, 1700000000); err != nil {
+			t.Fatalf("UpdateLastUsed: %v", err)
+		}
+		if found, _ := repos.keys.FindByID(ctx, key.ID); found == nil || found.LastUsedAt == nil || *found.LastUsedAt != 1700000000 {
+			t.Errorf("LastUsedAt not updated: %+v", found)
+		}
+
+		if err := repos.keys.DeleteForUser(ctx, key.ID, bob.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("DeleteForUser by another user: error %v, want ErrNotFound", err)
+		}
+		if err := repos.keys.DeleteForUser(ctx, key.ID, alice.ID); err != nil {
+			t.Fatalf("DeleteForUser: %v", err)
+		}
+		if _, err := repos.keys.FindByID(ctx, key.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("FindByID after delete: error %v, want ErrNotFound", err)
+		}
+		if err := repos.keys.DeleteForUser(ctx, key.ID, alice.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("second DeleteForUser: error %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestUsageRepositoryAdd(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos repositories) {
+		ctx := context.Background()
+		hour := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+		record := func(period time.Time, route string, requests int64) models.UsageRecord {
+			return models.UsageRecord{
+				Period: period, UserID: 1, APIKeyID: 2, Route: route,
+				Requests: requests, ClientErrors: 1, ServerErrors: 0, BytesIn: 10 * requests, BytesOut: 100 * requests,
+			}
+		}
+
+		// Adding to an existing record increments it, within one call and across calls
+		if err := repos.usage.Add(ctx, []models.UsageRecord{record(hour, "orders", 3), record(hour, "users", 1)}); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+		if err := repos.usage.Add(ctx, []models.UsageRecord{record(hour, "orders", 2), record(hour.Add(time.Hour), "orders", 5)}); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+
+		records, err := repos.usage.Query(ctx, UsageFilter{UserID: 1, Route: "orders"})
+		if err != nil {
+			t.Fatalf("Query: %v", err)
+		}
+		if len(records) != 2 {
+			t.Fatalf("Query = %d records, want 2: %+v", len(records), records)
+		}
+		first := records[0]
+		if !first.Period.Equal(hour) || first.Requests != 5 || first.ClientErrors != 2 || first.BytesIn != 50 || first.BytesOut != 500 {
+			t.Errorf("accumulated record = %+v, want 5 requests, 2 client errors, 50 bytes in, 500 out at %s", first, hour)
+		}
+		if !records[1].Period.Equal(hour.Add(time.Hour)) || records[1].Requests != 5 {
+			t.Errorf("second period = %+v, want 5 requests", records[1])
+		}
+
+		if inRange, _ := repos.usage.Query(ctx, UsageFilter{From: hour, To: hour.Add(time.Hour)}); len(inRange) != 2 {
+			t.Errorf("Query of the first hour = %d records, want 2", len(inRange))
+		}
+		if other, _ := repos.usage.Query(ctx, UsageFilter{UserID: 9}); len(other) != 0 {
+			t.Errorf("Query of another user = %d records, want 0", len(other))
+		}
+
+		deleted, err := repos.usage.DeleteBefore(ctx, hour.Add(time.Hour))
+		if err != nil || deleted != 2 {
+			t.Errorf("DeleteBefore = %d, %v; want 2", deleted, err)
+		}
+		if remaining, _ := repos.usage.Query(ctx, UsageFilter{}); len(remaining) != 1 {
+			t.Errorf("%d records left, want 1", len(remaining))
+		}
+	})
+}
+
+func TestRouteRepository(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos repositories) {
+		ctx := context.Background()
+		v1, v2 := `{"path_prefix":"/orders"}`, `{"path_prefix":"/v2/orders"}`
+
+		route := &models.Route{Name: "orders", Definition: v1}
+		if err := repos.routes.Create(ctx, route, 1); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if route.ID == 0 || route.Version != 1 {
+			t.Errorf("created route = %+v, want an ID and version 1", route)
+		}
+		if err := repos.routes.Create(ctx, &models.Route{Name: "orders", Definition: v2}, 1); !errors.Is(err, ErrDuplicate) {
+			t.Errorf("Create of an existing name: error %v, want ErrDuplicate", err)
+		}
+		if err := repos.routes.Create(ctx, &models.Route{Name: "billing", Definition: v1}, 1); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+
+		if err := repos.routes.Update(ctx, &models.Route{Name: "orders", Definition: v2}, models.RouteActionUpdate, 2); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		if err := repos.routes.Update(ctx, &models.Route{Name: "users", Definition: v2}, models.RouteActionUpdate, 2); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Update of a missing route: error %v, want ErrNotFound", err)
+		}
+
+		// A rollback stores an earlier definition as a new version
+		first, err := repos.routes.FindVersion(ctx, "orders", 1)
+		if err != nil || first.Definition != v1 {
+			t.Fatalf("FindVersion(1) = %+v, %v", first, err)
+		}
+		if _, err := repos.routes.FindVersion(ctx, "orders", 9); !errors.Is(err, ErrNotFound) {
+			t.Errorf("FindVersion of a missing version: error %v, want ErrNotFound", err)
+		}
+		rolledBack := &models.Route{Name: "orders", Definition: first.Definition}
+		if err := repos.routes.Update(ctx, rolledBack, models.RouteActionRollback, 3); err != nil {
+			t.Fatalf("rollback: %v", err)
+		}
+		if current, err := repos.routes.FindByName(ctx, "orders"); err != nil || current.Version != 3 || current.Definition != v1 || current.ID != route.ID {
+			t.Errorf("after rollback = %+v, %v; want version 3 of route %d with the first definition", current, err, route.ID)
+		}
+		if routes, err := repos.routes.List(ctx); err != nil || len(routes) != 2 || routes[0].Name != "billing" || routes[1].Name != "orders" {
+			t.Errorf("List = %+v, %v; want billing and orders", routes, err)
+		}
+
+		// Deleting records a version, and a route reusing the name continues the numbering
+		if err := repos.routes.Delete(ctx, "orders", 4); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := repos.routes.FindByName(ctx, "orders"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("FindByName after Delete: error %v, want ErrNotFound", err)
+		}
+		if err := repos.routes.Delete(ctx, "orders", 4); !errors.Is(err, ErrNotFound) {
+			t.Errorf("second Delete: error %v, want ErrNotFound", err)
+		}
+		recreated := &models.Route{Name: "orders", Definition: v2}
+		if err := repos.routes.Create(ctx, recreated, 5); err != nil {
+			t.Fatalf("Create after Delete: %v", err)
+		}
+		if recreated.Version != 5 {
+			t.Errorf("recreated route version = %d, want 5", recreated.Version)
+		}
+
+		versions, err := repos.routes.Versions(ctx, "orders")
+		if err != nil {
+			t.Fatalf("Versions: %v", err)
+		}
+		want := []struct {
+			action     string
+			definition string
+			changedBy  uint
+		}{
+			{models.RouteActionCreate, v1, 1},
+			{models.RouteActionUpdate, v2, 2},
+			{models.RouteActionRollback, v1, 3},
+			{models.RouteActionDelete, v1, 4},
+			{models.RouteActionCreate, v2, 5},
+		}
+		if len(versions) != len(want) {
+			t.Fatalf("Versions = %+v, want %d", versions, len(want))
+		}
+		for i, v := range versions {
+			if v.Version != i+1 || v.Action != want[i].action || v.Definition != want[i].definition || v.ChangedBy != want[i].changedBy {
+				t.Errorf("version %d = %+v, want %+v", i+1, v, want[i])
+			}
+		}
+		if _, err := repos.routes.Versions(ctx, "users"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Versions of an unknown route: error %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestCacheBackend(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos repositories) {
+		ctx := context.Background()
+		now := time.Now().Truncate(time.Second)
+		entry := func(route, uri string, tags ...string) *cache.Entry {
+			return &cache.Entry{
+				Status: 200, Body: []byte(route + uri), Route: route, URI: uri, Tags: tags,
+				StoredAt: now, FreshUntil: now.Add(time.Minute), ExpiresAt: now.Add(time.Hour),
+			}
+		}
+		set := func(key string, e *cache.Entry) {
+			t.Helper()
+			if err := repos.cache.Set(ctx, key, e); err != nil {
+				t.Fatalf("Set %s: %v", key, err)
+			}
+		}
+		exists := func(key string) bool {
+			t.Helper()
+			e, err := repos.cache.Get(ctx, key)
+			if err != nil {
+				t.Fatalf("Get %s: %v", key, err)
+			}
+			return e != nil
+		}
+
+		set("orders-1", entry("orders", "/orders/1", "order-1", "orders"))
+		set("orders-1-alice", entry("orders", "/orders/1"))
+		set("orders-2", entry("orders", "/orders/2", "orders"))
+		set("order_list", entry("orders", "/orders", "order_list"))
+		set("users-1", entry("users", "/users/1", "orders"))
+		expired := entry("users", "/users/2")
+		expired.ExpiresAt = now.Add(-time.Second)
+		set("users-2", expired)
+
+		got, err := repos.cache.Get(ctx, "orders-1")
+		if err != nil || got == nil || string(got.Body) != "orders/orders/1" || got.URI != "/orders/1" ||
+			len(got.Tags) != 2 || !got.FreshUntil.Equal(now.Add(time.Minute)) {
+			t.Errorf("Get = %+v, %v", got, err)
+		}
+		if exists("users-2") {
+			t.Error("expired entry returned")
+		}
+		if exists("missing") {
+			t.Error("missing key returned an entry")
+		}
+
+		// Replacing an entry keeps a single one under the key
+		set("orders-2", entry("orders", "/orders/2"))
+		if n, err := repos.cache.PurgeTag(ctx, "orders"); err != nil || n != 2 {
+			t.Errorf("PurgeTag(orders) = %d, %v; want orders-1 and users-1", n, err)
+		}
+		if exists("orders-1") || exists("users-1") || !exists("orders-2") {
+			t.Error("PurgeTag removed the wrong entries")
+		}
+		// LIKE wildcards in a tag are matched literally
+		if n, _ := repos.cache.PurgeTag(ctx, "order%"); n != 0 {
+			t.Errorf("PurgeTag(order%%) = %d, want 0", n)
+		}
+		if n, _ := repos.cache.PurgeTag(ctx, "order_list"); n != 1 {
+			t.Errorf("PurgeTag(order_list) = %d, want 1", n)
+		}
+
+		set("orders-1", entry("orders", "/orders/1"))
+		if n, err := repos.cache.PurgeURI(ctx, "orders", "/orders/1"); err != nil || n != 2 {
+			t.Errorf("PurgeURI = %d, %v; want every variant of /orders/1", n, err)
+		}
+		if exists("orders-1-alice") || !exists("orders-2") {
+			t.Error("PurgeURI removed the wrong entries")
+		}
+		if n, err := repos.cache.PurgeRoute(ctx, "orders"); err != nil || n != 1 {
+			t.Errorf("PurgeRoute = %d, %v; want 1", n, err)
+		}
+		if exists("orders-2") {
+			t.Error("PurgeRoute left an entry of the route")
+		}
+	})
+}
@@ -0,0 +1,35 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+)
+
+// FileProvider reads secrets from files, such as Kubernetes secret volumes
+// or Docker secrets under /run/secrets. The name is the file path.
+type FileProvider struct{}
+
+func (FileProvider) Get(_ context.Context, path string) (string, error) {
+	data, err := os.ReadFile(strings.TrimPrefix(path, "//"))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	// Secret files are usually written with a trailing newline
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// EnvProvider reads secrets from environment variables. The name is the variable.
+type EnvProvider struct{}
+
+func (EnvProvider) Get(_ context.Context, name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
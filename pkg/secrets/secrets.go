@@ -0,0 +1,76 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNotFound is returned when a provider has no value for the requested name
+var ErrNotFound = errors.New("secret not found")
+
+// Provider resolves a secret by name. What a name means depends on the
+// provider: a file path, an environment variable, or a Vault path and field.
+type Provider interface {
+	Get(ctx context.Context, name string) (string, error)
+}
+
+// Resolver maps reference schemes such as "file" or "vault" to providers.
+// A reference has the form "<scheme>:<name>"; any other value is a literal.
+type Resolver struct {
+	providers map[string]Provider
+}
+
+// NewResolver creates a Resolver with the file and env providers registered
+func NewResolver() *Resolver {
+	r := &Resolver{providers: make(map[string]Provider)}
+	r.Register("file", FileProvider{})
+	r.Register("env", EnvProvider{})
+	return r
+}
+
+// Register adds or replaces the provider for a scheme
+func (r *Resolver) Register(scheme string, provider Provider) {
+	r.providers[scheme] = provider
+}
+
+// Resolve returns the secret a reference points to, or the value itself if it
+// is not a reference
+func (r *Resolver) Resolve(ctx context.Context, value string) (string, error) {
+	scheme, name, ok := ParseReference(value)
+	if !ok {
+		return value, nil
+	}
+
+	provider, ok := r.providers[scheme]
+	if !ok {
+		return "", fmt.Errorf("no secret provider registered for %q", scheme)
+	}
+
+	secret, err := provider.Get(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s secret %q: %w", scheme, name, err)
+	}
+	return secret, nil
+}
+
+// ParseReference splits a "<scheme>:<name>" secret reference
+func ParseReference(value string) (scheme, name string, ok bool) {
+	scheme, name, found := strings.Cut(value, ":")
+	if !found || name == "" {
+		return "", "", false
+	}
+	switch scheme {
+	case "file", "env", "vault":
+		return scheme, name, true
+	}
+	return "", "", false
+}
+
+// IsReference reports whether value points at a secret provider rather than
+// holding the secret itself
+func IsReference(value string) bool {
+	_, _, ok := ParseReference(value)
+	return ok
+}
@@ -0,0 +1,70 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		value  string
+		scheme string
+		name   string
+		ok     bool
+	}{
+		{"file:/run/secrets/jwt", "file", "/run/secrets/jwt", true},
+		{"env:JWT_SECRET", "env", "JWT_SECRET", true},
+		{"vault:apiplatform/jwt#secret", "vault", "apiplatform/jwt#secret", true},
+		// Literal values may contain colons
+		{"postgres://user:pass@db:5432/app", "", "", false},
+		{"p@ss:word", "", "", false},
+		{"Env:JWT_SECRET", "", "", false},
+		{"env:", "", "", false},
+		{":JWT_SECRET", "", "", false},
+		{"plain-secret", "", "", false},
+		{"", "", "", false},
+	}
+	for _, tt := range tests {
+		scheme, name, ok := ParseReference(tt.value)
+		if scheme != tt.scheme || name != tt.name || ok != tt.ok {
+			t.Errorf("ParseReference(%q) = %q, %q, %v; want %q, %q, %v", tt.value, scheme, name, ok, tt.scheme, tt.name, tt.ok)
+		}
+		if IsReference(tt.value) != tt.ok {
+			t.Errorf("IsReference(%q) = %v, want %v", tt.value, !tt.ok, tt.ok)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	t.Setenv("SECRETS_TEST_VALUE", "from-env")
+	path := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(path, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	r := NewResolver()
+
+	tests := []struct {
+		value    string
+		want     string
+		notFound bool
+	}{
+		{"literal:with:colons", "literal:with:colons", false},
+		{"env:SECRETS_TEST_VALUE", "from-env", false},
+		{"file:" + path, "from-file", false},
+		{"env:SECRETS_TEST_MISSING", "", true},
+		{"file:" + path + ".missing", "", true},
+	}
+	for _, tt := range tests {
+		got, err := r.Resolve(context.Background(), tt.value)
+		if got != tt.want || errors.Is(err, ErrNotFound) != tt.notFound {
+			t.Errorf("Resolve(%q) = %q, %v; want %q, not found %v", tt.value, got, err, tt.want, tt.notFound)
+		}
+	}
+
+	if _, err := r.Resolve(context.Background(), "vault:apiplatform/jwt#secret"); err == nil {
+		t.Error("vault reference resolved without a registered provider")
+	}
+}
@@ -0,0 +1,104 @@
+package secrets
+
+import (
+	"apisecurityplatform/pkg/logging"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var logger = logging.For("secrets")
+
+// Value is a resolved secret that is re-resolved periodically. Callbacks
+// registered with OnChange run whenever the resolved value changes.
+type Value struct {
+	ref      string
+	resolver *Resolver
+	current  atomic.Pointer[string]
+
+	mu       sync.Mutex
+	onChange []func(string)
+}
+
+// Value resolves ref once and returns a Value that Refresh can update later
+func (r *Resolver) Value(ctx context.Context, ref string) (*Value, error) {
+	secret, err := r.Resolve(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &Value{ref: ref, resolver: r}
+	v.current.Store(&secret)
+	return v, nil
+}
+
+// Get returns the most recently resolved secret
+func (v *Value) Get() string {
+	return *v.current.Load()
+}
+
+// OnChange registers a callback run with the new secret after it rotates
+func (v *Value) OnChange(fn func(string)) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.onChange = append(v.onChange, fn)
+}
+
+// Refresh re-resolves the reference. A failed refresh keeps the last good value.
+func (v *Value) Refresh(ctx context.Context) error {
+	if !IsReference(v.ref) {
+		return nil
+	}
+
+	secret, err := v.resolver.Resolve(ctx, v.ref)
+	if err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if secret == v.Get() {
+		return nil
+	}
+	v.current.Store(&secret)
+	for _, fn := range v.onChange {
+		fn(secret)
+	}
+	return nil
+}
+
+// Refresher periodically refreshes a set of secret values
+type Refresher struct {
+	interval time.Duration
+	values   []*Value
+}
+
+// NewRefresher creates a Refresher running every interval; zero disables refresh
+func NewRefresher(interval time.Duration, values ...*Value) *Refresher {
+	return &Refresher{interval: interval, values: values}
+}
+
+// Start refreshes every value on each tick until ctx is cancelled
+func (r *Refresher) Start(ctx context.Context) {
+	if r.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(r.interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				for _, v := range r.values {
+					if err := v.Refresh(ctx); err != nil {
+						logger.Error("Failed to refresh secret", "error", err)
+					}
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,81 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// VaultProvider reads secrets from a Vault-compatible KV version 2 HTTP API.
+// Names have the form "<path>#<field>", e.g. "apiplatform/jwt#secret".
+type VaultProvider struct {
+	Address string
+	Mount   string
+	// Token returns the token sent with each request, so it can be rotated too
+	Token  func() string
+	Client *http.Client
+}
+
+// NewVaultProvider creates a VaultProvider for the KV engine mounted at mount
+func NewVaultProvider(address, mount string, token func() string) *VaultProvider {
+	return &VaultProvider{
+		Address: strings.TrimSuffix(address, "/"),
+		Mount:   strings.Trim(mount, "/"),
+		Token:   token,
+		Client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type vaultKVResponse struct {
+	Data struct {
+		Data map[string]any `json:"data"`
+	} `json:"data"`
+}
+
+func (p *VaultProvider) Get(ctx context.Context, name string) (string, error) {
+	path, field, ok := strings.Cut(name, "#")
+	if !ok || path == "" || field == "" {
+		return "", fmt.Errorf("vault secret %q must have the form <path>#<field>", name)
+	}
+
+	endpoint := fmt.Sprintf("%s/v1/%s/data/%s", p.Address, url.PathEscape(p.Mount), strings.Trim(path, "/"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	if p.Token != nil {
+		req.Header.Set("X-Vault-Token", p.Token())
+	}
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return "", ErrNotFound
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("vault returned %s", resp.Status)
+	}
+
+	var body vaultKVResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode vault response: %w", err)
+	}
+
+	value, ok := body.Data.Data[field]
+	if !ok {
+		return "", ErrNotFound
+	}
+	secret, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("vault field %q is not a string", field)
+	}
+	return secret, nil
+}
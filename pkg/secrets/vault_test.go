@@ -0,0 +1,111 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestVault serves the KV version 2 secrets of the "secret" mount,
+// recording the token and path of each request
+func newTestVault(t *testing.T, data map[string]string) (*httptest.Server, *[]*http.Request) {
+	t.Helper()
+	var requests []*http.Request
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		if r.Header.Get("X-Vault-Token") != "vault-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		body, ok := data[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &requests
+}
+
+func TestVaultProviderGet(t *testing.T) {
+	ts, requests := newTestVault(t, map[string]string{
+		"/v1/secret/data/apiplatform/jwt": `{"data":{"data":{"secret":"jwt-secret","rotations":3},"metadata":{"version":3}}}`,
+		"/v1/secret/data/broken":          `{"data":`,
+	})
+	p := NewVaultProvider(ts.URL+"/", "/secret/", func() string { return "vault-token" })
+
+	tests := []struct {
+		name     string
+		secret   string
+		want     string
+		notFound bool
+	}{
+		{"field", "apiplatform/jwt#secret", "jwt-secret", false},
+		{"surrounding slashes", "/apiplatform/jwt/#secret", "jwt-secret", false},
+		{"missing path", "apiplatform/db#password", "", true},
+		{"missing field", "apiplatform/jwt#password", "", true},
+		{"non-string field", "apiplatform/jwt#rotations", "", false},
+		{"malformed response", "broken#secret", "", false},
+		{"no field", "apiplatform/jwt", "", false},
+		{"empty field", "apiplatform/jwt#", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.Get(context.Background(), tt.secret)
+			if got != tt.want || errors.Is(err, ErrNotFound) != tt.notFound || (tt.want == "" && err == nil) {
+				t.Errorf("Get(%q) = %q, %v; want %q, not found %v", tt.secret, got, err, tt.want, tt.notFound)
+			}
+		})
+	}
+
+	for _, r := range *requests {
+		if r.Method != http.MethodGet || r.Header.Get("X-Vault-Token") != "vault-token" {
+			t.Errorf("request %s %s with token %q", r.Method, r.URL.Path, r.Header.Get("X-Vault-Token"))
+		}
+	}
+}
+
+func TestVaultProviderToken(t *testing.T) {
+	ts, requests := newTestVault(t, map[string]string{
+		"/v1/secret/data/jwt": `{"data":{"data":{"secret":"jwt-secret"}}}`,
+	})
+
+	// The token is read for every request, so a rotated one is used at once
+	token := "expired-token"
+	p := NewVaultProvider(ts.URL, "secret", func() string { return token })
+	if _, err := p.Get(context.Background(), "jwt#secret"); err == nil || !strings.Contains(err.Error(), "403") || errors.Is(err, ErrNotFound) {
+		t.Errorf("Get with a rejected token: error %v, want the 403 status", err)
+	}
+	token = "vault-token"
+	if got, err := p.Get(context.Background(), "jwt#secret"); err != nil || got != "jwt-secret" {
+		t.Errorf("Get with a rotated token = %q, %v", got, err)
+	}
+
+	// Without a token function no token header is sent
+	p.Token = nil
+	p.Get(context.Background(), "jwt#secret")
+	last := (*requests)[len(*requests)-1]
+	if _, sent := last.Header["X-Vault-Token"]; sent {
+		t.Error("token header sent without a token")
+	}
+}
+
+func TestResolveVault(t *testing.T) {
+	ts, _ := newTestVault(t, map[string]string{
+		"/v1/kv/data/db": `{"data":{"data":{"password":"db-password"}}}`,
+	})
+	r := NewResolver()
+	r.Register("vault", NewVaultProvider(ts.URL, "kv", func() string { return "vault-token" }))
+
+	if got, err := r.Resolve(context.Background(), "vault:db#password"); err != nil || got != "db-password" {
+		t.Errorf("Resolve = %q, %v", got, err)
+	}
+	if _, err := r.Resolve(context.Background(), "vault:db#user"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Resolve of a missing field: error %v, want ErrNotFound", err)
+	}
+}
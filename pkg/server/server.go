@@ -0,0 +1,97 @@
+package server
+
+import (
+	"apisecurityplatform/pkg/config"
+	"apisecurityplatform/pkg/logging"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var logger = logging.For("server")
+
+// New creates an http.Server for handler with the configured timeouts
+func New(name, addr string, handler http.Handler, cfg config.ServerConfig) *Server {
+	return &Server{
+		Name: name,
+		HTTP: &http.Server{
+			Addr:              addr,
+			Handler:           handler,
+			ReadTimeout:       cfg.ReadTimeout,
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			WriteTimeout:      cfg.WriteTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
+			MaxHeaderBytes:    cfg.MaxHeaderBytes,
+		},
+	}
+}
+
+// NewAdmin creates the admin listener's http.Server. It takes the public
+// server's timeouts except WriteTimeout: pprof profiles and traces stream for
+// as long as they were asked to run, which the write timeout would cut short.
+func NewAdmin(addr string, handler http.Handler, cfg config.ServerConfig) *Server {
+	s := New("admin", addr, handler, cfg)
+	s.HTTP.WriteTimeout = 0
+	return s
+}
+
+// Server is a named listener taking part in Run. It serves TLS when
+// HTTP.TLSConfig is set; certificates come from the TLSConfig itself.
+type Server struct {
+	Name string
+	HTTP *http.Server
+}
+
+func (s *Server) serve() error {
+	if s.HTTP.TLSConfig != nil {
+		return s.HTTP.ListenAndServeTLS("", "")
+	}
+	return s.HTTP.ListenAndServe()
+}
+
+// Run serves every server until ctx is cancelled or one of them fails, then
+// stops accepting connections and waits up to drainTimeout for in-flight
+// requests to complete
+func Run(ctx context.Context, drainTimeout time.Duration, servers ...*Server) error {
+	errs := make(chan error, len(servers))
+	for _, s := range servers {
+		go func(s *Server) {
+			logger.Info("Starting listener", "listener", s.Name, "address", s.HTTP.Addr)
+			if err := s.serve(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errs <- fmt.Errorf("%s listener: %w", s.Name, err)
+			}
+		}(s)
+	}
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		logger.Info("Shutdown signal received, draining connections")
+	case runErr = <-errs:
+		logger.Error("Listener failed, shutting down", "error", runErr)
+	}
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var shutdownErrs []error
+	for _, s := range servers {
+		wg.Add(1)
+		go func(s *Server) {
+			defer wg.Done()
+			if err := s.HTTP.Shutdown(drainCtx); err != nil {
+				mu.Lock()
+				shutdownErrs = append(shutdownErrs, fmt.Errorf("%s listener: %w", s.Name, err))
+				mu.Unlock()
+			}
+		}(s)
+	}
+	wg.Wait()
+
+	return errors.Join(append([]error{runErr}, shutdownErrs...)...)
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type shutdownStep struct {
+	name string
+	fn   func(context.Context) error
+}
+
+// Shutdown runs cleanup steps in the order they were added, each bounded by
+// the remaining time of a shared deadline
+type Shutdown struct {
+	steps []shutdownStep
+}
+
+// Add appends a named cleanup step
+func (s *Shutdown) Add(name string, fn func(context.Context) error) {
+	s.steps = append(s.steps, shutdownStep{name: name, fn: fn})
+}
+
+// Run executes every step, continuing past failures, and returns their errors
+func (s *Shutdown) Run(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var errs []error
+	for _, step := range s.steps {
+		if err := step.fn(ctx); err != nil {
+			logger.Error("Shutdown step failed", "step", step.name, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", step.name, err))
+			continue
+		}
+		logger.Info("Shutdown step complete", "step", step.name)
+	}
+	return errors.Join(errs...)
+}
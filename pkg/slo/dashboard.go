@@ -0,0 +1,138 @@
+package slo
+
+import (
+	"fmt"
+	"time"
+)
+
+// Dashboard generates a Grafana dashboard with a row per objective: the error
+// budget left over the SLO window, the burn rate over the alerting windows
+// and the SLI against its objective. It charts the series recorded by Rules,
+// so those have to be loaded into Prometheus.
+func (t *Tracker) Dashboard() map[string]any {
+	panels := []any{}
+	id := 0
+	nextID := func() int {
+		id++
+		return id
+	}
+	y := 0
+	for _, o := range t.objectives {
+		panels = append(panels, map[string]any{
+			"type":      "row",
+			"id":        nextID(),
+			"title":     fmt.Sprintf("%s (%s)", o.Name, o.Route),
+			"collapsed": false,
+			"gridPos":   gridPos(0, y, 24, 1),
+			"panels":    []any{},
+		})
+		y++
+
+		var budgetTargets, burnTargets, sliTargets []any
+		for i, sli := range o.slis() {
+			budget := formatFloat(1 - o.target(sli))
+			refID := string(rune('A' + i))
+			budgetTargets = append(budgetTargets, promTarget(refID, sli,
+				fmt.Sprintf("1 - %s / %s", errorRatioSeries(o.Name, sli, t.window), budget)))
+			sliTargets = append(sliTargets,
+				promTarget(refID, sli, fmt.Sprintf("1 - %s", errorRatioSeries(o.Name, sli, 5*time.Minute))),
+				promTarget(refID+"objective", sli+" objective", fmt.Sprintf("vector(%s)", formatFloat(o.target(sli)))),
+			)
+			for j, alert := range alertsFor(t.window) {
+				if j > 1 {
+					// The fast-burn windows are the ones worth watching live
+					break
+				}
+				burnTargets = append(burnTargets, promTarget(fmt.Sprintf("%s%d", refID, j), fmt.Sprintf("%s %s", sli, promDuration(alert.Long)),
+					fmt.Sprintf("%s / %s", errorRatioSeries(o.Name, sli, alert.Long), budget)))
+			}
+		}
+
+		panels = append(panels,
+			map[string]any{
+				"type":        "stat",
+				"id":          nextID(),
+				"title":       fmt.Sprintf("Error budget remaining (%s)", promDuration(t.window)),
+				"description": "Share of the error budget left over the SLO window; negative once the objective is missed.",
+				"datasource":  promDatasource,
+				"gridPos":     gridPos(0, y, 6, 8),
+				"fieldConfig": map[string]any{"defaults": map[string]any{
+					"unit": "percentunit",
+					"thresholds": map[string]any{"mode": "absolute", "steps": []any{
+						map[string]any{"color": "red", "value": nil},
+						map[string]any{"color": "yellow", "value": 0},
+						map[string]any{"color": "green", "value": 0.25},
+					}},
+				}},
+				"options": map[string]any{"reduceOptions": map[string]any{"calcs": []string{"lastNotNull"}, "fields": "", "values": false}},
+				"targets": budgetTargets,
+			},
+			map[string]any{
+				"type":        "timeseries",
+				"id":          nextID(),
+				"title":       "Burn rate",
+				"description": "How many times faster than sustainable the error budget is being spent. Alerts page above the dashed thresholds.",
+				"datasource":  promDatasource,
+				"gridPos":     gridPos(6, y, 9, 8),
+				"fieldConfig": map[string]any{"defaults": map[string]any{
+					"unit":       "short",
+					"custom":     map[string]any{"thresholdsStyle": map[string]any{"mode": "dashed"}},
+					"thresholds": map[string]any{"mode": "absolute", "steps": burnThresholds(t.window)},
+				}},
+				"targets": burnTargets,
+			},
+			map[string]any{
+				"type":        "timeseries",
+				"id":          nextID(),
+				"title":       "SLI (5m)",
+				"description": "Share of requests passing each indicator, against its objective.",
+				"datasource":  promDatasource,
+				"gridPos":     gridPos(15, y, 9, 8),
+				"fieldConfig": map[string]any{"defaults": map[string]any{
+					"unit": "percentunit",
+					"max":  1,
+				}},
+				"targets": sliTargets,
+			},
+		)
+		y += 8
+	}
+
+	return map[string]any{
+		"uid":           "api-security-platform-slo",
+		"title":         "API Security Platform SLOs",
+		"tags":          []string{"slo"},
+		"editable":      true,
+		"schemaVersion": 38,
+		"refresh":       "1m",
+		"time":          map[string]any{"from": "now-24h", "to": "now"},
+		"panels":        panels,
+	}
+}
+
+var promDatasource = map[string]any{"type": "prometheus", "uid": "prometheus"}
+
+func promTarget(refID, legend, expr string) map[string]any {
+	return map[string]any{"refId": refID, "legendFormat": legend, "expr": expr, "datasource": promDatasource}
+}
+
+func gridPos(x, y, w, h int) map[string]any {
+	return map[string]any{"x": x, "y": y, "w": w, "h": h}
+}
+
+// burnThresholds marks the burn rates of the paging tiers
+func burnThresholds(window time.Duration) []any {
+	steps := []any{map[string]any{"color": "green", "value": nil}}
+	colors := []string{"red", "orange"}
+	// Slowest burn first, so the steps ascend
+	var paging []burnRateAlert
+	for _, alert := range alertsFor(window) {
+		if alert.Severity == "page" {
+			paging = append([]burnRateAlert{alert}, paging...)
+		}
+	}
+	for i, alert := range paging {
+		steps = append(steps, map[string]any{"color": colors[len(paging)-1-i], "value": alert.burnRate(window)})
+	}
+	return steps
+}
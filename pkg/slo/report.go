@@ -0,0 +1,140 @@
+package slo
+
+import (
+	"apisecurityplatform/pkg/observability"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Report is the error budget consumption of every objective, as counted by
+// this instance since it started. Fleet-wide figures, over the SLO window
+// and the alerting windows, come from the generated recording rules.
+type Report struct {
+	// Since is when the instance started counting
+	Since      time.Time         `json:"since"`
+	Objectives []ObjectiveReport `json:"objectives"`
+}
+
+// ObjectiveReport covers the indicators of one objective
+type ObjectiveReport struct {
+	Name  string      `json:"name"`
+	Route string      `json:"route"`
+	SLIs  []SLIReport `json:"slis"`
+}
+
+// SLIReport is the state of one indicator's error budget
+type SLIReport struct {
+	SLI string `json:"sli"`
+	// Objective is the fraction of requests that must pass
+	Objective float64 `json:"objective"`
+	// Threshold is the latency objective, for the latency SLI
+	Threshold string  `json:"threshold,omitempty"`
+	Requests  float64 `json:"requests"`
+	Bad       float64 `json:"bad"`
+	// ErrorRatio is the fraction of requests that failed
+	ErrorRatio float64 `json:"error_ratio"`
+	// BudgetConsumed is the fraction of the error budget spent, above 1
+	// once the objective is missed
+	BudgetConsumed  float64 `json:"budget_consumed"`
+	BudgetRemaining float64 `json:"budget_remaining"`
+}
+
+// sliCounts are the requests and failures of one SLI
+type sliCounts struct {
+	requests, bad float64
+}
+
+// Report measures every objective against the HTTP server metrics' current
+// values
+func (t *Tracker) Report() Report {
+	availability := make(map[string]sliCounts)
+	collect(observability.RequestCounter, func(labels map[string]string, m *dto.Metric) {
+		counts := availability[labels["endpoint"]]
+		counts.requests += m.GetCounter().GetValue()
+		if strings.HasPrefix(labels["status"], "5") {
+			counts.bad += m.GetCounter().GetValue()
+		}
+		availability[labels["endpoint"]] = counts
+	})
+
+	// Latency is only read for the routes and thresholds of the objectives
+	thresholds := make(map[string][]float64)
+	for _, o := range t.objectives {
+		if o.Latency > 0 {
+			thresholds[o.series] = append(thresholds[o.series], o.Latency.Seconds())
+		}
+	}
+	latency := make(map[string]map[float64]sliCounts)
+	collect(observability.HTTPServerDuration, func(labels map[string]string, m *dto.Metric) {
+		route := labels["route"]
+		if len(thresholds[route]) == 0 {
+			return
+		}
+		if latency[route] == nil {
+			latency[route] = make(map[float64]sliCounts)
+		}
+		histogram := m.GetHistogram()
+		for _, bucket := range histogram.GetBucket() {
+			for _, threshold := range thresholds[route] {
+				if bucket.GetUpperBound() == threshold {
+					counts := latency[route][threshold]
+					counts.requests += float64(histogram.GetSampleCount())
+					counts.bad += float64(histogram.GetSampleCount() - bucket.GetCumulativeCount())
+					latency[route][threshold] = counts
+				}
+			}
+		}
+	})
+
+	report := Report{Since: t.started, Objectives: []ObjectiveReport{}}
+	for _, o := range t.objectives {
+		objectiveReport := ObjectiveReport{Name: o.Name, Route: o.Route}
+		for _, sli := range o.slis() {
+			counts := availability[o.series]
+			sliReport := SLIReport{SLI: sli, Objective: o.target(sli)}
+			if sli == SLILatency {
+				counts = latency[o.series][o.Latency.Seconds()]
+				sliReport.Threshold = o.Latency.String()
+			}
+			sliReport.Requests = counts.requests
+			sliReport.Bad = counts.bad
+			sliReport.ErrorRatio = ratio(counts.bad, counts.requests)
+			sliReport.BudgetConsumed = sliReport.ErrorRatio / (1 - o.target(sli))
+			sliReport.BudgetRemaining = 1 - sliReport.BudgetConsumed
+			objectiveReport.SLIs = append(objectiveReport.SLIs, sliReport)
+		}
+		report.Objectives = append(report.Objectives, objectiveReport)
+	}
+	return report
+}
+
+// collect calls each with the labels and value of every series of c
+func collect(c prometheus.Collector, each func(labels map[string]string, m *dto.Metric)) {
+	metrics := make(chan prometheus.Metric)
+	go func() {
+		c.Collect(metrics)
+		close(metrics)
+	}()
+	for metric := range metrics {
+		var m dto.Metric
+		if err := metric.Write(&m); err != nil {
+			continue
+		}
+		labels := make(map[string]string, len(m.GetLabel()))
+		for _, label := range m.GetLabel() {
+			labels[label.GetName()] = label.GetValue()
+		}
+		each(labels, &m)
+	}
+}
+
+// ratio is bad/total, 0 without traffic
+func ratio(bad, total float64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return bad / total
+}
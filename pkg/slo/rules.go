@@ -0,0 +1,183 @@
+package slo
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// burnRateAlert is one tier of multi-window burn-rate alerting: it fires when
+// the error budget burns fast enough over both windows to spend Consumption
+// of it within Long. The short window lets the alert resolve soon after the
+// burn stops.
+type burnRateAlert struct {
+	Long, Short time.Duration
+	Consumption float64
+	Severity    string
+	For         time.Duration
+}
+
+// burnRateAlerts are the tiers recommended by the SRE workbook. Their burn
+// rates, 14.4, 6, 3 and 1 over a 30 day window, are scaled to the window.
+var burnRateAlerts = []burnRateAlert{
+	{Long: time.Hour, Short: 5 * time.Minute, Consumption: 0.02, Severity: "page", For: 2 * time.Minute},
+	{Long: 6 * time.Hour, Short: 30 * time.Minute, Consumption: 0.05, Severity: "page", For: 15 * time.Minute},
+	{Long: 24 * time.Hour, Short: 2 * time.Hour, Consumption: 0.10, Severity: "ticket", For: time.Hour},
+	{Long: 72 * time.Hour, Short: 6 * time.Hour, Consumption: 0.10, Severity: "ticket", For: time.Hour},
+}
+
+// alertsFor returns the tiers whose long window fits in window
+func alertsFor(window time.Duration) []burnRateAlert {
+	var alerts []burnRateAlert
+	for _, alert := range burnRateAlerts {
+		if alert.Long <= window {
+			alerts = append(alerts, alert)
+		}
+	}
+	return alerts
+}
+
+// burnRate is the rate at which the tier spends its consumption within Long
+func (a burnRateAlert) burnRate(window time.Duration) float64 {
+	return a.Consumption * float64(window) / float64(a.Long)
+}
+
+// burnRateWindows are the windows the alerts of window evaluate, shortest first
+func burnRateWindows(window time.Duration) []time.Duration {
+	var windows []time.Duration
+	for _, alert := range alertsFor(window) {
+		for _, w := range []time.Duration{alert.Short, alert.Long} {
+			if !slices.Contains(windows, w) {
+				windows = append(windows, w)
+			}
+		}
+	}
+	slices.Sort(windows)
+	return windows
+}
+
+// RuleFile is a Prometheus rules file
+type RuleFile struct {
+	Groups []RuleGroup `json:"groups"`
+}
+
+// RuleGroup is a group of Prometheus rules evaluated together
+type RuleGroup struct {
+	Name  string `json:"name"`
+	Rules []Rule `json:"rules"`
+}
+
+// Rule is a Prometheus recording or alerting rule
+type Rule struct {
+	Record      string            `json:"record,omitempty"`
+	Alert       string            `json:"alert,omitempty"`
+	Expr        string            `json:"expr"`
+	For         string            `json:"for,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// errorRatioRecord names the recording rule of the error ratio over window
+func errorRatioRecord(window time.Duration) string {
+	return "slo:sli_error:ratio_rate" + promDuration(window)
+}
+
+// errorRatioSeries selects an objective's recorded error ratio over window
+func errorRatioSeries(name, sli string, window time.Duration) string {
+	return fmt.Sprintf(`%s{slo=%q, sli=%q}`, errorRatioRecord(window), name, sli)
+}
+
+// errorRatio is the expression of the fraction of the objective's requests
+// failing sli over window. Availability counts 5xx responses on
+// http_requests_total; latency counts the requests above the threshold's
+// bucket of http_server_duration_seconds.
+func (o *objective) errorRatio(sli string, window time.Duration) string {
+	w := promDuration(window)
+	if sli == SLILatency {
+		return fmt.Sprintf(`1 - sum(rate(http_server_duration_seconds_bucket{route=%q, le%s}[%s])) / sum(rate(http_server_duration_seconds_count{route=%q}[%s]))`,
+			o.series, bucketMatcher(o.Latency), w, o.series, w)
+	}
+	return fmt.Sprintf(`sum(rate(http_requests_total{endpoint=%q, status=~"5.."}[%s])) / sum(rate(http_requests_total{endpoint=%q}[%s]))`,
+		o.series, w, o.series, w)
+}
+
+// bucketMatcher matches the le label of the bucket bounded by d. Whole
+// seconds are exposed as "1" but stored as "1.0" by Prometheus 3, so both
+// are matched.
+func bucketMatcher(d time.Duration) string {
+	le := strconv.FormatFloat(d.Seconds(), 'g', -1, 64)
+	if d%time.Second == 0 {
+		return fmt.Sprintf(`=~"%s|%s.0"`, le, le)
+	}
+	return fmt.Sprintf("=%q", le)
+}
+
+// Rules generates, per objective, a group recording the error ratio of each
+// SLI over the alerting windows and the SLO window, and alerting on fast and
+// slow error budget burn
+func (t *Tracker) Rules() RuleFile {
+	file := RuleFile{Groups: []RuleGroup{}}
+	windows := burnRateWindows(t.window)
+	if !slices.Contains(windows, t.window) {
+		windows = append(windows, t.window)
+	}
+	for _, o := range t.objectives {
+		group := RuleGroup{Name: "slo-" + o.Name}
+		for _, sli := range o.slis() {
+			for _, window := range windows {
+				group.Rules = append(group.Rules, Rule{
+					Record: errorRatioRecord(window),
+					Expr:   o.errorRatio(sli, window),
+					Labels: map[string]string{"slo": o.Name, "sli": sli},
+				})
+			}
+		}
+		for _, sli := range o.slis() {
+			budget := formatFloat(1 - o.target(sli))
+			for _, alert := range alertsFor(t.window) {
+				burnRate := formatFloat(alert.burnRate(t.window))
+				group.Rules = append(group.Rules, Rule{
+					Alert: "SLOErrorBudgetBurn",
+					Expr: fmt.Sprintf("%s > (%s * %s)\nand\n%s > (%s * %s)",
+						errorRatioSeries(o.Name, sli, alert.Long), burnRate, budget,
+						errorRatioSeries(o.Name, sli, alert.Short), burnRate, budget),
+					For: promDuration(alert.For),
+					Labels: map[string]string{
+						"severity":    alert.Severity,
+						"slo":         o.Name,
+						"sli":         sli,
+						"long_window": promDuration(alert.Long),
+					},
+					Annotations: map[string]string{
+						"summary": fmt.Sprintf("SLO %s is burning its %s error budget %sx too fast", o.Name, sli, burnRate),
+						"description": fmt.Sprintf("Route %s spent at least %g%% of its %s error budget within the last %s and is still burning it over the last %s.",
+							o.Route, alert.Consumption*100, promDuration(t.window), promDuration(alert.Long), promDuration(alert.Short)),
+					},
+				})
+			}
+		}
+		file.Groups = append(file.Groups, group)
+	}
+	return file
+}
+
+// promDuration formats d in the largest Prometheus unit dividing it, such as
+// "30m", "6h" or "30d"
+func promDuration(d time.Duration) string {
+	units := []struct {
+		suffix string
+		unit   time.Duration
+	}{{"d", 24 * time.Hour}, {"h", time.Hour}, {"m", time.Minute}, {"s", time.Second}}
+	for _, u := range units {
+		if d >= u.unit && d%u.unit == 0 {
+			return strconv.FormatInt(int64(d/u.unit), 10) + u.suffix
+		}
+	}
+	return strconv.FormatInt(d.Milliseconds(), 10) + "ms"
+}
+
+func formatFloat(f float64) string {
+	// Round away float noise such as 1 - 0.999 = 0.0010000000000000009
+	return strconv.FormatFloat(f, 'g', 10, 64)
+}
@@ -0,0 +1,91 @@
+// Package slo measures the service level objectives declared in the config.
+// Its indicators come from the HTTP server metrics every request is already
+// counted on, http_requests_total and http_server_duration_seconds: it
+// reports the error budget they consume and generates the Prometheus rules
+// and Grafana panels alerting on and charting them.
+package slo
+
+import (
+	"apisecurityplatform/pkg/config"
+	"apisecurityplatform/pkg/observability"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Service level indicators
+const (
+	// SLIAvailability fails requests answered with a 5xx
+	SLIAvailability = "availability"
+	// SLILatency fails requests slower than the objective's latency
+	SLILatency = "latency"
+)
+
+// objective is a configured objective with the label its route is recorded
+// under by the HTTP server metrics
+type objective struct {
+	config.SLOObjective
+	series string
+}
+
+// slis returns the indicators the objective declares, availability first
+func (o *objective) slis() []string {
+	if o.Latency > 0 {
+		return []string{SLIAvailability, SLILatency}
+	}
+	return []string{SLIAvailability}
+}
+
+// target returns the fraction of requests that must pass sli
+func (o *objective) target(sli string) float64 {
+	if sli == SLILatency {
+		return o.LatencyTarget
+	}
+	return o.Availability
+}
+
+// Tracker measures the configured objectives
+type Tracker struct {
+	window     time.Duration
+	objectives []*objective
+	started    time.Time
+}
+
+// NewTracker creates a Tracker for the objectives of cfg. Latency objectives
+// are read from a histogram bucket, so their latency must be one of the
+// bucket bounds of http_server_duration_seconds.
+func NewTracker(cfg config.SLOConfig) (*Tracker, error) {
+	t := &Tracker{window: cfg.Window, started: time.Now()}
+	var errs []error
+	for _, o := range cfg.Objectives {
+		if o.Latency > 0 && !slices.Contains(observability.HTTPServerDurationBuckets, o.Latency.Seconds()) {
+			errs = append(errs, fmt.Errorf("objective %s: latency %s is not a bucket bound of http_server_duration_seconds (%s)",
+				o.Name, o.Latency, bucketBounds()))
+			continue
+		}
+		t.objectives = append(t.objectives, &objective{SLOObjective: o, series: seriesRoute(o.Route)})
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// seriesRoute returns the label the HTTP server metrics record route under:
+// platform endpoints by path pattern, gateway routes by name with a prefix
+func seriesRoute(route string) string {
+	if strings.HasPrefix(route, "/") {
+		return route
+	}
+	return "gateway:" + route
+}
+
+func bucketBounds() string {
+	bounds := make([]string, len(observability.HTTPServerDurationBuckets))
+	for i, bound := range observability.HTTPServerDurationBuckets {
+		bounds[i] = time.Duration(bound * float64(time.Second)).String()
+	}
+	return strings.Join(bounds, ", ")
+}
@@ -0,0 +1,114 @@
+package slo
+
+import (
+	"apisecurityplatform/pkg/config"
+	"apisecurityplatform/pkg/observability"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewTrackerRequiresBucketLatency(t *testing.T) {
+	objective := config.SLOObjective{Name: "profile", Route: "/users/me", Availability: 0.999, LatencyTarget: 0.95}
+	for latency, ok := range map[time.Duration]bool{0: true, 75 * time.Millisecond: true, 250 * time.Millisecond: true, time.Second: true, 300 * time.Millisecond: false} {
+		objective.Latency = latency
+		_, err := NewTracker(config.SLOConfig{Window: 720 * time.Hour, Objectives: []config.SLOObjective{objective}})
+		if (err == nil) != ok {
+			t.Errorf("latency %s: error %v, want valid = %v", latency, err, ok)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	tracker, err := NewTracker(config.SLOConfig{Window: 720 * time.Hour, Objectives: []config.SLOObjective{
+		{Name: "report-endpoint", Route: "/slo-test/report", Availability: 0.99, Latency: 250 * time.Millisecond, LatencyTarget: 0.9},
+		{Name: "report-gateway", Route: "slo-test-report", Availability: 0.9},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Requests as recorded by the metrics middleware
+	record := func(route, status string, n int, seconds float64) {
+		for i := 0; i < n; i++ {
+			observability.RequestCounter.WithLabelValues("GET", route, status).Inc()
+			observability.HTTPServerDuration.WithLabelValues("GET", route, status).Observe(seconds)
+		}
+	}
+	record("/slo-test/report", "200", 90, 0.1)
+	record("/slo-test/report", "404", 5, 0.3)
+	record("/slo-test/report", "503", 5, 1)
+	record("gateway:slo-test-report", "502", 1, 0.1)
+	record("gateway:slo-test-report", "201", 3, 0.1)
+	record("/slo-test/other", "500", 10, 1)
+
+	report := tracker.Report()
+	want := map[string][]SLIReport{
+		"report-endpoint": {
+			{SLI: SLIAvailability, Requests: 100, Bad: 5, ErrorRatio: 0.05, BudgetConsumed: 5},
+			{SLI: SLILatency, Threshold: "250ms", Requests: 100, Bad: 10, ErrorRatio: 0.1, BudgetConsumed: 1},
+		},
+		"report-gateway": {
+			{SLI: SLIAvailability, Requests: 4, Bad: 1, ErrorRatio: 0.25, BudgetConsumed: 2.5},
+		},
+	}
+	if len(report.Objectives) != len(want) {
+		t.Fatalf("%d objectives reported, want %d", len(report.Objectives), len(want))
+	}
+	for _, objective := range report.Objectives {
+		slis := want[objective.Name]
+		if len(objective.SLIs) != len(slis) {
+			t.Fatalf("%s: %d SLIs, want %d", objective.Name, len(objective.SLIs), len(slis))
+		}
+		for i, got := range objective.SLIs {
+			w := slis[i]
+			if got.SLI != w.SLI || got.Threshold != w.Threshold || got.Requests != w.Requests || got.Bad != w.Bad ||
+				!near(got.ErrorRatio, w.ErrorRatio) || !near(got.BudgetConsumed, w.BudgetConsumed) || !near(got.BudgetRemaining, 1-w.BudgetConsumed) {
+				t.Errorf("%s %s = %+v, want %+v", objective.Name, w.SLI, got, w)
+			}
+		}
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestRules(t *testing.T) {
+	tracker, err := NewTracker(config.SLOConfig{Window: 720 * time.Hour, Objectives: []config.SLOObjective{
+		{Name: "profile", Route: "/users/me", Availability: 0.999, Latency: 250 * time.Millisecond, LatencyTarget: 0.95},
+		{Name: "orders", Route: "orders", Availability: 0.99, Latency: time.Second, LatencyTarget: 0.9},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exprs := make(map[string]string)
+	for _, group := range tracker.Rules().Groups {
+		for _, rule := range group.Rules {
+			if rule.Record == "slo:sli_error:ratio_rate1h" {
+				exprs[rule.Labels["slo"]+" "+rule.Labels["sli"]] = rule.Expr
+			}
+		}
+	}
+	want := map[string]string{
+		"profile availability": `sum(rate(http_requests_total{endpoint="/users/me", status=~"5.."}[1h])) / sum(rate(http_requests_total{endpoint="/users/me"}[1h]))`,
+		"profile latency":      `1 - sum(rate(http_server_duration_seconds_bucket{route="/users/me", le="0.25"}[1h])) / sum(rate(http_server_duration_seconds_count{route="/users/me"}[1h]))`,
+		"orders availability":  `sum(rate(http_requests_total{endpoint="gateway:orders", status=~"5.."}[1h])) / sum(rate(http_requests_total{endpoint="gateway:orders"}[1h]))`,
+		"orders latency":       `1 - sum(rate(http_server_duration_seconds_bucket{route="gateway:orders", le=~"1|1.0"}[1h])) / sum(rate(http_server_duration_seconds_count{route="gateway:orders"}[1h]))`,
+	}
+	for name, expr := range want {
+		if exprs[name] != expr {
+			t.Errorf("%s error ratio:\n got %s\nwant %s", name, exprs[name], expr)
+		}
+	}
+
+	for _, group := range tracker.Rules().Groups {
+		for _, rule := range group.Rules {
+			if strings.Contains(rule.Expr, "slo_") {
+				t.Errorf("rule %s%s reads a series of its own: %s", rule.Record, rule.Alert, rule.Expr)
+			}
+		}
+	}
+}
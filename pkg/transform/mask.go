@@ -0,0 +1,67 @@
+package transform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// redacted replaces the value of fields masked with the redact action
+const redacted = "[REDACTED]"
+
+type maskRule struct {
+	path        []string
+	redact      bool
+	unlessScope string
+}
+
+// maskJSON applies the rules to a JSON document
+func maskJSON(body []byte, rules []maskRule) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	// Numbers are kept as written rather than converted to float64
+	decoder.UseNumber()
+	var doc any
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON body: %w", err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid JSON body: trailing data")
+	}
+
+	for _, rule := range rules {
+		maskValue(doc, rule.path, rule.redact)
+	}
+
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(doc); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(out.Bytes(), []byte("\n")), nil
+}
+
+// maskValue walks path through v. Arrays are traversed transparently and a
+// * segment matches every key of an object.
+func maskValue(v any, path []string, redact bool) {
+	switch node := v.(type) {
+	case []any:
+		for _, elem := range node {
+			maskValue(elem, path, redact)
+		}
+	case map[string]any:
+		for key, child := range node {
+			if path[0] != "*" && path[0] != key {
+				continue
+			}
+			if len(path) > 1 {
+				maskValue(child, path[1:], redact)
+			} else if redact {
+				node[key] = redacted
+			} else {
+				delete(node, key)
+			}
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Package transform applies the declarative request and response
+// transformations of a gateway route as gin middleware
+package transform
+
+import (
+	"apisecurityplatform/pkg/config"
+	"apisecurityplatform/pkg/logging"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var logger = logging.For("transform")
+
+// Pipeline is the compiled transformation policy of one route
+type Pipeline struct {
+	requestHeaders  config.HeaderTransformConfig
+	pathPattern     *regexp.Regexp
+	pathReplacement string
+	query           config.QueryTransformConfig
+	responseHeaders config.HeaderTransformConfig
+	mask            []maskRule
+	maxBodyBytes    int64
+}
+
+// New compiles a validated transformation policy
+func New(cfg config.TransformConfig) (*Pipeline, error) {
+	p := &Pipeline{
+		requestHeaders:  cfg.Request.Headers,
+		pathReplacement: cfg.Request.Path.Replacement,
+		query:           cfg.Request.Query,
+		responseHeaders: cfg.Response.Headers,
+		maxBodyBytes:    cfg.Response.MaxBodyBytes,
+	}
+	if cfg.Request.Path.Pattern != "" {
+		pattern, err := regexp.Compile(cfg.Request.Path.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		p.pathPattern = pattern
+	}
+	for _, rule := range cfg.Response.Mask {
+		p.mask = append(p.mask, maskRule{
+			path:        strings.Split(rule.Field, "."),
+			redact:      rule.Action == config.MaskActionRedact,
+			unlessScope: rule.UnlessScope,
+		})
+	}
+	return p, nil
+}
+
+// Middleware transforms the request, then the response written by the
+// handlers after it. It runs after the auth middlewares, since masking
+// depends on the scopes they record.
+func (p *Pipeline) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p.transformRequest(c.Request)
+
+		mask := p.maskFor(c.GetStringSlice("scopes"))
+		if len(mask) == 0 && len(p.responseHeaders.Set) == 0 && len(p.responseHeaders.Remove) == 0 {
+			c.Next()
+			return
+		}
+		// Masking needs a plain body, and the transport decompresses
+		// responses only when it negotiated the encoding itself
+		if len(mask) > 0 {
+			c.Request.Header.Del("Accept-Encoding")
+		}
+
+		w := &responseWriter{ResponseWriter: c.Writer, headers: p.responseHeaders, mask: mask, maxBodyBytes: p.maxBodyBytes}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		if err := w.finish(); err != nil {
+			logger.ErrorContext(c.Request.Context(), "Response transformation failed", "error", err)
+			clear(c.Writer.Header())
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Upstream response could not be transformed"})
+		}
+	}
+}
+
+func (p *Pipeline) transformRequest(r *http.Request) {
+	for _, name := range p.requestHeaders.Remove {
+		r.Header.Del(name)
+	}
+	for _, header := range p.requestHeaders.Set {
+		r.Header.Set(header.Name, header.Value)
+	}
+
+	if p.pathPattern != nil {
+		r.URL.Path = p.pathPattern.ReplaceAllString(r.URL.Path, p.pathReplacement)
+		r.URL.RawPath = ""
+		if !strings.HasPrefix(r.URL.Path, "/") {
+			r.URL.Path = "/" + r.URL.Path
+		}
+	}
+
+	if len(p.query.Rename) == 0 && len(p.query.Remove) == 0 && len(p.query.Set) == 0 {
+		return
+	}
+	query := r.URL.Query()
+	renameQuery(query, p.query.Rename)
+	for _, name := range p.query.Remove {
+		query.Del(name)
+	}
+	for _, param := range p.query.Set {
+		query.Set(param.Name, param.Value)
+	}
+	r.URL.RawQuery = query.Encode()
+}
+
+func renameQuery(query url.Values, renames []config.QueryRenameConfig) {
+	for _, rename := range renames {
+		values, ok := query[rename.From]
+		if !ok {
+			continue
+		}
+		delete(query, rename.From)
+		query[rename.To] = values
+	}
+}
+
+// maskFor returns the mask rules that apply to a caller with these scopes
+func (p *Pipeline) maskFor(scopes []string) []maskRule {
+	var rules []maskRule
+	for _, rule := range p.mask {
+		if rule.unlessScope == "" || !slices.Contains(scopes, rule.unlessScope) {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
@@ -0,0 +1,346 @@
+package transform
+
+import (
+	"apisecurityplatform/pkg/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// serve runs a request through the pipeline of cfg in front of handler
+func serve(t *testing.T, cfg config.TransformConfig, scopes []string, req *http.Request, handler gin.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+	if cfg.Response.MaxBodyBytes == 0 {
+		cfg.Response.MaxBodyBytes = 1 << 20
+	}
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if scopes != nil {
+			c.Set("scopes", scopes)
+		}
+	}, p.Middleware())
+	r.Any("/*path", handler)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRequestHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers config.HeaderTransformConfig
+		in      http.Header
+		want    http.Header
+	}{
+		{
+			name:    "set",
+			headers: config.HeaderTransformConfig{Set: []config.NameValueConfig{{Name: "X-Tenant", Value: "acme"}}},
+			in:      http.Header{"X-Tenant": {"other"}},
+			want:    http.Header{"X-Tenant": {"acme"}},
+		},
+		{
+			name:    "remove",
+			headers: config.HeaderTransformConfig{Remove: []string{"x-debug"}},
+			in:      http.Header{"X-Debug": {"1"}, "X-Keep": {"1"}},
+			want:    http.Header{"X-Keep": {"1"}},
+		},
+		{
+			name: "remove then set",
+			headers: config.HeaderTransformConfig{
+				Remove: []string{"X-Tenant"},
+				Set:    []config.NameValueConfig{{Name: "X-Tenant", Value: "acme"}},
+			},
+			in:   http.Header{"X-Tenant": {"a", "b"}},
+			want: http.Header{"X-Tenant": {"acme"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header = tt.in
+			var got http.Header
+			serve(t, config.TransformConfig{Request: config.RequestTransformConfig{Headers: tt.headers}}, nil, req, func(c *gin.Context) {
+				got = c.Request.Header.Clone()
+			})
+			for name := range tt.in {
+				if _, ok := tt.want[name]; !ok && got.Get(name) != "" {
+					t.Errorf("%s = %q, want it removed", name, got.Get(name))
+				}
+			}
+			for name, values := range tt.want {
+				if g := got.Values(name); len(g) != len(values) || g[0] != values[0] {
+					t.Errorf("%s = %q, want %q", name, g, values)
+				}
+			}
+		})
+	}
+}
+
+func TestResponseHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers config.HeaderTransformConfig
+		want    http.Header
+		absent  []string
+	}{
+		{
+			name:    "set",
+			headers: config.HeaderTransformConfig{Set: []config.NameValueConfig{{Name: "Cache-Control", Value: "no-store"}}},
+			want:    http.Header{"Cache-Control": {"no-store"}, "Server": {"upstream"}},
+		},
+		{
+			name:    "remove",
+			headers: config.HeaderTransformConfig{Remove: []string{"server", "X-Powered-By"}},
+			absent:  []string{"Server", "X-Powered-By"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := serve(t, config.TransformConfig{Response: config.ResponseTransformConfig{Headers: tt.headers}}, nil, req, func(c *gin.Context) {
+				c.Header("Server", "upstream")
+				c.Header("X-Powered-By", "php")
+				c.Header("Cache-Control", "max-age=60")
+				c.String(http.StatusOK, "ok")
+			})
+			for name, values := range tt.want {
+				if got := rec.Header().Get(name); got != values[0] {
+					t.Errorf("%s = %q, want %q", name, got, values[0])
+				}
+			}
+			for _, name := range tt.absent {
+				if got := rec.Header().Get(name); got != "" {
+					t.Errorf("%s = %q, want it removed", name, got)
+				}
+			}
+			if rec.Body.String() != "ok" {
+				t.Errorf("body = %q, want it passed through", rec.Body)
+			}
+		})
+	}
+}
+
+func TestPathRewrite(t *testing.T) {
+	tests := []struct {
+		name        string
+		pattern     string
+		replacement string
+		path        string
+		want        string
+	}{
+		{"capture groups", `^/v1/users/(\d+)$`, "/users/$1/profile", "/v1/users/42", "/users/42/profile"},
+		{"no match", `^/v1/`, "/v2/", "/v3/users", "/v3/users"},
+		{"every match", `/+`, "/", "//a//b", "/a/b"},
+		{"leading slash added", `^/api/`, "", "/api/users", "/users"},
+		{"escaped path", `^/files/`, "/blobs/", "/files/a%2Fb", "/blobs/a/b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.TransformConfig{Request: config.RequestTransformConfig{
+				Path: config.PathRewriteConfig{Pattern: tt.pattern, Replacement: tt.replacement},
+			}}
+			var got string
+			serve(t, cfg, nil, httptest.NewRequest(http.MethodGet, tt.path, nil), func(c *gin.Context) {
+				got = c.Request.URL.Path
+			})
+			if got != tt.want {
+				t.Errorf("path = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryMapping(t *testing.T) {
+	tests := []struct {
+		name  string
+		query config.QueryTransformConfig
+		in    string
+		want  string
+	}{
+		{"rename", config.QueryTransformConfig{Rename: []config.QueryRenameConfig{{From: "q", To: "search"}}}, "q=a&q=b&page=2", "page=2&search=a&search=b"},
+		{"rename missing", config.QueryTransformConfig{Rename: []config.QueryRenameConfig{{From: "q", To: "search"}}}, "page=2", "page=2"},
+		{"remove", config.QueryTransformConfig{Remove: []string{"debug"}}, "debug=1&page=2", "page=2"},
+		{"set", config.QueryTransformConfig{Set: []config.NameValueConfig{{Name: "limit", Value: "10"}}}, "limit=1000&limit=5", "limit=10"},
+		{
+			"rename, remove, set in order",
+			config.QueryTransformConfig{
+				Rename: []config.QueryRenameConfig{{From: "token", To: "debug"}},
+				Remove: []string{"debug"},
+				Set:    []config.NameValueConfig{{Name: "v", Value: "2"}},
+			},
+			"token=x", "v=2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.TransformConfig{Request: config.RequestTransformConfig{Query: tt.query}}
+			var got string
+			serve(t, cfg, nil, httptest.NewRequest(http.MethodGet, "/?"+tt.in, nil), func(c *gin.Context) {
+				got = c.Request.URL.RawQuery
+			})
+			if got != tt.want {
+				t.Errorf("query = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMasking(t *testing.T) {
+	rules := []config.MaskRuleConfig{
+		{Field: "password"},
+		{Field: "items.email", Action: config.MaskActionRedact, UnlessScope: "pii:read"},
+		{Field: "meta.*.secret"},
+	}
+	tests := []struct {
+		name        string
+		scopes      []string
+		contentType string
+		encoding    string
+		status      int
+		body        string
+		wantStatus  int
+		wantBody    string
+	}{
+		{
+			name:        "remove and redact",
+			contentType: "application/json",
+			body:        `{"id":1,"password":"x","items":[{"email":"a@example.com","n":1.50},{"email":"b@example.com"}]}`,
+			wantStatus:  http.StatusOK,
+			wantBody:    `{"id":1,"items":[{"email":"[REDACTED]","n":1.50},{"email":"[REDACTED]"}]}`,
+		},
+		{
+			name:        "wildcard",
+			contentType: "application/problem+json",
+			body:        `{"meta":{"a":{"secret":1,"k":2},"b":{"secret":3}}}`,
+			wantStatus:  http.StatusOK,
+			wantBody:    `{"meta":{"a":{"k":2},"b":{}}}`,
+		},
+		{
+			name:        "exempt scope",
+			scopes:      []string{"pii:read"},
+			contentType: "application/json",
+			body:        `{"password":"x","items":[{"email":"a@example.com"}]}`,
+			wantStatus:  http.StatusOK,
+			wantBody:    `{"items":[{"email":"a@example.com"}]}`,
+		},
+		{
+			name:        "mislabelled JSON",
+			contentType: "text/plain",
+			body:        `{"password":"x"}`,
+			wantStatus:  http.StatusOK,
+			wantBody:    `{}`,
+		},
+		{
+			name:       "unlabelled JSON",
+			body:       `[{"password":"x","id":1}]`,
+			wantStatus: http.StatusOK,
+			wantBody:   `[{"id":1}]`,
+		},
+		{
+			name:        "error body",
+			contentType: "application/json",
+			status:      http.StatusNotFound,
+			body:        `{"error":"not found","password":"x"}`,
+			wantStatus:  http.StatusNotFound,
+			wantBody:    `{"error":"not found"}`,
+		},
+		{
+			name:        "empty body",
+			contentType: "application/json",
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "html",
+			contentType: "text/html",
+			body:        `<p>password: x</p>`,
+			wantStatus:  http.StatusBadGateway,
+		},
+		{
+			name:        "invalid JSON",
+			contentType: "application/json",
+			body:        `{"password":`,
+			wantStatus:  http.StatusBadGateway,
+		},
+		{
+			name:        "trailing data",
+			contentType: "application/json",
+			body:        `{} {"password":"x"}`,
+			wantStatus:  http.StatusBadGateway,
+		},
+		{
+			name:        "compressed",
+			contentType: "application/json",
+			encoding:    "gzip",
+			body:        `{"password":"x"}`,
+			wantStatus:  http.StatusBadGateway,
+		},
+		{
+			name:        "too large",
+			contentType: "application/json",
+			body:        `{"password":"` + string(make([]byte, 100)) + `"}`,
+			wantStatus:  http.StatusBadGateway,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.TransformConfig{Response: config.ResponseTransformConfig{Mask: rules, MaxBodyBytes: 100}}
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			var upstreamEncoding string
+			rec := serve(t, cfg, tt.scopes, req, func(c *gin.Context) {
+				upstreamEncoding = c.Request.Header.Get("Accept-Encoding")
+				if tt.contentType != "" {
+					c.Header("Content-Type", tt.contentType)
+				}
+				if tt.encoding != "" {
+					c.Header("Content-Encoding", tt.encoding)
+				}
+				status := tt.status
+				if status == 0 {
+					status = http.StatusOK
+				}
+				c.Status(status)
+				c.Writer.WriteString(tt.body)
+			})
+
+			if upstreamEncoding != "" {
+				t.Errorf("Accept-Encoding %q passed on, want it removed for masking", upstreamEncoding)
+			}
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus == http.StatusBadGateway {
+				if got := rec.Body.String(); got != `{"error":"Upstream response could not be transformed"}` {
+					t.Errorf("body = %s, want the transformation error", got)
+				}
+				return
+			}
+			if got := rec.Body.String(); got != tt.wantBody {
+				t.Errorf("body = %s, want %s", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestMaskingSkipsBodylessResponses(t *testing.T) {
+	cfg := config.TransformConfig{Response: config.ResponseTransformConfig{Mask: []config.MaskRuleConfig{{Field: "password"}}}}
+	for _, status := range []int{http.StatusNoContent, http.StatusNotModified} {
+		rec := serve(t, cfg, nil, httptest.NewRequest(http.MethodGet, "/", nil), func(c *gin.Context) {
+			c.Status(status)
+			c.Writer.WriteHeaderNow()
+		})
+		if rec.Code != status {
+			t.Errorf("status = %d, want %d passed through", rec.Code, status)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package transform
+
+import (
+	"apisecurityplatform/pkg/config"
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// responseWriter applies the response header rules as the response starts,
+// and holds back bodies that must be masked until they are complete. Every
+// body is held back whatever its Content-Type, since an upstream mislabelling
+// JSON must not bypass masking.
+type responseWriter struct {
+	gin.ResponseWriter
+	headers      config.HeaderTransformConfig
+	mask         []maskRule
+	maxBodyBytes int64
+
+	wroteHeader bool
+	// buffering is set when the body is held back for masking
+	buffering bool
+	status    int
+	body      bytes.Buffer
+	overflow  bool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	// Informational responses are passed through untouched
+	if status < http.StatusOK {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+
+	header := w.Header()
+	for _, name := range w.headers.Remove {
+		header.Del(name)
+	}
+	for _, h := range w.headers.Set {
+		header.Set(h.Name, h.Value)
+	}
+
+	if len(w.mask) > 0 && bodyAllowed(status) {
+		w.buffering = true
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) WriteHeaderNow() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.buffering {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.buffering {
+		return w.ResponseWriter.Write(b)
+	}
+	if !w.overflow {
+		if int64(w.body.Len()+len(b)) > w.maxBodyBytes {
+			w.overflow = true
+			w.body = bytes.Buffer{}
+		} else {
+			w.body.Write(b)
+		}
+	}
+	return len(b), nil
+}
+
+func (w *responseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *responseWriter) Status() int {
+	if w.wroteHeader {
+		return w.status
+	}
+	return w.ResponseWriter.Status()
+}
+
+// Flush is a no-op while the body is held back
+func (w *responseWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.buffering {
+		w.ResponseWriter.Flush()
+	}
+}
+
+// finish masks and writes a held back body. On error nothing has been sent,
+// so the caller can still answer with an error. Masking fails closed: a body
+// that cannot be masked, including one that is not JSON, is never passed
+// through.
+func (w *responseWriter) finish() error {
+	if !w.buffering {
+		return nil
+	}
+	if w.overflow {
+		return fmt.Errorf("response body exceeds %d bytes", w.maxBodyBytes)
+	}
+	if encoding := w.Header().Get("Content-Encoding"); encoding != "" && !strings.EqualFold(encoding, "identity") {
+		return fmt.Errorf("cannot mask a body with Content-Encoding %q", encoding)
+	}
+
+	body := w.body.Bytes()
+	if len(body) > 0 {
+		masked, err := maskJSON(body, w.mask)
+		if err != nil {
+			return err
+		}
+		body = masked
+	}
+
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.ResponseWriter.WriteHeader(w.status)
+	// A write error means the client went away; there is no one to tell
+	w.ResponseWriter.Write(body)
+	return nil
+}
+
+func bodyAllowed(status int) bool {
+	return status != http.StatusNoContent && status != http.StatusNotModified
+}
@@ -0,0 +1,180 @@
+// Package usage meters the requests of each user and API key into hourly
+// records in the database, where they can be queried per tenant. Prometheus
+// only sees bounded aggregates; per-caller counts live here instead.
+package usage
+
+import (
+	"apisecurityplatform/pkg/logging"
+	"apisecurityplatform/pkg/models"
+	"apisecurityplatform/pkg/observability"
+	"apisecurityplatform/pkg/repository"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var logger = logging.For("usage")
+
+// Period is the granularity of stored usage records
+const Period = time.Hour
+
+// Request is one metered request
+type Request struct {
+	UserID   uint
+	APIKeyID uint
+	Route    string
+	Status   int
+	BytesIn  int64
+	BytesOut int64
+	At       time.Time
+}
+
+type recordKey struct {
+	period   time.Time
+	userID   uint
+	apiKeyID uint
+	route    string
+}
+
+// Meter aggregates metered requests in memory and adds them to the usage
+// store in batches, so a busy caller costs one write per record and flush
+// rather than one per request
+type Meter struct {
+	store repository.UsageRepository
+
+	mu      sync.Mutex
+	pending map[recordKey]*models.UsageRecord
+}
+
+// NewMeter creates a Meter adding to store
+func NewMeter(store repository.UsageRepository) *Meter {
+	return &Meter{store: store, pending: make(map[recordKey]*models.UsageRecord)}
+}
+
+// Record counts req towards its caller's usage
+func (m *Meter) Record(req Request) {
+	record := models.UsageRecord{
+		Period:   req.At.UTC().Truncate(Period),
+		UserID:   req.UserID,
+		APIKeyID: req.APIKeyID,
+		Route:    req.Route,
+		Requests: 1,
+		BytesIn:  max(req.BytesIn, 0),
+		BytesOut: max(req.BytesOut, 0),
+	}
+	switch {
+	case req.Status >= http.StatusInternalServerError:
+		record.ServerErrors = 1
+	case req.Status >= http.StatusBadRequest:
+		record.ClientErrors = 1
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.add(record)
+}
+
+func (m *Meter) add(record models.UsageRecord) {
+	key := recordKey{period: record.Period, userID: record.UserID, apiKeyID: record.APIKeyID, route: record.Route}
+	if pending, ok := m.pending[key]; ok {
+		pending.Add(record)
+		return
+	}
+	m.pending[key] = &record
+}
+
+// Flush adds the usage recorded since the last flush to the store. When the
+// store fails, the usage is kept for the next flush.
+func (m *Meter) Flush(ctx context.Context) error {
+	m.mu.Lock()
+	pending := m.pending
+	m.pending = make(map[recordKey]*models.UsageRecord)
+	m.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+	records := make([]models.UsageRecord, 0, len(pending))
+	for _, record := range pending {
+		records = append(records, *record)
+	}
+
+	// Add is transactional, so on failure nothing was counted yet
+	if err := m.store.Add(ctx, records); err != nil {
+		observability.UsageFlushes.WithLabelValues("error").Inc()
+		m.mu.Lock()
+		for _, record := range records {
+			m.add(record)
+		}
+		m.mu.Unlock()
+		return err
+	}
+	observability.UsageFlushes.WithLabelValues("success").Inc()
+	return nil
+}
+
+// Watch flushes every interval and, with a positive retention, deletes
+// records older than retention once an hour, until ctx is cancelled. The
+// final flush is left to shutdown, after requests have drained.
+func (m *Meter) Watch(ctx context.Context, interval, retention time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		var lastPrune time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := m.Flush(ctx); err != nil && ctx.Err() == nil {
+					logger.Error("Failed to flush usage", "error", err)
+				}
+				if retention <= 0 || time.Since(lastPrune) < Period {
+					continue
+				}
+				lastPrune = time.Now()
+				deleted, err := m.store.DeleteBefore(ctx, time.Now().Add(-retention))
+				if err != nil && ctx.Err() == nil {
+					logger.Error("Failed to delete expired usage", "error", err)
+				} else if deleted > 0 {
+					logger.Debug("Deleted expired usage", "records", deleted)
+				}
+			}
+		}
+	}()
+}
+
+// Middleware meters authenticated requests once they complete. Requests are
+// attributed to the user and API key set by the auth middleware and counted
+// under the gateway route that served them or the endpoint's path pattern;
+// anonymous requests are not metered.
+func (m *Meter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		userID := c.GetUint("user_id")
+		apiKeyID := c.GetUint("api_key_id")
+		if userID == 0 && apiKeyID == 0 {
+			return
+		}
+		route := c.GetString("gateway_route")
+		if route == "" {
+			route = c.FullPath()
+		}
+		if route == "" {
+			route = "not_found"
+		}
+		m.Record(Request{
+			UserID:   userID,
+			APIKeyID: apiKeyID,
+			Route:    route,
+			Status:   c.Writer.Status(),
+			BytesIn:  c.Request.ContentLength,
+			BytesOut: int64(c.Writer.Size()),
+			At:       time.Now(),
+		})
+	}
+}